@@ -0,0 +1,502 @@
+package cmd
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ActionHandler validates and executes one Task action. Handlers are looked up
+// by action name in actionHandlers.
+type ActionHandler interface {
+	// Validate checks the structure of a task before anything is executed
+	Validate(task Task) error
+	// Execute performs the task, adding its output to run.result
+	Execute(run *taskRun) error
+}
+
+// actionSpec describes a Task action: which repository endpoints and fields it
+// needs, which files it accepts and which task options apply. It implements
+// ActionHandler; validateTask, executeTask and GET /v1/api/actions are all
+// derived from actionSpecs. SchemaType is the Schema.org action type used for
+// the task in JSON-LD responses, matching the semantic API. Aliases are other
+// action names accepted for the same action.
+type actionSpec struct {
+	Name           string              `json:"name"`
+	Aliases        []string            `json:"aliases,omitempty"`
+	Description    string              `json:"description"`
+	SchemaType     string              `json:"schema_type"`
+	Src            *actionEndpointSpec `json:"src,omitempty"`
+	Tgt            *actionEndpointSpec `json:"tgt,omitempty"`
+	Files          []actionFileSpec    `json:"files,omitempty"`
+	Options        []string            `json:"options,omitempty"`
+	SupportsDryRun bool                `json:"supports_dry_run"`
+
+	// validate runs action specific checks after the required fields are present
+	validate func(task Task) error
+	// execute performs the action
+	execute func(run *taskRun) error
+}
+
+// actionEndpointSpec describes the src or tgt repository of an action
+type actionEndpointSpec struct {
+	Required       bool     `json:"required"`
+	RequiredFields []string `json:"required_fields,omitempty"`
+	OptionalFields []string `json:"optional_fields,omitempty"`
+}
+
+// actionFileSpec describes a multipart file field accepted by an action.
+// {index} in the key stands for the position of the task in the request.
+type actionFileSpec struct {
+	Key         string `json:"key"`
+	Required    bool   `json:"required"`
+	Description string `json:"description"`
+
+	// requiredWhen makes an optional file required for some tasks
+	requiredWhen func(task Task) bool
+	// check validates the content of an uploaded file before the task runs
+	check func(fileHeader *multipart.FileHeader) error
+	// maxFiles caps the number of files uploaded with the key, 0 = unlimited
+	maxFiles func() int
+}
+
+// taskFieldError is a validation error caused by one field of a task,
+// e.g. "tgt.repo". The field lets clients show the error next to the input.
+type taskFieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *taskFieldError) Error() string {
+	return e.Message
+}
+
+// credentialFields are accepted by every repository endpoint
+var credentialFields = []string{"username", "password", "token", "auth_type", "headers"}
+
+// actionSpecs lists all supported actions in documentation order
+var actionSpecs = []actionSpec{
+	{
+		Name:        "repo-migration",
+		Description: "Migrate a repository (config and data) between GraphDB instances",
+		SchemaType:  "TransferAction",
+		execute:     executeRepoMigrationTask,
+		Src:         &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo"}, OptionalFields: append([]string{"graphs"}, credentialFields...)},
+		Tgt:         &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo"}, OptionalFields: credentialFields},
+		Options:     []string{"verify", "report_graphs", "force_recreate"},
+		validate: func(task Task) error {
+			return validateSelectedGraphs(task.Src.Graphs)
+		},
+	},
+	{
+		Name:        "graph-migration",
+		Description: "Migrate a named graph between repositories, replacing the target graph",
+		SchemaType:  "TransferAction",
+		execute:     executeGraphMigrationTask,
+		Src:         &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo", "graph"}, OptionalFields: append([]string{"accept"}, credentialFields...)},
+		Tgt:         &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo", "graph"}, OptionalFields: append([]string{"content_type"}, credentialFields...)},
+		Options:     []string{"export_format", "compress"},
+		validate: func(task Task) error {
+			if normalizeURL(task.Src.URL) == normalizeURL(task.Tgt.URL) && task.Src.Repo == task.Tgt.Repo && task.Src.Graph == task.Tgt.Graph {
+				return &taskFieldError{Field: "tgt.graph", Message: "src and tgt of graph-migration are the same graph"}
+			}
+			if _, err := migrationExportFormat(task); err != nil {
+				return &taskFieldError{Field: "export_format", Message: err.Error()}
+			}
+			if err := validateMediaTypeOverride("src.accept", task.Src.Accept); err != nil {
+				return err
+			}
+			return validateMediaTypeOverride("tgt.content_type", task.Tgt.ContentType)
+		},
+	},
+	{
+		Name:           "repo-delete",
+		Description:    "Delete a repository, or all repositories matching tgt.pattern",
+		SchemaType:     "DeleteAction",
+		execute:        executeRepoDeleteTask,
+		Tgt:            &actionEndpointSpec{Required: true, RequiredFields: []string{"url"}, OptionalFields: append([]string{"repo", "pattern"}, credentialFields...)},
+		Options:        []string{"confirm_pattern", "continue_on_error"},
+		SupportsDryRun: true,
+		validate: func(task Task) error {
+			return validateDeletePattern(task, "repo", task.Tgt.Repo)
+		},
+	},
+	{
+		Name:           "graph-delete",
+		Description:    "Delete a named graph, or all graphs matching tgt.pattern",
+		SchemaType:     "DeleteAction",
+		execute:        executeGraphDeleteTask,
+		Tgt:            &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo"}, OptionalFields: append([]string{"graph", "pattern"}, credentialFields...)},
+		Options:        []string{"confirm_pattern", "continue_on_error"},
+		SupportsDryRun: true,
+		validate: func(task Task) error {
+			return validateDeletePattern(task, "graph", task.Tgt.Graph)
+		},
+	},
+	{
+		Name:           "graphs-delete",
+		Description:    "Delete several named graphs of one repository",
+		SchemaType:     "DeleteAction",
+		execute:        executeGraphsDeleteTask,
+		Tgt:            &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo", "graphs"}, OptionalFields: credentialFields},
+		Options:        []string{"continue_on_error"},
+		SupportsDryRun: true,
+	},
+	{
+		Name:        "repo-create",
+		Description: "Create a repository from an uploaded config or a generated config for a ruleset",
+		SchemaType:  "CreateAction",
+		execute:     executeRepoCreateTask,
+		Tgt:         &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo"}, OptionalFields: append([]string{"ruleset", "repo_type"}, credentialFields...)},
+		Files: []actionFileSpec{
+			{Key: "task_{index}_config", Description: "Repository config in Turtle; required unless tgt.ruleset is set", requiredWhen: func(task Task) bool { return task.Tgt.Ruleset == "" }, check: checkUploadedRepositoryConfig},
+		},
+		Options: []string{"if_not_exists"},
+		validate: func(task Task) error {
+			if task.Tgt.Ruleset != "" {
+				return validateRepositoryTemplate(task.Tgt.Ruleset, task.Tgt.RepoType)
+			}
+			return nil
+		},
+	},
+	{
+		Name:        "repos-create",
+		Description: "Create several repositories from one uploaded config template or a generated config for a ruleset",
+		SchemaType:  "CreateAction",
+		execute:     executeReposCreateTask,
+		Tgt:         &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repos"}, OptionalFields: append([]string{"ruleset", "repo_type"}, credentialFields...)},
+		Files: []actionFileSpec{
+			{Key: "task_{index}_config", Description: "Repository config template in Turtle, renamed for every repository; required unless tgt.ruleset is set", requiredWhen: func(task Task) bool { return task.Tgt.Ruleset == "" }, check: checkUploadedRepositoryConfig},
+		},
+		Options: []string{"if_not_exists"},
+		validate: func(task Task) error {
+			if err := validateRepositoryNames(task.Tgt.Repos); err != nil {
+				return err
+			}
+			if task.Tgt.Ruleset != "" {
+				return validateRepositoryTemplate(task.Tgt.Ruleset, task.Tgt.RepoType)
+			}
+			return nil
+		},
+	},
+	{
+		Name:        "graph-import",
+		Description: "Import uploaded RDF files into a named graph",
+		SchemaType:  "UploadAction",
+		execute:     executeGraphImportTask,
+		Tgt:         &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo"}, OptionalFields: append([]string{"graph", "format", "content_type", "preserve_graphs", "mode"}, credentialFields...)},
+		Files: []actionFileSpec{
+			{Key: "task_{index}_files", Required: true, Description: "RDF files; tgt.graph may be omitted when all files are quad formats and preserve_graphs is set", check: checkImportFileSize, maxFiles: maxImportFiles},
+		},
+		Options: []string{"skip_unchanged", "async_import"},
+		validate: func(task Task) error {
+			if _, err := graphImportMode(task.Tgt); err != nil {
+				return err
+			}
+			return validateMediaTypeOverride("tgt.content_type", task.Tgt.ContentType)
+		},
+	},
+	{
+		Name:        "repo-import",
+		Description: "Import a BRF backup into a repository, from an upload or from the src repository",
+		SchemaType:  "UploadAction",
+		execute:     executeRepoImportTask,
+		Src:         &actionEndpointSpec{OptionalFields: append([]string{"url", "repo"}, credentialFields...)},
+		Tgt:         &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo"}, OptionalFields: credentialFields},
+		Files: []actionFileSpec{
+			{Key: "task_{index}_files", Description: "BRF backup; required unless src is set", requiredWhen: func(task Task) bool { return task.Src == nil }},
+		},
+		Options: []string{"skip_disk_check"},
+	},
+	{
+		Name:           "repo-rename",
+		Description:    "Rename a repository (backup, recreate, restore)",
+		SchemaType:     "UpdateAction",
+		execute:        executeRepoRenameTask,
+		Tgt:            &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo_old", "repo_new"}, OptionalFields: credentialFields},
+		Options:        []string{"force", "keep_backup", "backup_id", "compress", "skip_disk_check"},
+		SupportsDryRun: true,
+		validate: func(task Task) error {
+			if task.Tgt.RepoOld == task.Tgt.RepoNew {
+				return &taskFieldError{Field: "tgt.repo_new", Message: "repo_old and repo_new must differ for repo-rename"}
+			}
+			return nil
+		},
+	},
+	{
+		Name:           "graph-rename",
+		Description:    "Rename a named graph (export, import, delete)",
+		SchemaType:     "UpdateAction",
+		execute:        executeGraphRenameTask,
+		Tgt:            &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo", "graph_old", "graph_new"}, OptionalFields: credentialFields},
+		Options:        []string{"compress"},
+		SupportsDryRun: true,
+		validate: func(task Task) error {
+			if task.Tgt.GraphOld == task.Tgt.GraphNew {
+				return &taskFieldError{Field: "tgt.graph_new", Message: "graph_old and graph_new must differ for graph-rename"}
+			}
+			return nil
+		},
+	},
+	{
+		Name:           "graph-move",
+		Description:    "Move a named graph to another repository under a new name, deleting the source graph",
+		SchemaType:     "TransferAction",
+		execute:        executeGraphMoveTask,
+		Src:            &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo", "graph"}, OptionalFields: credentialFields},
+		Tgt:            &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo", "graph_new"}, OptionalFields: credentialFields},
+		Options:        []string{"compress"},
+		SupportsDryRun: true,
+		validate: func(task Task) error {
+			if normalizeURL(task.Src.URL) == normalizeURL(task.Tgt.URL) && task.Src.Repo == task.Tgt.Repo && task.Src.Graph == task.Tgt.GraphNew {
+				return &taskFieldError{Field: "tgt.graph_new", Message: "src and tgt of graph-move are the same graph"}
+			}
+			return nil
+		},
+	},
+	{
+		Name:           "graph-merge",
+		Aliases:        []string{"graphs-merge"},
+		Description:    "Merge several named graphs into one target graph",
+		SchemaType:     "TransferAction",
+		execute:        executeGraphMergeTask,
+		Src:            &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo", "graphs"}, OptionalFields: credentialFields},
+		Tgt:            &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo", "graph"}, OptionalFields: credentialFields},
+		Options:        []string{"delete_sources", "continue_on_error"},
+		SupportsDryRun: true,
+	},
+	{
+		Name:        "graph-query-import",
+		Description: "Replace a named graph with the result of a CONSTRUCT/DESCRIBE query on src",
+		SchemaType:  "TransferAction",
+		execute:     executeGraphQueryImportTask,
+		Src:         &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo", "query"}, OptionalFields: credentialFields},
+		Tgt:         &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo", "graph"}, OptionalFields: credentialFields},
+	},
+	{
+		Name:           "graph-sync",
+		Description:    "Apply only the triple differences of a source graph to the target graph",
+		SchemaType:     "UpdateAction",
+		execute:        executeGraphSyncTask,
+		Src:            &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo", "graph"}, OptionalFields: credentialFields},
+		Tgt:            &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo"}, OptionalFields: append([]string{"graph"}, credentialFields...)},
+		SupportsDryRun: true,
+	},
+	{
+		Name:        "repo-restore-backup",
+		Description: "Recreate a repository from a backup retained by repo-rename with keep_backup",
+		SchemaType:  "CreateAction",
+		execute:     executeRepoRestoreBackupTask,
+		Tgt:         &actionEndpointSpec{Required: true, RequiredFields: []string{"url"}, OptionalFields: append([]string{"repo"}, credentialFields...)},
+		Options:     []string{"backup_id"},
+		validate: func(task Task) error {
+			if task.BackupID == "" {
+				return &taskFieldError{Field: "backup_id", Message: "backup_id is required for repo-restore-backup"}
+			}
+			return nil
+		},
+	},
+	{
+		Name:        "server-backup",
+		Description: "Back up the config and BRF data of all repositories of a server into one tar.gz archive",
+		SchemaType:  "CreateAction",
+		execute:     executeServerBackupTask,
+		Tgt:         &actionEndpointSpec{Required: true, RequiredFields: []string{"url"}, OptionalFields: append([]string{"repos", "pattern"}, credentialFields...)},
+		Options:     []string{"continue_on_error"},
+		validate: func(task Task) error {
+			if task.Tgt.Pattern == "" {
+				return nil
+			}
+			if len(task.Tgt.Repos) > 0 {
+				return &taskFieldError{Field: "tgt.pattern", Message: "tgt.repos and tgt.pattern cannot both be set for server-backup"}
+			}
+			if _, err := compileNamePattern(task.Tgt.Pattern); err != nil {
+				return &taskFieldError{Field: "tgt.pattern", Message: err.Error()}
+			}
+			return nil
+		},
+	},
+	{
+		Name:        "server-restore",
+		Description: "Recreate the repositories of an uploaded server-backup archive",
+		SchemaType:  "CreateAction",
+		execute:     executeServerRestoreTask,
+		Tgt:         &actionEndpointSpec{Required: true, RequiredFields: []string{"url"}, OptionalFields: append([]string{"repos", "exclude_repos"}, credentialFields...)},
+		Files: []actionFileSpec{
+			{Key: "task_{index}_files", Required: true, Description: "tar.gz archive produced by server-backup", check: checkUploadedServerBackup},
+		},
+		Options: []string{"force_recreate", "continue_on_error"},
+	},
+	{
+		Name:        "repo-clone",
+		Description: "Copy a repository (config and data) under a new name, server side when src and tgt are the same server",
+		SchemaType:  "CreateAction",
+		execute:     executeRepoCloneTask,
+		Src:         &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo"}, OptionalFields: credentialFields},
+		Tgt:         &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo"}, OptionalFields: credentialFields},
+		validate: func(task Task) error {
+			if normalizeURL(task.Src.URL) == normalizeURL(task.Tgt.URL) && task.Src.Repo == task.Tgt.Repo {
+				return &taskFieldError{Field: "tgt.repo", Message: "src and tgt of repo-clone are the same repository"}
+			}
+			return nil
+		},
+	},
+	{
+		Name:           "sparql-update",
+		Description:    "Run a SPARQL UPDATE (INSERT/DELETE) against a repository; requires SPARQL_UPDATE_ENABLED",
+		SchemaType:     "UpdateAction",
+		execute:        executeSparqlUpdateTask,
+		Tgt:            &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo", "update"}, OptionalFields: credentialFields},
+		SupportsDryRun: true,
+		validate:       validateSparqlUpdateTask,
+	},
+	{
+		Name:        "sparql-query",
+		Description: "Run a read-only SPARQL SELECT or ASK query against a repository and return the results",
+		SchemaType:  "SearchAction",
+		execute:     executeSparqlQueryTask,
+		Tgt:         &actionEndpointSpec{Required: true, RequiredFields: []string{"url", "repo", "query"}, OptionalFields: credentialFields},
+		Options:     []string{"limit"},
+		validate:    validateSparqlQueryTask,
+	},
+}
+
+// taskOptions are accepted by every action
+var taskOptions = []string{"retry_attempts", "retry_delay_ms", "timeout_seconds", "cluster_aware"}
+
+// actionHandlers maps every action name to its handler
+var actionHandlers = make(map[string]ActionHandler)
+
+func init() {
+	for i := range actionSpecs {
+		actionHandlers[actionSpecs[i].Name] = &actionSpecs[i]
+		for _, alias := range actionSpecs[i].Aliases {
+			actionHandlers[alias] = &actionSpecs[i]
+		}
+	}
+}
+
+// Validate checks that the src and tgt repositories required by the action are
+// present with all required fields, then runs the action specific checks.
+func (s *actionSpec) Validate(task Task) error {
+	if err := s.Src.check(s.Name, "src", task.Src); err != nil {
+		return err
+	}
+	if err := s.Tgt.check(s.Name, "tgt", task.Tgt); err != nil {
+		return err
+	}
+	if s.validate != nil {
+		return s.validate(task)
+	}
+	return nil
+}
+
+// Execute performs the action
+func (s *actionSpec) Execute(run *taskRun) error {
+	return s.execute(run)
+}
+
+// check verifies that a src or tgt repository required by an action is present
+// and has all required fields set
+func (e *actionEndpointSpec) check(action, role string, repo *Repository) error {
+	if e == nil {
+		return nil
+	}
+	if repo == nil {
+		if e.Required {
+			return &taskFieldError{Field: role, Message: fmt.Sprintf("%s is required for %s", role, action)}
+		}
+		return nil
+	}
+	for _, field := range e.RequiredFields {
+		if !repositoryFieldSet(repo, field) {
+			return &taskFieldError{Field: role + "." + field, Message: fmt.Sprintf("%s.%s is required for %s", role, field, action)}
+		}
+	}
+	return nil
+}
+
+// missingFiles returns the multipart file keys the action requires for the
+// task at taskIndex that are not present in files
+func (s *actionSpec) missingFiles(task Task, taskIndex int, files map[string][]*multipart.FileHeader) []string {
+	var missing []string
+	for _, f := range s.Files {
+		if !f.Required && (f.requiredWhen == nil || !f.requiredWhen(task)) {
+			continue
+		}
+		key := strings.ReplaceAll(f.Key, "{index}", strconv.Itoa(taskIndex))
+		if len(files[key]) == 0 {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// invalidFiles checks the number and the content of the uploaded files of the
+// task at taskIndex and returns an error per file key whose files are invalid
+func (s *actionSpec) invalidFiles(taskIndex int, files map[string][]*multipart.FileHeader) []*taskFieldError {
+	var invalid []*taskFieldError
+	for _, f := range s.Files {
+		key := strings.ReplaceAll(f.Key, "{index}", strconv.Itoa(taskIndex))
+		if f.maxFiles != nil {
+			if limit := f.maxFiles(); limit > 0 && len(files[key]) > limit {
+				invalid = append(invalid, &taskFieldError{Field: key, Message: fmt.Sprintf("%d files uploaded, more than the limit of %d", len(files[key]), limit)})
+				continue
+			}
+		}
+		if f.check == nil {
+			continue
+		}
+		for _, fileHeader := range files[key] {
+			if err := f.check(fileHeader); err != nil {
+				invalid = append(invalid, &taskFieldError{Field: key, Message: err.Error()})
+			}
+		}
+	}
+	return invalid
+}
+
+// repositoryFieldSet reports whether the Repository field with the given JSON name is set
+func repositoryFieldSet(repo *Repository, field string) bool {
+	switch field {
+	case "url":
+		return repo.URL != ""
+	case "repo":
+		return repo.Repo != ""
+	case "graph":
+		return repo.Graph != ""
+	case "graphs":
+		return len(repo.Graphs) > 0
+	case "repos":
+		return len(repo.Repos) > 0
+	case "repo_old":
+		return repo.RepoOld != ""
+	case "repo_new":
+		return repo.RepoNew != ""
+	case "graph_old":
+		return repo.GraphOld != ""
+	case "graph_new":
+		return repo.GraphNew != ""
+	case "query":
+		return repo.Query != ""
+	case "update":
+		return repo.Update != ""
+	}
+	return true
+}
+
+// listActionsREST handles REST GET /v1/api/actions
+//
+// Returns the description of every supported action so clients can build task
+// forms without reading the source, and the RDF formats with their MIME types.
+func listActionsREST(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"actions":      actionSpecs,
+		"task_options": taskOptions,
+		"rdf_formats":  rdfFormats(),
+	})
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// currentAPIVersion is the version of the request format of this service
+const currentAPIVersion = "v0.0.1"
+
+// supportedAPIVersions are the request format versions the service accepts.
+// Requests with another patch level of a supported version are accepted too:
+// patch releases do not change the request format.
+var supportedAPIVersions = []string{currentAPIVersion}
+
+// apiVersionPattern matches vMAJOR.MINOR.PATCH; the v and the patch level are optional
+var apiVersionPattern = regexp.MustCompile(`^[vV]?(\d+)\.(\d+)(?:\.(\d+))?$`)
+
+// normalizeAPIVersion checks the version of a request and returns it in the
+// form vMAJOR.MINOR.PATCH, e.g. v0.0.1 for "0.0.1" or " V0.0.1 ". A version
+// whose major and minor version match no supported version is rejected.
+func normalizeAPIVersion(version string) (string, error) {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return "", &taskFieldError{Field: "version", Message: "version is required"}
+	}
+	match := apiVersionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return "", &taskFieldError{Field: "version", Message: fmt.Sprintf("invalid version '%s', expected vMAJOR.MINOR.PATCH such as %s", version, currentAPIVersion)}
+	}
+	patch := match[3]
+	if patch == "" {
+		patch = "0"
+	}
+	normalized := fmt.Sprintf("v%s.%s.%s", trimLeadingZeros(match[1]), trimLeadingZeros(match[2]), trimLeadingZeros(patch))
+
+	release := normalized[:strings.LastIndex(normalized, ".")+1]
+	for _, supported := range supportedAPIVersions {
+		if strings.HasPrefix(supported, release) {
+			return normalized, nil
+		}
+	}
+	return "", &taskFieldError{Field: "version", Message: fmt.Sprintf("unsupported version '%s' (supported: %s)", version, strings.Join(supportedAPIVersions, ", "))}
+}
+
+// trimLeadingZeros returns a version number without leading zeros, "0" for zero
+func trimLeadingZeros(number string) string {
+	if trimmed := strings.TrimLeft(number, "0"); trimmed != "" {
+		return trimmed
+	}
+	return "0"
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"eve.evalgo.org/common"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// apiKeyHeader carries the API key of a request
+	apiKeyHeader = "x-api-key"
+	// apiKeyLabelContextKey stores the label of the matched API key in the echo context
+	apiKeyLabelContextKey = "api_key_label"
+	// defaultAPIKeyLabel labels the single key from GRAPHDB_API_KEY / --api-key
+	defaultAPIKeyLabel = "default"
+)
+
+// apiKey is one accepted API key and the label identifying its client
+type apiKey struct {
+	label string
+	key   string
+}
+
+// parseAPIKeys parses the GRAPHDB_API_KEYS value. It is either a JSON object
+// mapping labels to keys ({"ci": "k1", "ui": "k2"}) or a comma separated list
+// of label:key pairs; entries without a label are labelled key-1, key-2, ...
+func parseAPIKeys(raw string) ([]apiKey, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	var keys []apiKey
+	if strings.HasPrefix(raw, "{") {
+		var labelled map[string]string
+		if err := json.Unmarshal([]byte(raw), &labelled); err != nil {
+			return nil, fmt.Errorf("invalid GRAPHDB_API_KEYS JSON: %w", err)
+		}
+		for _, label := range sortedMapKeys(labelled) {
+			keys = append(keys, apiKey{label: label, key: labelled[label]})
+		}
+	} else {
+		for i, entry := range strings.Split(raw, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			label, key, found := strings.Cut(entry, ":")
+			if !found {
+				label, key = fmt.Sprintf("key-%d", i+1), entry
+			}
+			keys = append(keys, apiKey{label: strings.TrimSpace(label), key: strings.TrimSpace(key)})
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		if k.key == "" {
+			return nil, fmt.Errorf("API key '%s' is empty", k.label)
+		}
+		if seen[k.label] {
+			return nil, fmt.Errorf("duplicate API key label '%s'", k.label)
+		}
+		seen[k.label] = true
+	}
+	return keys, nil
+}
+
+// loadAPIKeys combines the single API key (labelled "default") with the keys of
+// GRAPHDB_API_KEYS. The label "default" is reserved for the single key, since
+// it is the default admin label and would otherwise be ambiguous.
+func loadAPIKeys(single, multiple string) ([]apiKey, error) {
+	keys, err := parseAPIKeys(multiple)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		if k.label == defaultAPIKeyLabel {
+			return nil, fmt.Errorf("API key label '%s' is reserved for GRAPHDB_API_KEY", defaultAPIKeyLabel)
+		}
+	}
+	if single != "" {
+		keys = append([]apiKey{{label: defaultAPIKeyLabel, key: single}}, keys...)
+	}
+	return keys, nil
+}
+
+// apiKeysMiddleware accepts requests whose x-api-key header matches any of keys
+// and stores the label of the matching key in the request context.
+func apiKeysMiddleware(keys []apiKey) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			provided := c.Request().Header.Get(apiKeyHeader)
+			if provided == "" {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Missing x-api-key header")
+			}
+
+			// Compare against every key so the time taken does not reveal which one matched
+			label := ""
+			for _, k := range keys {
+				if subtle.ConstantTimeCompare([]byte(provided), []byte(k.key)) == 1 {
+					label = k.label
+				}
+			}
+			if label == "" {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid API key")
+			}
+
+			c.Set(apiKeyLabelContextKey, label)
+			serviceLog.Debug("API key accepted", "api_key_label", label, "method", c.Request().Method, "path", c.Path())
+			return next(c)
+		}
+	}
+}
+
+// apiKeyLabel returns the label of the API key used for a request, or "" if
+// the request was not authenticated with an API key
+func apiKeyLabel(c echo.Context) string {
+	label, _ := c.Get(apiKeyLabelContextKey).(string)
+	return label
+}
+
+// adminKeyLabels returns the labels of the API keys with admin rights, set by
+// GRAPHDB_ADMIN_KEYS as a comma separated list. It defaults to the single key
+// of GRAPHDB_API_KEY (label "default").
+func adminKeyLabels() map[string]bool {
+	labels := make(map[string]bool)
+	for _, label := range strings.Split(common.GetEnv("GRAPHDB_ADMIN_KEYS", defaultAPIKeyLabel), ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			labels[label] = true
+		}
+	}
+	return labels
+}
+
+// isAdminRequest reports whether a request may use the admin endpoints: it was
+// sent with an admin API key, or the service runs without API keys at all
+func isAdminRequest(c echo.Context) bool {
+	label := apiKeyLabel(c)
+	return label == "" || adminKeyLabels()[label]
+}
+
+// adminOnlyMiddleware rejects requests that are not sent with an admin API key.
+// It runs after the API key middleware, which sets the key label.
+func adminOnlyMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !isAdminRequest(c) {
+			return echo.NewHTTPError(http.StatusForbidden, "API key has no admin rights (GRAPHDB_ADMIN_KEYS)")
+		}
+		return next(c)
+	}
+}
+
+// sortedMapKeys returns the keys of a string map in sorted order
+func sortedMapKeys(m map[string]string) []string {
+	set := make(map[string]bool, len(m))
+	for k := range m {
+		set[k] = true
+	}
+	return sortedKeys(set)
+}
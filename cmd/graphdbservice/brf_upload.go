@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+
+	"eve.evalgo.org/common"
+)
+
+// defaultUploadChunkSizeKB is the default of UPLOAD_CHUNK_SIZE_KB
+const defaultUploadChunkSizeKB = 1024
+
+// uploadProgressStage is the progress stage of repository data uploads; the
+// progress is counted in MB
+const uploadProgressStage = "Uploading repository data (MB)"
+
+// uploadChunkSize returns the size in bytes of the blocks repository data is
+// read in while it is uploaded to GraphDB, set by UPLOAD_CHUNK_SIZE_KB
+func uploadChunkSize() int {
+	kb := common.GetEnvInt("UPLOAD_CHUNK_SIZE_KB", defaultUploadChunkSizeKB)
+	if kb < 4 {
+		kb = 4
+	}
+	return kb << 10
+}
+
+// progressReader reads an upload in chunks and reports every MB sent.
+// A negative total means the size is unknown; the progress then reports the
+// bytes sent so far as total.
+type progressReader struct {
+	reader   io.Reader
+	total    int64
+	count    atomic.Int64 // May be read while the HTTP transport still sends
+	progress ProgressFunc
+}
+
+// newProgressReader returns a reader of r reading uploadChunkSize blocks.
+// progress may be nil.
+func newProgressReader(r io.Reader, total int64, progress ProgressFunc) *progressReader {
+	return &progressReader{reader: bufio.NewReaderSize(r, uploadChunkSize()), total: total, progress: progress}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	count := p.count.Add(int64(n))
+	if p.progress != nil && (count>>20 != (count-int64(n))>>20 || err == io.EOF) {
+		total := p.total
+		if total < 0 {
+			total = count
+		}
+		p.progress(uploadProgressStage, int(count>>20), int(total>>20))
+	}
+	return n, err
+}
+
+// brfUpload describes the upload of a BRF file into a repository
+type brfUpload struct {
+	Bytes         int64 // Size of the file
+	Attempts      int   // Number of times the upload was started
+	InterruptedAt int64 // Bytes sent before the last interrupted attempt
+}
+
+// setResult records the upload in a task result
+func (u brfUpload) setResult(result map[string]interface{}) {
+	result["data_size"] = u.Bytes
+	result["upload_attempts"] = u.Attempts
+	if u.Attempts > 1 {
+		result["interrupted_at"] = u.InterruptedAt
+	}
+}
+
+// graphDBRestoreRepositoryData imports a BRF file into a repository. The file
+// is streamed in uploadChunkSize blocks and the progress is reported in MB.
+//
+// GraphDB has no resumable upload: the statements of one request are imported
+// in a single transaction that is rolled back when the connection drops. The
+// request body can be reopened, so the retrying client restarts the upload from
+// the beginning of the file when the connection was refused; a POST that reached
+// GraphDB is not repeated (see retryHTTPTransport).
+func graphDBRestoreRepositoryData(client *http.Client, serverURL, username, password, repo, fileName string, progress ProgressFunc) (brfUpload, error) {
+	upload := brfUpload{}
+
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return upload, fmt.Errorf("failed to stat file %s: %w", fileName, err)
+	}
+	upload.Bytes = info.Size()
+
+	var current *progressReader
+	openBody := func() (io.ReadCloser, error) {
+		file, err := os.Open(fileName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file %s: %w", fileName, err)
+		}
+		if current != nil && current.count.Load() > 0 {
+			upload.InterruptedAt = current.count.Load()
+		}
+		upload.Attempts++
+		current = newProgressReader(file, upload.Bytes, progress)
+		return struct {
+			io.Reader
+			io.Closer
+		}{current, file}, nil
+	}
+
+	body, err := openBody()
+	if err != nil {
+		return upload, err
+	}
+
+	endpoint := fmt.Sprintf("%s/repositories/%s/statements", normalizeURL(serverURL), url.PathEscape(repo))
+	req, err := http.NewRequest(http.MethodPost, endpoint, body)
+	if err != nil {
+		_ = body.Close()
+		return upload, err
+	}
+	req.GetBody = openBody
+	req.ContentLength = upload.Bytes
+	req.Header.Set("Content-Type", brfContentType)
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return upload, fmt.Errorf("failed to restore repository data: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 300 {
+		return upload, graphDBStatusError(resp.StatusCode, "restoring data into repository '%s' failed with status %d: %s", repo, resp.StatusCode, readErrorBody(resp))
+	}
+	return upload, nil
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"eve.evalgo.org/common"
+)
+
+const (
+	// defaultCallbackAttempts is the default number of callback delivery attempts
+	defaultCallbackAttempts = 5
+	// callbackTimeout bounds a single callback delivery attempt
+	callbackTimeout = 30 * time.Second
+	// callbackSignatureHeader carries the HMAC-SHA256 signature of the callback body
+	callbackSignatureHeader = "X-Signature-256"
+	// callbackSessionHeader carries the session ID the callback belongs to
+	callbackSessionHeader = "X-Session-ID"
+)
+
+// callbackSecrets maps the label of every API key to the key. A callback is
+// signed with the key of the request that asked for it.
+var callbackSecrets = map[string]string{}
+
+// setCallbackSecrets sets the API keys callbacks are signed with
+func setCallbackSecrets(keys []apiKey) {
+	secrets := make(map[string]string, len(keys))
+	for _, k := range keys {
+		secrets[k.label] = k.key
+	}
+	callbackSecrets = secrets
+}
+
+// validateCallbackURL checks that a callback URL is an absolute http(s) URL
+// and that the callback can be signed, which requires API keys. Hosts that
+// resolve to a loopback, private or link-local address are rejected unless
+// they are listed in CALLBACK_ALLOWED_HOSTS.
+func validateCallbackURL(raw string) error {
+	if len(callbackSecrets) == 0 {
+		return fmt.Errorf("callback_url requires API keys (GRAPHDB_API_KEY or GRAPHDB_API_KEYS) to sign the callback")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid callback_url: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid callback_url: must be an absolute http or https URL")
+	}
+
+	host := u.Hostname()
+	if callbackHostAllowed(host) {
+		return nil
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return fmt.Errorf("invalid callback_url: cannot resolve %s: %w", host, err)
+	}
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+			return fmt.Errorf("invalid callback_url: %s resolves to the internal address %s, list the host in CALLBACK_ALLOWED_HOSTS to allow it", host, ip)
+		}
+	}
+	return nil
+}
+
+// callbackHostAllowed reports whether host is listed in CALLBACK_ALLOWED_HOSTS,
+// a comma separated list of host names and IP addresses
+func callbackHostAllowed(host string) bool {
+	for _, allowed := range strings.Split(common.GetEnv("CALLBACK_ALLOWED_HOSTS", ""), ",") {
+		if allowed = strings.TrimSpace(allowed); allowed != "" && strings.EqualFold(allowed, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// signCallbackPayload returns the hex encoded HMAC-SHA256 of payload
+func signCallbackPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// runAsyncMigration executes the tasks of a request in the background, finalizes
+// its session and posts the results to the request's callback URL, signed with
+// the API key labelled keyLabel.
+func runAsyncMigration(sessionID, keyLabel string, req MigrationRequest, slot *sessionSlot) {
+	log := serviceLog.With("session_id", sessionID)
+
+	results, errs := executeMigrationTasks(req, nil, !req.Parallel, sessionID, slot)
+	if len(errs) > 0 && errors.Is(errs[0], errSessionSuspended) {
+		// Resumed at the next start, which delivers the callback
+		return
+	}
+
+	status, _ := migrationStatus(results, errs)
+	for i, err := range errs {
+		if err != nil && !errors.Is(err, errTaskCancelled) {
+			log.Error("Async migration task failed", "task_index", i, "error", err)
+		}
+	}
+
+	finishMigrationSession(sessionID)
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"session_id":   sessionID,
+		"status":       status,
+		"version":      req.Version,
+		"results":      results,
+		"completed_at": time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		log.Error("Failed to encode callback payload", "error", err)
+		return
+	}
+
+	if err := deliverCallback(req.CallbackURL, sessionID, keyLabel, payload); err != nil {
+		log.Error("Callback delivery failed", "callback_url", req.CallbackURL, "error", err)
+		return
+	}
+	log.Info("Callback delivered", "callback_url", req.CallbackURL, "status", status)
+}
+
+// deliverCallback POSTs payload to callbackURL, retrying with exponential backoff
+// until the receiver answers with a 2xx status or CALLBACK_RETRY_ATTEMPTS is reached.
+// The payload is signed with the API key labelled keyLabel.
+func deliverCallback(callbackURL, sessionID, keyLabel string, payload []byte) error {
+	secret := callbackSecrets[keyLabel]
+	if secret == "" {
+		return fmt.Errorf("no API key labelled %q to sign the callback", keyLabel)
+	}
+	attempts := common.GetEnvInt("CALLBACK_RETRY_ATTEMPTS", defaultCallbackAttempts)
+	if attempts < 1 {
+		attempts = 1
+	}
+	client := &http.Client{Timeout: callbackTimeout}
+	delay := time.Second
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		lastErr = postCallback(client, callbackURL, sessionID, secret, payload)
+		if lastErr == nil {
+			return nil
+		}
+		debugLog("Callback to %s failed (attempt %d/%d): %v", callbackURL, attempt, attempts, lastErr)
+		if attempt < attempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", attempts, lastErr)
+}
+
+// postCallback performs a single callback delivery attempt
+func postCallback(client *http.Client, callbackURL, sessionID, secret string, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, callbackURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(callbackSessionHeader, sessionID)
+	req.Header.Set(callbackSignatureHeader, "sha256="+signCallbackPayload(secret, payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback receiver returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"net/http"
+	"sync"
+
+	"eve.evalgo.org/db"
+)
+
+// clientCacheKey identifies a Ziti client by identity file and service
+type clientCacheKey struct {
+	identityFile string
+	serviceURL   string
+}
+
+// zitiClientCache reuses Ziti HTTP clients across tasks and requests, so repeated
+// tasks against the same GraphDB server do not rebuild the Ziti context.
+type zitiClientCache struct {
+	mu      sync.Mutex
+	clients map[clientCacheKey]*http.Client
+}
+
+// graphDBClients is the process wide cache of Ziti clients
+var graphDBClients = &zitiClientCache{clients: make(map[clientCacheKey]*http.Client)}
+
+// get returns the cached client for the identity and service, creating it on first use.
+// The returned client is shared and must not be modified; wrap it with a copy instead.
+func (c *zitiClientCache) get(identity, serviceURL string) (*http.Client, error) {
+	key := clientCacheKey{identityFile: identity, serviceURL: serviceURL}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if client, exists := c.clients[key]; exists {
+		return client, nil
+	}
+
+	client, err := db.GraphDBZitiClient(identity, serviceURL)
+	if err != nil {
+		return nil, err
+	}
+	debugLog("Created Ziti client for service %s", serviceURL)
+	c.clients[key] = client
+	return client, nil
+}
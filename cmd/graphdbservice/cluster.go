@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// clusterNodeLeader is the nodeState of the GraphDB cluster node accepting writes
+const clusterNodeLeader = "LEADER"
+
+// errNoCluster is returned for servers that are not part of a GraphDB cluster
+var errNoCluster = errors.New("server is not part of a GraphDB cluster")
+
+// ClusterNodeStatus is one node reported by /rest/cluster/group/status.
+// Address is the cluster RPC address, Endpoint the HTTP URL of the node.
+type ClusterNodeStatus struct {
+	Address   string `json:"address"`
+	NodeState string `json:"nodeState"`
+	Term      int    `json:"term"`
+	Endpoint  string `json:"endpoint"`
+}
+
+// graphDBClusterStatus queries /rest/cluster/group/status of a GraphDB server.
+// Servers without cluster answer 404 (or 412 while no group is configured),
+// which is reported as errNoCluster.
+func graphDBClusterStatus(client *http.Client, serverURL, username, password string) ([]ClusterNodeStatus, error) {
+	endpoint := normalizeURL(serverURL) + "/rest/cluster/group/status"
+
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusPreconditionFailed:
+		return nil, errNoCluster
+	default:
+		return nil, fmt.Errorf("cluster status request failed with status %d: %s", resp.StatusCode, readErrorBody(resp))
+	}
+
+	var nodes []ClusterNodeStatus
+	if err := json.NewDecoder(resp.Body).Decode(&nodes); err != nil {
+		return nil, fmt.Errorf("failed to parse cluster status: %w", err)
+	}
+	if len(nodes) == 0 {
+		return nil, errNoCluster
+	}
+	return nodes, nil
+}
+
+// clusterLeaderURL returns the HTTP endpoint of the leader among nodes
+func clusterLeaderURL(nodes []ClusterNodeStatus) (string, error) {
+	for _, node := range nodes {
+		if node.NodeState != clusterNodeLeader {
+			continue
+		}
+		if node.Endpoint == "" {
+			return "", fmt.Errorf("cluster leader %s reports no HTTP endpoint", node.Address)
+		}
+		return node.Endpoint, nil
+	}
+	return "", errors.New("cluster has no leader")
+}
+
+// resolveClusterLeader points the tgt repository of a cluster_aware task at the
+// cluster leader, since every action writes to tgt and GraphDB only accepts
+// writes on the leader. A src on the same server as tgt follows it, keeping
+// server side copies possible. If the cluster status cannot be determined the
+// provided URL is kept and a warning added to the result.
+func resolveClusterLeader(run *taskRun) {
+	tgt := run.task.Tgt
+	if tgt == nil || tgt.URL == "" {
+		return
+	}
+
+	client := run.tgtClient
+	if identityFile != "" {
+		serviceURL, err := URL2ServiceRobust(tgt.URL)
+		if err == nil {
+			client, err = run.zitiClient(serviceURL)
+		}
+		if err != nil {
+			clusterFallback(run, err)
+			return
+		}
+	}
+
+	nodes, err := graphDBClusterStatus(client, tgt.URL, tgt.Username, tgt.Password)
+	if err != nil {
+		clusterFallback(run, err)
+		return
+	}
+	for _, node := range nodes {
+		if normalizeURL(node.Endpoint) == normalizeURL(tgt.URL) {
+			run.result["cluster_node_state"] = node.NodeState
+		}
+	}
+	leaderURL, err := clusterLeaderURL(nodes)
+	if err != nil {
+		clusterFallback(run, err)
+		return
+	}
+
+	run.result["cluster_leader"] = leaderURL
+	if normalizeURL(leaderURL) == normalizeURL(tgt.URL) {
+		return
+	}
+
+	run.log.Info("Writing to cluster leader", "url", redactURL(tgt.URL), "leader", leaderURL)
+	leaderTgt := *tgt
+	leaderTgt.URL = leaderURL
+	run.task.Tgt = &leaderTgt
+	if src := run.task.Src; src != nil && normalizeURL(src.URL) == normalizeURL(tgt.URL) {
+		leaderSrc := *src
+		leaderSrc.URL = leaderURL
+		run.task.Src = &leaderSrc
+	}
+}
+
+// clusterFallback records that the provided tgt URL is used because the
+// cluster leader could not be determined
+func clusterFallback(run *taskRun, err error) {
+	run.log.Warn("Cluster leader not determined, using the provided URL", "url", redactURL(run.task.Tgt.URL), "error", err)
+	addResultWarning(run.result, fmt.Sprintf("cluster leader not determined (%v), using tgt.url", err))
+}
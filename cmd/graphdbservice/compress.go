@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"eve.evalgo.org/common"
+)
+
+// defaultCompressThresholdMB is the export file size from which intermediate
+// files are gzipped when a task does not set compress
+const defaultCompressThresholdMB = 64
+
+// exportCompression collects the sizes of the intermediate export files of a task
+type exportCompression struct {
+	files             int
+	compressedFiles   int
+	uncompressedBytes int64
+	compressedBytes   int64
+}
+
+// shouldCompressExport decides whether an export file of size bytes is gzipped.
+// Task.Compress forces the decision, otherwise files from
+// EXPORT_COMPRESS_THRESHOLD_MB (default 64) on are compressed.
+func shouldCompressExport(task Task, size int64) bool {
+	if task.Compress != nil {
+		return *task.Compress
+	}
+	thresholdMB := common.GetEnvInt("EXPORT_COMPRESS_THRESHOLD_MB", defaultCompressThresholdMB)
+	return size >= int64(thresholdMB)<<20
+}
+
+// compressExport gzips an exported file if the task asks for it and returns
+// the name of the file to import, which ends in .gz when it was compressed.
+// The uncompressed file is removed after a successful compression.
+func (c *exportCompression) compressExport(task Task, fileName string) (string, error) {
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return "", err
+	}
+	c.files++
+	c.uncompressedBytes += info.Size()
+	if !shouldCompressExport(task, info.Size()) {
+		c.compressedBytes += info.Size()
+		return fileName, nil
+	}
+
+	gzName, size, err := gzipFile(fileName)
+	if err != nil {
+		return "", err
+	}
+	c.compressedFiles++
+	c.compressedBytes += size
+	return gzName, nil
+}
+
+// report adds the export sizes to a task result once a file was compressed
+func (c *exportCompression) report(result map[string]interface{}) {
+	if c.compressedFiles == 0 {
+		return
+	}
+	result["compressed_files"] = c.compressedFiles
+	result["export_uncompressed_bytes"] = c.uncompressedBytes
+	result["export_compressed_bytes"] = c.compressedBytes
+}
+
+// gzipFile compresses fileName to fileName.gz, removes the original and returns
+// the new file name and its size
+func gzipFile(fileName string) (string, int64, error) {
+	in, err := os.Open(fileName)
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() { _ = in.Close() }()
+
+	gzName := fileName + ".gz"
+	out, err := os.Create(gzName)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create %s: %w", gzName, err)
+	}
+
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(gzName)
+		return "", 0, fmt.Errorf("failed to compress %s: %w", fileName, err)
+	}
+
+	info, err := os.Stat(gzName)
+	if err != nil {
+		return "", 0, err
+	}
+	_ = in.Close()
+	_ = os.Remove(fileName)
+	return gzName, info.Size(), nil
+}
+
+// isGzipFile reports whether an intermediate file was compressed by gzipFile
+func isGzipFile(fileName string) bool {
+	return strings.HasSuffix(fileName, ".gz")
+}
+
+// importExportedGraph loads an intermediate export file of contentType into an
+// empty graph. Uncompressed RDF/XML files use graphDBAPI.ImportGraphRdf; gzipped
+// files are decompressed while they are uploaded, without a second copy on disk.
+func importExportedGraph(client *http.Client, serverURL, username, password, repo, graph, fileName, contentType string) error {
+	if !isGzipFile(fileName) {
+		if contentType == rdfContentTypes["rdf-xml"] {
+			return graphDBWith(client).ImportGraphRdf(serverURL, username, password, repo, graph, fileName)
+		}
+		return graphDBAppendGraphRdf(client, serverURL, username, password, repo, graph, fileName, contentType)
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", fileName, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to read compressed file %s: %w", fileName, err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	return graphDBAppendGraphData(client, serverURL, username, password, repo, graph, zr, -1, contentType)
+}
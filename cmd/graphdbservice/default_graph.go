@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"eve.evalgo.org/db"
+)
+
+// defaultGraphName addresses the default (unnamed) graph of a repository in
+// graph, graph_old, graph_new and graphs
+const defaultGraphName = "default"
+
+// sesameNil is the IRI GraphDB uses for the default graph in SPARQL
+const sesameNil = "http://www.openrdf.org/schema/sesame#nil"
+
+// isDefaultGraph reports whether a graph name denotes the default graph:
+// "default" or GraphDB's sesame:nil and rdf4j:nil, prefixed or as full IRI
+func isDefaultGraph(graph string) bool {
+	switch graph {
+	case defaultGraphName, "sesame:nil", "rdf4j:nil", sesameNil, "http://rdf4j.org/schema/rdf4j#nil":
+		return true
+	}
+	return false
+}
+
+// graphStoreURL returns the endpoint holding the statements of a graph: the
+// SPARQL Graph Store service for a named graph, and the statements endpoint
+// restricted to the null context for the default graph. The statements
+// endpoint without a context would address the whole repository instead.
+func graphStoreURL(serverURL, repo, graph string) string {
+	base := fmt.Sprintf("%s/repositories/%s", normalizeURL(serverURL), url.PathEscape(repo))
+	if isDefaultGraph(graph) {
+		return base + "/statements?context=null"
+	}
+	return base + "/rdf-graphs/service?graph=" + url.QueryEscape(graph)
+}
+
+// sparqlGraphIRI returns the IRI of a graph for GRAPH patterns of SPARQL
+// queries and updates, sesame:nil for the default graph. Graph names may come
+// from GraphDB listings rather than validated requests, so a name with
+// characters that cannot appear in an IRI, such as '>' or whitespace, is
+// rejected instead of being written into the query.
+func sparqlGraphIRI(graph string) (string, error) {
+	if isDefaultGraph(graph) {
+		return "<" + sesameNil + ">", nil
+	}
+	if err := checkGraphIRI(graph, true); err != nil {
+		return "", err
+	}
+	return "<" + graph + ">", nil
+}
+
+// sparqlGraphRef returns the graph reference of SPARQL graph management
+// operations such as COPY: DEFAULT for the default graph, GRAPH <iri> otherwise
+func sparqlGraphRef(graph string) (string, error) {
+	if isDefaultGraph(graph) {
+		return "DEFAULT", nil
+	}
+	if err := checkGraphIRI(graph, true); err != nil {
+		return "", err
+	}
+	return "GRAPH <" + graph + ">", nil
+}
+
+// repositoryGraphs returns the graphs to transfer with a whole repository: the
+// named graphs of its listing, and "default" first if the default graph has
+// statements. GraphDB does not list the default graph as a context.
+func repositoryGraphs(client *http.Client, serverURL, username, password, repo string, listing *db.GraphDBResponse) []string {
+	var graphs []string
+	if count, err := countGraphTriples(client, serverURL, username, password, repo, defaultGraphName); err == nil && count > 0 {
+		graphs = append(graphs, defaultGraphName)
+	}
+	for _, bind := range listing.Results.Bindings {
+		if graphURI := bind.ContextID.Value; graphURI != "" {
+			graphs = append(graphs, graphURI)
+		}
+	}
+	return graphs
+}
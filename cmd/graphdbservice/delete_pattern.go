@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// namePatternRegexPrefix marks a tgt.pattern as regular expression instead of a glob
+const namePatternRegexPrefix = "re:"
+
+// compileNamePattern compiles the tgt.pattern of repo-delete and graph-delete.
+// A pattern is a glob in which * matches any sequence of characters (including
+// "/" in graph URIs) and ? matches one character; with the prefix "re:" the rest
+// is a regular expression. Both must match the whole name.
+func compileNamePattern(pattern string) (*regexp.Regexp, error) {
+	if expr, found := strings.CutPrefix(pattern, namePatternRegexPrefix); found {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+		}
+		return re, nil
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String()), nil
+}
+
+// matchNames returns the names matched by re, in their original order
+func matchNames(re *regexp.Regexp, names []string) []string {
+	matched := make([]string, 0)
+	for _, name := range names {
+		if re.MatchString(name) {
+			matched = append(matched, name)
+		}
+	}
+	return matched
+}
+
+// validateDeletePattern checks that a repo-delete or graph-delete task names
+// either tgt.<field> or tgt.pattern, and that a pattern is valid and confirmed.
+// A dry run needs no confirmation, so the matches can be previewed.
+func validateDeletePattern(task Task, field, value string) error {
+	if task.Tgt.Pattern == "" {
+		if value == "" {
+			return &taskFieldError{Field: "tgt." + field, Message: fmt.Sprintf("tgt.%s or tgt.pattern is required for %s", field, task.Action)}
+		}
+		return nil
+	}
+	if value != "" {
+		return &taskFieldError{Field: "tgt.pattern", Message: fmt.Sprintf("tgt.%s and tgt.pattern cannot both be set for %s", field, task.Action)}
+	}
+	if _, err := compileNamePattern(task.Tgt.Pattern); err != nil {
+		return &taskFieldError{Field: "tgt.pattern", Message: err.Error()}
+	}
+	if !task.ConfirmPattern && !task.DryRun {
+		return &taskFieldError{Field: "confirm_pattern", Message: fmt.Sprintf("%s with tgt.pattern requires \"confirm_pattern\": true", task.Action)}
+	}
+	return nil
+}
+
+// deleteMatchingNames deletes every name with deleteName. kind ("repository"
+// or "graph") is used in progress and error messages. With continue_on_error
+// a failing deletion does not stop the rest and is returned in failed.
+func deleteMatchingNames(run *taskRun, kind string, names []string, deleteName func(name string) error) (deleted, failed []string, err error) {
+	deleted = make([]string, 0, len(names))
+	for i, name := range names {
+		run.progress(fmt.Sprintf("Deleting matching %s", kind), i+1, len(names))
+		if err := deleteName(name); err != nil {
+			if !run.task.ContinueOnError {
+				return deleted, failed, fmt.Errorf("failed to delete %s '%s' after deleting %d of %d matches: %w", kind, name, len(deleted), len(names), err)
+			}
+			run.log.Warn("Failed to delete "+kind, kind, name, "error", err)
+			failed = append(failed, name)
+			continue
+		}
+		deleted = append(deleted, name)
+	}
+	return deleted, failed, nil
+}
+
+// executeRepoDeleteByPattern deletes all repositories of the tgt server whose
+// names match tgt.pattern (repo-delete with a pattern).
+func executeRepoDeleteByPattern(run *taskRun, tgtClient *http.Client) error {
+	task, result := run.task, run.result
+
+	re, err := compileNamePattern(task.Tgt.Pattern)
+	if err != nil {
+		return err
+	}
+	repos, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	if err != nil {
+		return fmt.Errorf("failed to fetch repositories from %s: %w", task.Tgt.URL, err)
+	}
+	matched := matchNames(re, getRepositoryNames(repos.Results.Bindings))
+	result["pattern"] = task.Tgt.Pattern
+
+	if task.DryRun {
+		operations := make([]map[string]interface{}, 0, len(matched))
+		for _, name := range matched {
+			operations = append(operations, plannedOperation("delete-repository", name, "", -1))
+		}
+		setDryRunResult(result, fmt.Sprintf("Dry run: %d repositories would be deleted", len(matched)), operations)
+		result["matched_repositories"] = matched
+		return nil
+	}
+
+	deleted, failed, err := deleteMatchingNames(run, "repository", matched, func(name string) error {
+		return run.graphDB(tgtClient).DeleteRepository(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, name)
+	})
+	if err != nil {
+		return err
+	}
+
+	result["message"] = fmt.Sprintf("Deleted %d repositories matching '%s'", len(deleted), task.Tgt.Pattern)
+	if len(failed) > 0 {
+		result["status"] = "partial"
+		result["failed_repositories"] = failed
+	}
+	result["deleted_repositories"] = deleted
+	return nil
+}
+
+// executeGraphDeleteByPattern deletes all graphs of tgt.repo whose URIs match
+// tgt.pattern (graph-delete with a pattern).
+func executeGraphDeleteByPattern(run *taskRun, tgtClient *http.Client) error {
+	task, result := run.task, run.result
+
+	re, err := compileNamePattern(task.Tgt.Pattern)
+	if err != nil {
+		return err
+	}
+	if err := requireTaskRepository(run.graphDB(tgtClient), task.Tgt, "tgt"); err != nil {
+		return err
+	}
+	graphs, err := run.graphDB(tgtClient).ListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
+	if err != nil {
+		return fmt.Errorf("failed to list graphs in repository '%s': %w", task.Tgt.Repo, err)
+	}
+	graphURIs := make([]string, 0, len(graphs.Results.Bindings))
+	for _, bind := range graphs.Results.Bindings {
+		graphURIs = append(graphURIs, bind.ContextID.Value)
+	}
+	matched := matchNames(re, graphURIs)
+	result["pattern"] = task.Tgt.Pattern
+	result["repository"] = task.Tgt.Repo
+
+	if task.DryRun {
+		operations := make([]map[string]interface{}, 0, len(matched))
+		for _, graphURI := range matched {
+			count, _ := countGraphTriples(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, graphURI)
+			operations = append(operations, plannedOperation("delete-graph", task.Tgt.Repo, graphURI, count))
+		}
+		setDryRunResult(result, fmt.Sprintf("Dry run: %d graphs would be deleted", len(matched)), operations)
+		result["matched_graphs"] = matched
+		return nil
+	}
+
+	deleted, failed, err := deleteMatchingNames(run, "graph", matched, func(graphURI string) error {
+		return run.graphDB(tgtClient).DeleteGraph(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, graphURI)
+	})
+	if err != nil {
+		return err
+	}
+
+	result["message"] = fmt.Sprintf("Deleted %d graphs matching '%s'", len(deleted), task.Tgt.Pattern)
+	if len(failed) > 0 {
+		result["status"] = "partial"
+		result["failed_graphs"] = failed
+	}
+	result["deleted_graphs"] = deleted
+	return nil
+}
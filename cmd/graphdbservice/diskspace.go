@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// estimatedBytesPerStatement estimates the size of one exported statement. It is
+// deliberately low, RDF/XML exports of real data are usually larger, so the
+// check only fails when the space is obviously insufficient.
+const estimatedBytesPerStatement = 100
+
+// errDiskSpaceUnsupported is returned by freeDiskSpace on platforms without statfs
+var errDiskSpaceUnsupported = errors.New("free disk space cannot be determined on this platform")
+
+// repositorySize is the statement count of a repository reported by
+// /rest/repositories/{id}/size
+type repositorySize struct {
+	Explicit int64 `json:"explicit"`
+	Inferred int64 `json:"inferred"`
+	Total    int64 `json:"total"`
+}
+
+// graphDBRepositorySize queries the statement counts of a repository
+func graphDBRepositorySize(client *http.Client, serverURL, username, password, repo string) (*repositorySize, error) {
+	endpoint := fmt.Sprintf("%s/rest/repositories/%s/size", normalizeURL(serverURL), url.PathEscape(repo))
+
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("size request failed with status %d: %s", resp.StatusCode, readErrorBody(resp))
+	}
+	var size repositorySize
+	if err := json.NewDecoder(resp.Body).Decode(&size); err != nil {
+		return nil, fmt.Errorf("failed to parse repository size: %w", err)
+	}
+	return &size, nil
+}
+
+// checkExportDiskSpace fails a task before it writes the data of a repository
+// when the free space of the directory receiving it is below the estimated size
+// of the export. That is the temp directory, or with keep_backup of repo-rename
+// the backup directory below MIGRATION_LOG_DIR, which keeps the BRF data of the
+// repository and all graph exports, so twice the estimate. The check is skipped
+// with a warning if the repository size or the free space cannot be determined,
+// and entirely with skip_disk_check.
+func checkExportDiskSpace(run *taskRun, client *http.Client, conn *Repository, repo string) error {
+	if run.task.SkipDiskCheck {
+		return nil
+	}
+
+	dir, kind, copies, hint := run.tempDir, "temp", uint64(1), "set temp_dir or MIGRATION_TEMP_DIR to a larger volume"
+	if run.task.KeepBackup && migrationLogger != nil {
+		dir, kind, copies, hint = migrationLogger.backupRoot(), "backup", 2, "set MIGRATION_LOG_DIR to a larger volume"
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return fmt.Errorf("failed to create backup directory: %w", err)
+		}
+	}
+
+	size, err := graphDBRepositorySize(client, conn.URL, conn.Username, conn.Password, repo)
+	if err != nil {
+		run.log.Warn("Disk space check skipped", "repository", repo, "error", err)
+		addResultWarning(run.result, fmt.Sprintf("disk space check skipped: size of repository '%s' unknown (%v)", repo, err))
+		return nil
+	}
+	free, err := freeDiskSpace(dir)
+	if err != nil {
+		run.log.Warn("Disk space check skipped", kind+"_dir", dir, "error", err)
+		addResultWarning(run.result, fmt.Sprintf("disk space check skipped: free space of the %s directory unknown (%v)", kind, err))
+		return nil
+	}
+
+	required := copies * uint64(size.Explicit) * estimatedBytesPerStatement
+	run.result[kind+"_free_bytes"] = free
+	run.result[kind+"_required_bytes"] = required
+	if free < required {
+		return fmt.Errorf("not enough disk space in %s directory %s: %s free, repository '%s' with %d statements needs about %s (%s, or skip_disk_check to run anyway)",
+			kind, dir, formatBytes(free), repo, size.Explicit, formatBytes(required), hint)
+	}
+	return nil
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. "1.5 GiB"
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd)
+
+package cmd
+
+// freeDiskSpace is not implemented on this platform; the disk space check of
+// tasks is skipped with a warning
+func freeDiskSpace(dir string) (uint64, error) {
+	return 0, errDiskSpaceUnsupported
+}
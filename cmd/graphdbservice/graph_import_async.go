@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"eve.evalgo.org/common"
+)
+
+// asyncImportStage is the progress stage of imports through the GraphDB import
+// API. The number of statements is not known in advance, so the progress
+// counts the statements added so far and its total is 0.
+const asyncImportStage = "Importing statements"
+
+// Default of GRAPHDB_IMPORT_POLL_MS: the interval of the import status requests
+const defaultImportPollMs = 2000
+
+// errAsyncImportUnavailable is returned when a GraphDB server does not offer
+// the server-side import API, e.g. before version 9 or behind a proxy
+var errAsyncImportUnavailable = errors.New("the GraphDB import API is unavailable")
+
+// importStatus is the status of a server-side import in GraphDB's import
+// listing. GraphDB 10 counts the statements while the import runs.
+type importStatus struct {
+	Name              string `json:"name"`
+	Status            string `json:"status"` // NONE, PENDING, IMPORTING, DONE, ERROR or INTERRUPTING
+	Message           string `json:"message"`
+	Context           string `json:"context"`
+	AddedStatements   int64  `json:"addedStatements"`
+	RemovedStatements int64  `json:"removedStatements"`
+}
+
+// importSettings are the settings of a file uploaded to the import API. An
+// empty format lets GraphDB derive it from the extension of the name, and an
+// empty context imports into the default graph or the graphs of a quad file.
+type importSettings struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Context     string `json:"context"`
+	BaseURI     string `json:"baseURI,omitempty"`
+	ForceSerial bool   `json:"forceSerial"`
+}
+
+// importPollInterval returns the interval between two import status requests
+func importPollInterval() time.Duration {
+	ms := common.GetEnvInt("GRAPHDB_IMPORT_POLL_MS", defaultImportPollMs)
+	if ms <= 0 {
+		ms = defaultImportPollMs
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// importAPIURL returns an endpoint of the import API of a repository
+func importAPIURL(serverURL, repo, path string) string {
+	return fmt.Sprintf("%s/rest/repositories/%s/import/upload%s", normalizeURL(serverURL), url.PathEscape(repo), path)
+}
+
+// graphDBStartImport uploads a file to the import API of a repository, which
+// imports it in the background under the name of the settings. The file is
+// streamed, so it is not held in memory. A server without the import API
+// returns errAsyncImportUnavailable.
+func graphDBStartImport(ctx context.Context, client *http.Client, serverURL, username, password, repo, fileName string, settings importSettings) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", fileName, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		err := func() error {
+			part, err := form.CreateFormField("importSettings")
+			if err != nil {
+				return err
+			}
+			if err := json.NewEncoder(part).Encode(settings); err != nil {
+				return err
+			}
+			part, err = form.CreateFormFile("file", settings.Name)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(part, file); err != nil {
+				return err
+			}
+			return form.Close()
+		}()
+		_ = writer.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, importAPIURL(serverURL, repo, "/file"), body)
+	if err != nil {
+		_ = body.Close()
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := client.Do(req)
+	_ = body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to upload %s to the GraphDB import API: %w", settings.Name, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented:
+		return errAsyncImportUnavailable
+	case resp.StatusCode >= 300:
+		return graphDBStatusError(resp.StatusCode, "starting the import of %s into repository '%s' failed with status %d: %s", settings.Name, repo, resp.StatusCode, readErrorBody(resp))
+	}
+	return nil
+}
+
+// graphDBImportStatus returns the status of the server-side import with the
+// given name, and false if GraphDB does not list it
+func graphDBImportStatus(ctx context.Context, client *http.Client, serverURL, username, password, repo, name string) (importStatus, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, importAPIURL(serverURL, repo, ""), nil)
+	if err != nil {
+		return importStatus{}, false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return importStatus{}, false, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return importStatus{}, false, graphDBStatusError(resp.StatusCode, "import status request for repository '%s' failed with status %d: %s", repo, resp.StatusCode, readErrorBody(resp))
+	}
+
+	var statuses []importStatus
+	if err := json.NewDecoder(resp.Body).Decode(&statuses); err != nil {
+		return importStatus{}, false, fmt.Errorf("failed to parse import status: %w", err)
+	}
+	for _, status := range statuses {
+		if status.Name == name {
+			return status, true, nil
+		}
+	}
+	return importStatus{}, false, nil
+}
+
+// graphDBInterruptImport asks GraphDB to stop a running server-side import
+func graphDBInterruptImport(client *http.Client, serverURL, username, password, repo, name string) error {
+	req, err := http.NewRequest(http.MethodDelete, importAPIURL(serverURL, repo, "")+"?name="+url.QueryEscape(name), nil)
+	if err != nil {
+		return err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		return graphDBStatusError(resp.StatusCode, "interrupting the import of %s failed with status %d: %s", name, resp.StatusCode, readErrorBody(resp))
+	}
+	return nil
+}
+
+// graphDBAsyncImport imports a file through the import API of a repository
+// into graph, or into the graphs of a quad file for an empty graph. It polls
+// the import status until GraphDB finished and reports the statements added
+// so far to progress. A cancelled ctx interrupts the import on the server.
+func graphDBAsyncImport(ctx context.Context, client *http.Client, serverURL, username, password, repo, graph, fileName string, progress ProgressFunc) (importStatus, error) {
+	settings := importSettings{Name: filepath.Base(fileName), Type: "file", Context: graph}
+	if isDefaultGraph(graph) {
+		settings.Context = ""
+	}
+	if err := graphDBStartImport(ctx, client, serverURL, username, password, repo, fileName, settings); err != nil {
+		return importStatus{}, err
+	}
+
+	ticker := time.NewTicker(importPollInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := graphDBInterruptImport(client, serverURL, username, password, repo, settings.Name); err != nil {
+				debugLog("Failed to interrupt the import of %s: %v", settings.Name, err)
+			}
+			return importStatus{}, ctx.Err()
+		case <-ticker.C:
+		}
+
+		status, found, err := graphDBImportStatus(ctx, client, serverURL, username, password, repo, settings.Name)
+		if err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+			return importStatus{}, err
+		}
+		if !found {
+			return importStatus{}, fmt.Errorf("import of %s is no longer listed by GraphDB", settings.Name)
+		}
+		progress(asyncImportStage, int(status.AddedStatements), 0)
+		switch status.Status {
+		case "DONE":
+			return status, nil
+		case "ERROR":
+			return status, fmt.Errorf("GraphDB failed to import %s: %s", settings.Name, status.Message)
+		}
+	}
+}
+
+// importGraphFileAsync imports a file of graph-import through the GraphDB
+// import API. It reports false when the server has no import API, after adding
+// a warning, so the caller uploads this and the following files synchronously.
+func importGraphFileAsync(run *taskRun, client *http.Client, fileName, fileType string, index int) (bool, error) {
+	task, result := run.task, run.result
+	graph := task.Tgt.Graph
+	if task.Tgt.PreserveGraphs && isQuadFormat(fileType) {
+		graph = ""
+	}
+
+	status, err := graphDBAsyncImport(run.ctx, client, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, graph, fileName, run.progress)
+	if errors.Is(err, errAsyncImportUnavailable) {
+		addResultWarning(result, fmt.Sprintf("GraphDB server %s has no import API, the files are uploaded synchronously", normalizeURL(task.Tgt.URL)))
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	debugLog("Imported %s through the GraphDB import API: %s", fileName, status.Message)
+	result[fmt.Sprintf("file_%d_statements", index)] = status.AddedStatements
+	return true, nil
+}
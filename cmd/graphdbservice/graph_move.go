@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/google/uuid"
+)
+
+// executeGraphMoveTask executes the graph-move action.
+//
+// graph-move is a rename across repositories: the source graph is exported
+// from src, imported into tgt under tgt.graph_new and deleted from src once the
+// new graph exists. Unlike graph-migration the source graph is removed and an
+// existing target graph is never replaced.
+func executeGraphMoveTask(run *taskRun) error {
+	task, progress, log, result, srcClient, tgtClient, zitiClient := run.task, run.progress, run.log, run.result, run.srcClient, run.tgtClient, run.zitiClient
+
+	if identityFile != "" {
+		srcURL, err := URL2ServiceRobust(task.Src.URL)
+		if err != nil {
+			return err
+		}
+		srcClient, err = zitiClient(srcURL)
+		if err != nil {
+			return err
+		}
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
+		if err != nil {
+			return err
+		}
+		tgtClient, err = zitiClient(tgtURL)
+		if err != nil {
+			return err
+		}
+	}
+	srcGraph, newGraph := task.Src.Graph, task.Tgt.GraphNew
+
+	// Step 1: Check that the source graph exists and the target graph does not
+	if err := requireTaskRepository(run.graphDB(srcClient), task.Src, "src"); err != nil {
+		return err
+	}
+	if err := requireTaskRepository(run.graphDB(tgtClient), task.Tgt, "tgt"); err != nil {
+		return err
+	}
+	srcGraphs, err := run.graphDB(srcClient).ListGraphs(task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo)
+	if err != nil {
+		return fmt.Errorf("failed to list graphs in repository '%s': %w", task.Src.Repo, err)
+	}
+	if !graphListed(srcGraphs, srcGraph) {
+		return newTaskError(ErrGraphNotFound, "source graph '%s' not found in repository '%s'", srcGraph, task.Src.Repo)
+	}
+	tgtGraphs, err := run.graphDB(tgtClient).ListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
+	if err != nil {
+		return fmt.Errorf("failed to list graphs in repository '%s': %w", task.Tgt.Repo, err)
+	}
+	if graphListed(tgtGraphs, newGraph) {
+		return fmt.Errorf("target graph '%s' already exists in repository '%s'", newGraph, task.Tgt.Repo)
+	}
+
+	result["source"] = map[string]interface{}{"url": task.Src.URL, "repo": task.Src.Repo, "graph": srcGraph}
+	result["destination"] = map[string]interface{}{"url": task.Tgt.URL, "repo": task.Tgt.Repo, "graph": newGraph}
+
+	if task.DryRun {
+		count, _ := countGraphTriples(srcClient, task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo, srcGraph)
+		setDryRunResult(result, "Dry run: graph would be moved", []map[string]interface{}{
+			plannedOperation("export-graph", task.Src.Repo, srcGraph, count),
+			plannedOperation("import-graph", task.Tgt.Repo, newGraph, count),
+			plannedOperation("delete-graph", task.Src.Repo, srcGraph, count),
+		})
+		return nil
+	}
+
+	// Step 2: Export the source graph as a single RDF/XML document, so blank node
+	// labels keep their document scope
+	progress("Exporting graph", 1, 1)
+	blankNodes := recordBlankNodes(srcClient, task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo, srcGraph, result)
+	exportFile := run.tempFile(fmt.Sprintf("graph_move_%s.rdf", uuid.New().String()))
+	defer func() { _ = os.Remove(exportFile) }()
+	if err := run.graphDB(srcClient).ExportGraphRdf(task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo, srcGraph, exportFile); err != nil {
+		return fmt.Errorf("failed to export graph '%s': %w", srcGraph, err)
+	}
+	fileInfo, err := os.Stat(exportFile)
+	if err != nil {
+		return fmt.Errorf("failed to verify exported file: %w", err)
+	}
+	if fileInfo.Size() == 0 {
+		return fmt.Errorf("exported graph file is empty - graph '%s' may be empty", srcGraph)
+	}
+	var compression exportCompression
+	importFile, err := compression.compressExport(task, exportFile)
+	if err != nil {
+		return fmt.Errorf("failed to compress exported graph: %w", err)
+	}
+	defer func() { _ = os.Remove(importFile) }()
+	compression.report(result)
+
+	// Step 3: Import it into the new graph of the target repository
+	if err := run.ctx.Err(); err != nil {
+		return err
+	}
+	progress("Importing graph", 1, 1)
+	if err := importExportedGraph(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, newGraph, importFile, rdfContentTypes["rdf-xml"]); err != nil {
+		return fmt.Errorf("failed to import graph data to '%s': %w", newGraph, err)
+	}
+	if err := verifyBlankNodes(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, newGraph, blankNodes); err != nil {
+		return err
+	}
+	tgtGraphs, err = run.graphDB(tgtClient).ListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
+	if err != nil {
+		return fmt.Errorf("failed to verify new graph creation: %w", err)
+	}
+	if !graphListed(tgtGraphs, newGraph) {
+		return fmt.Errorf("new graph '%s' was not created successfully", newGraph)
+	}
+
+	// Step 4: Compare the triple counts and delete the source graph
+	srcTriples, srcErr := countGraphTriples(srcClient, task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo, srcGraph)
+	tgtTriples, tgtErr := countGraphTriples(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, newGraph)
+	if srcErr == nil && tgtErr == nil {
+		result["src_triples"] = srcTriples
+		result["tgt_triples"] = tgtTriples
+		if srcTriples != tgtTriples {
+			addResultWarning(result, fmt.Sprintf("Triple count mismatch: source graph had %d triples, new graph has %d triples", srcTriples, tgtTriples))
+		}
+	}
+	if err := run.ctx.Err(); err != nil {
+		return err
+	}
+	progress("Deleting source graph", 1, 1)
+	if err := run.graphDB(srcClient).DeleteGraph(task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo, srcGraph); err != nil {
+		// The new graph exists, so the move is not undone
+		log.Warn("Failed to delete source graph", "graph", srcGraph, "error", err)
+		addResultWarning(result, fmt.Sprintf("New graph created successfully, but failed to delete source graph: %v", err))
+	} else {
+		result["source_deleted"] = true
+	}
+
+	result["message"] = "Graph moved successfully"
+	result["data_size"] = fileInfo.Size()
+	return nil
+}
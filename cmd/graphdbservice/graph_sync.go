@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// graphSyncBatchSize is the number of triples sent per SPARQL UPDATE by graph-sync
+const graphSyncBatchSize = 1000
+
+// tripleSet is a set of N-Triples statements, one line per triple
+type tripleSet map[string]struct{}
+
+// graphTripleSet exports a named graph as N-Triples and returns its triples.
+// Triples containing blank nodes are counted but not included in the set:
+// blank node labels are local to a single export, so they cannot be compared
+// between repositories, and SPARQL DELETE DATA does not accept blank nodes.
+func graphTripleSet(client *http.Client, serverURL, username, password, repo, graph string) (triples tripleSet, blankNodeTriples int, size int64, err error) {
+	body, err := graphDBExportGraph(client, serverURL, username, password, repo, graph, "application/n-triples")
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer func() { _ = body.Close() }()
+
+	reader := &countingReader{reader: body}
+	triples, blankNodeTriples, err = readTripleSet(reader)
+	return triples, blankNodeTriples, reader.count, err
+}
+
+// readTripleSet reads N-Triples statements from r, skipping comments and triples with blank nodes
+func readTripleSet(r io.Reader) (tripleSet, int, error) {
+	triples := make(tripleSet)
+	blankNodeTriples := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024) // Long literals can exceed the default token size
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if hasBlankNode(line) {
+			blankNodeTriples++
+			continue
+		}
+		triples[line] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read N-Triples: %w", err)
+	}
+	return triples, blankNodeTriples, nil
+}
+
+// hasBlankNode reports whether the subject or object of an N-Triples statement is a blank node
+func hasBlankNode(line string) bool {
+	if strings.HasPrefix(line, "_:") {
+		return true
+	}
+	// Subject and predicate are IRIs, which cannot contain spaces or '>'
+	for term := 0; term < 2; term++ {
+		end := strings.Index(line, "> ")
+		if end < 0 {
+			return false
+		}
+		line = strings.TrimSpace(line[end+2:])
+	}
+	return strings.HasPrefix(line, "_:")
+}
+
+// diffTripleSets returns the sorted triples only in src (to add) and only in tgt (to remove)
+func diffTripleSets(src, tgt tripleSet) (additions, removals []string) {
+	for triple := range src {
+		if _, exists := tgt[triple]; !exists {
+			additions = append(additions, triple)
+		}
+	}
+	for triple := range tgt {
+		if _, exists := src[triple]; !exists {
+			removals = append(removals, triple)
+		}
+	}
+	sort.Strings(additions)
+	sort.Strings(removals)
+	return additions, removals
+}
+
+// applyGraphTriples sends triples to a graph in batches of graphSyncBatchSize, using
+// operation ("INSERT DATA" or "DELETE DATA"). stage is reported to progress.
+func applyGraphTriples(client *http.Client, serverURL, username, password, repo, graph, operation string, triples []string, stage string, progress ProgressFunc) error {
+	graphIRI, err := sparqlGraphIRI(graph)
+	if err != nil {
+		return err
+	}
+	batches := (len(triples) + graphSyncBatchSize - 1) / graphSyncBatchSize
+	for batch := 0; batch < batches; batch++ {
+		progress(stage, batch+1, batches)
+
+		end := (batch + 1) * graphSyncBatchSize
+		if end > len(triples) {
+			end = len(triples)
+		}
+		update := fmt.Sprintf("%s { GRAPH %s {\n%s\n} }", operation, graphIRI, strings.Join(triples[batch*graphSyncBatchSize:end], "\n"))
+		if err := sparqlUpdate(client, serverURL, username, password, repo, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
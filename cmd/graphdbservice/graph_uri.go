@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"eve.evalgo.org/common"
+)
+
+// iriScheme matches the scheme of an absolute IRI (RFC 3987), e.g. "http:" or "urn:"
+var iriScheme = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*:`)
+
+// invalidIRIChars are the characters never allowed in an IRI
+const invalidIRIChars = "<>\"{}|\\^`"
+
+// allowRelativeGraphs reports whether graph names may be relative IRIs, which
+// GraphDB resolves against its base IRI; set by GRAPHDB_ALLOW_RELATIVE_GRAPHS
+func allowRelativeGraphs() bool {
+	return common.GetEnvBool("GRAPHDB_ALLOW_RELATIVE_GRAPHS", false)
+}
+
+// checkGraphIRI checks that a graph name is an absolute IRI, or a relative one
+// if relative is set, or one of the names of the default graph.
+func checkGraphIRI(graph string, relative bool) error {
+	if isDefaultGraph(graph) {
+		return nil
+	}
+	for _, r := range graph {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune(invalidIRIChars, r) {
+			return fmt.Errorf("'%s' is not a valid IRI: it contains %q", graph, r)
+		}
+	}
+	scheme := iriScheme.FindString(graph)
+	switch {
+	case scheme != "" && len(scheme) < len(graph):
+		return nil
+	case scheme != "":
+		return fmt.Errorf("'%s' is not a valid IRI: nothing follows the scheme", graph)
+	case !relative:
+		return fmt.Errorf("'%s' is not an absolute IRI such as http://example.org/graph (set GRAPHDB_ALLOW_RELATIVE_GRAPHS to allow relative graph names)", graph)
+	}
+	return nil
+}
+
+// validateGraphIRIs checks the graph names of a src or tgt repository: graph,
+// graph_old, graph_new and every entry of graphs
+func validateGraphIRIs(role string, repo *Repository) error {
+	if repo == nil {
+		return nil
+	}
+	relative := allowRelativeGraphs()
+	check := func(field, graph string) error {
+		if graph == "" {
+			return nil
+		}
+		if err := checkGraphIRI(graph, relative); err != nil {
+			return &taskFieldError{Field: role + "." + field, Message: fmt.Sprintf("invalid %s.%s: %v", role, field, err)}
+		}
+		return nil
+	}
+
+	if err := check("graph", repo.Graph); err != nil {
+		return err
+	}
+	if err := check("graph_old", repo.GraphOld); err != nil {
+		return err
+	}
+	if err := check("graph_new", repo.GraphNew); err != nil {
+		return err
+	}
+	for i, graph := range repo.Graphs {
+		if err := check(fmt.Sprintf("graphs[%d]", i), graph); err != nil {
+			return err
+		}
+	}
+	return nil
+}
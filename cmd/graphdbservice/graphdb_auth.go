@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const (
+	// authTypeBearer sends a token as "Authorization: Bearer <token>" (OAuth / OpenID)
+	authTypeBearer = "bearer"
+	// authTypeGDB sends a token as "Authorization: GDB <token>" (GraphDB's own tokens)
+	authTypeGDB = "gdb"
+)
+
+// headerNamePattern matches valid HTTP header names (RFC 9110 tokens)
+var headerNamePattern = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// reservedHeaders are set by the service or the HTTP client for each request
+// and cannot be overridden by Repository.Headers
+var reservedHeaders = map[string]bool{"Host": true, "Content-Length": true, "Content-Type": true, "Transfer-Encoding": true, "Connection": true}
+
+// validateRepositoryAuth checks that a repository uses at most one authentication
+// method: basic auth (username/password) or a token.
+func validateRepositoryAuth(role string, repo *Repository) error {
+	if repo == nil {
+		return nil
+	}
+	if err := validateRepositoryHeaders(role, repo); err != nil {
+		return err
+	}
+	if repo.Token == "" {
+		if repo.AuthType != "" {
+			return &taskFieldError{Field: role + ".auth_type", Message: fmt.Sprintf("%s.auth_type requires %s.token", role, role)}
+		}
+		return nil
+	}
+	if repo.Username != "" || repo.Password != "" {
+		return &taskFieldError{Field: role + ".token", Message: fmt.Sprintf("%s must use either username/password or token, not both", role)}
+	}
+	switch strings.ToLower(repo.AuthType) {
+	case "", authTypeBearer, authTypeGDB:
+		return nil
+	}
+	return &taskFieldError{Field: role + ".auth_type", Message: fmt.Sprintf("invalid %s.auth_type '%s': must be bearer or gdb", role, repo.AuthType)}
+}
+
+// validateRepositoryHeaders checks the extra headers of a repository: valid
+// names and values, no header the service sets itself, and no Authorization
+// header together with a token.
+func validateRepositoryHeaders(role string, repo *Repository) error {
+	for name, value := range repo.Headers {
+		field := role + ".headers." + name
+		canonical := http.CanonicalHeaderKey(name)
+		switch {
+		case !headerNamePattern.MatchString(name):
+			return &taskFieldError{Field: field, Message: fmt.Sprintf("invalid header name '%s' in %s.headers", name, role)}
+		case strings.ContainsAny(value, "\r\n\x00"):
+			return &taskFieldError{Field: field, Message: fmt.Sprintf("header '%s' in %s.headers contains a line break", name, role)}
+		case reservedHeaders[canonical]:
+			return &taskFieldError{Field: field, Message: fmt.Sprintf("header '%s' is set by the service and cannot be overridden in %s.headers", canonical, role)}
+		case canonical == "Authorization" && repo.Token != "":
+			return &taskFieldError{Field: field, Message: fmt.Sprintf("%s must use either an Authorization header or token, not both", role)}
+		}
+	}
+	return nil
+}
+
+// authorizationHeader returns the Authorization header for the token of a
+// repository, or "" if it uses basic auth.
+func (r *Repository) authorizationHeader() string {
+	if r == nil || r.Token == "" {
+		return ""
+	}
+	if strings.EqualFold(r.AuthType, authTypeGDB) {
+		return "GDB " + r.Token
+	}
+	return "Bearer " + r.Token
+}
+
+// setHeaders sets the extra headers and the token Authorization header of
+// the repository on a request to its server. The token is set last, so it
+// takes precedence over an Authorization header in Headers.
+func (r *Repository) setHeaders(req *http.Request) {
+	for name, value := range r.Headers {
+		req.Header.Set(name, value)
+	}
+	if header := r.authorizationHeader(); header != "" {
+		req.Header.Set("Authorization", header)
+	}
+}
+
+// serverAuth is the repository whose headers are sent to one GraphDB server
+type serverAuth struct {
+	serverURL string
+	repo      *Repository
+}
+
+// tokenAuthHTTPTransport sets the extra headers and the token Authorization
+// header on requests to the servers of a task. The eve db functions only know
+// basic auth, so the headers are set here, after the request was built; a
+// token or an Authorization header in Repository.Headers therefore takes
+// precedence over username/password.
+type tokenAuthHTTPTransport struct {
+	Transport http.RoundTripper
+	auth      []serverAuth
+}
+
+// RoundTrip implements http.RoundTripper interface and adds the headers of the matching server
+func (t *tokenAuthHTTPTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestURL := req.URL.String()
+	for _, a := range t.auth {
+		rest, found := strings.CutPrefix(requestURL, a.serverURL)
+		if found && (rest == "" || strings.HasPrefix(rest, "/") || strings.HasPrefix(rest, "?")) {
+			req = req.Clone(req.Context())
+			a.repo.setHeaders(req)
+			break
+		}
+	}
+	return t.Transport.RoundTrip(req)
+}
+
+// withTaskAuth returns a copy of the HTTP client that authenticates with the
+// tokens and sends the extra headers of the task's src and tgt repositories.
+// Without tokens and headers the client is returned unchanged.
+func withTaskAuth(task Task, client *http.Client) *http.Client {
+	var auth []serverAuth
+	for _, repo := range []*Repository{task.Tgt, task.Src} {
+		if repo != nil && repo.URL != "" && (repo.Token != "" || len(repo.Headers) > 0) {
+			auth = append(auth, serverAuth{serverURL: normalizeURL(repo.URL), repo: repo})
+		}
+	}
+	if len(auth) == 0 {
+		return client
+	}
+
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	wrapped := *client
+	wrapped.Transport = &tokenAuthHTTPTransport{
+		Transport: transport,
+		auth:      auth,
+	}
+	return &wrapped
+}
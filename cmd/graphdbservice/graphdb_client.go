@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"eve.evalgo.org/db"
+)
+
+// graphDBAPI sends the GraphDB requests of the eve db package functions
+// (GraphDBRepositories, GraphDBListGraphs, GraphDBRestoreConf, ...) with an
+// explicit HTTP client. The eve functions read the client from the package
+// global db.HttpClient, so concurrent tasks against different servers would race
+// on which client is used; these methods take the client of their task instead
+// and run in parallel.
+//
+// Within a task (see taskRun.graphDB) repository and graph listings go through
+// the listing cache of the request, and changes made through it drop the
+// affected listings.
+type graphDBAPI struct {
+	client   *http.Client
+	listings *taskListings
+}
+
+// graphDBWith returns a graphDBAPI using client for all GraphDB requests
+func graphDBWith(client *http.Client) graphDBAPI {
+	return graphDBAPI{client: client}
+}
+
+// graphDB returns a graphDBAPI using client and the listing cache of the request
+func (run *taskRun) graphDB(client *http.Client) graphDBAPI {
+	return graphDBAPI{client: client, listings: run.listings}
+}
+
+// invalidate drops cached listings after a change: the graphs of repo, or the
+// whole server if graphsOnly is false
+func (g graphDBAPI) invalidate(serverURL, repo string, graphsOnly bool) {
+	if g.listings == nil {
+		return
+	}
+	if graphsOnly {
+		g.listings.cache.invalidateGraphs(serverURL, repo)
+	} else {
+		g.listings.cache.invalidateServer(serverURL)
+	}
+}
+
+// do sends a request with basic auth and returns the response of a successful
+// request. On a status of 300 or above the body is closed and an error built
+// from what is returned.
+func (g graphDBAPI) do(req *http.Request, username, password, what string) (*http.Response, error) {
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer func() { _ = resp.Body.Close() }()
+		return nil, graphDBStatusError(resp.StatusCode, "%s failed with status %d: %s", what, resp.StatusCode, readErrorBody(resp))
+	}
+	return resp, nil
+}
+
+// listing requests a SPARQL JSON listing such as /repositories or rdf-graphs
+func (g graphDBAPI) listing(endpoint, username, password, what string) (*db.GraphDBResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/sparql-results+json")
+	resp, err := g.do(req, username, password, what)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var listing db.GraphDBResponse
+	if err := json.NewDecoder(resp.Body).Decode(&listing); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", what, err)
+	}
+	return &listing, nil
+}
+
+// Repositories lists the repositories of a GraphDB server
+func (g graphDBAPI) Repositories(serverURL, username, password string) (*db.GraphDBResponse, error) {
+	fetch := func() (*db.GraphDBResponse, error) {
+		return g.listing(normalizeURL(serverURL)+"/repositories", username, password, "listing repositories")
+	}
+	if g.listings == nil {
+		return fetch()
+	}
+	return g.listings.get(repositoriesKey(serverURL, username, g.listings.credentials(serverURL, password)), fetch)
+}
+
+// ListGraphs lists the named graphs of a repository
+func (g graphDBAPI) ListGraphs(serverURL, username, password, repo string) (*db.GraphDBResponse, error) {
+	fetch := func() (*db.GraphDBResponse, error) {
+		endpoint := fmt.Sprintf("%s/repositories/%s/rdf-graphs", normalizeURL(serverURL), url.PathEscape(repo))
+		return g.listing(endpoint, username, password, fmt.Sprintf("listing graphs of repository '%s'", repo))
+	}
+	if g.listings == nil {
+		return fetch()
+	}
+	return g.listings.get(graphsKey(serverURL, username, g.listings.credentials(serverURL, password), repo), fetch)
+}
+
+// RestoreConf creates a repository from a configuration file
+func (g graphDBAPI) RestoreConf(serverURL, username, password, fileName string) error {
+	err := g.restoreConf(serverURL, username, password, fileName)
+	g.invalidate(serverURL, "", false)
+	return err
+}
+
+func (g graphDBAPI) restoreConf(serverURL, username, password, fileName string) error {
+	config, err := os.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", fileName, err)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("config", filepath.Base(fileName))
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(config); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, normalizeURL(serverURL)+"/rest/repositories", &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	resp, err := g.do(req, username, password, "creating repository from "+filepath.Base(fileName))
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// DeleteRepository deletes a repository
+func (g graphDBAPI) DeleteRepository(serverURL, username, password, repo string) error {
+	err := g.delete(fmt.Sprintf("%s/rest/repositories/%s", normalizeURL(serverURL), url.PathEscape(repo)), username, password, fmt.Sprintf("deleting repository '%s'", repo))
+	g.invalidate(serverURL, repo, false)
+	return err
+}
+
+// DeleteGraph deletes a named graph of a repository, or clears the default graph
+func (g graphDBAPI) DeleteGraph(serverURL, username, password, repo, graph string) error {
+	err := g.delete(graphStoreURL(serverURL, repo, graph), username, password, fmt.Sprintf("deleting graph '%s'", graph))
+	g.invalidate(serverURL, repo, true)
+	return err
+}
+
+func (g graphDBAPI) delete(endpoint, username, password, what string) error {
+	req, err := http.NewRequest(http.MethodDelete, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := g.do(req, username, password, what)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// ExportGraphRdf exports a named graph or the default graph as RDF/XML to fileName
+func (g graphDBAPI) ExportGraphRdf(serverURL, username, password, repo, graph, fileName string) error {
+	_, err := graphDBExportGraphToFile(g.client, serverURL, username, password, repo, graph, rdfContentTypes["rdf-xml"], fileName)
+	return err
+}
+
+// ImportGraphRdf replaces the content of a named graph or the default graph with
+// an RDF file, sent as the media type of its extension and as RDF/XML otherwise
+func (g graphDBAPI) ImportGraphRdf(serverURL, username, password, repo, graph, fileName string) error {
+	err := g.importGraph(serverURL, username, password, repo, graph, fileName)
+	g.invalidate(serverURL, repo, true)
+	return err
+}
+
+func (g graphDBAPI) importGraph(serverURL, username, password, repo, graph, fileName string) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", fileName, err)
+	}
+	defer func() { _ = file.Close() }()
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file %s: %w", fileName, err)
+	}
+
+	contentType := rdfMediaType(getFileType(fileName), "")
+	if contentType == "" {
+		contentType = rdfContentTypes["rdf-xml"]
+	}
+
+	req, err := http.NewRequest(http.MethodPut, graphStoreURL(serverURL, repo, graph), file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = fileInfo.Size()
+	req.Header.Set("Content-Type", contentType)
+	resp, err := g.do(req, username, password, fmt.Sprintf("importing into graph '%s'", graph))
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
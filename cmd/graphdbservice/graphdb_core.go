@@ -4,16 +4,26 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"log/slog"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"eve.evalgo.org/db"
 	"github.com/google/uuid"
@@ -35,49 +45,123 @@ var (
 //   - graph-migration: Migrate a named graph between repositories
 //   - repo-delete: Delete a repository
 //   - graph-delete: Delete a named graph
+//   - graphs-delete: Delete several named graphs of one repository
 //   - repo-create: Create a new repository from TTL configuration
+//   - repos-create: Create several repositories from one TTL configuration template
 //   - graph-import: Import RDF data into a graph
 //   - repo-import: Import repository from BRF backup file
 //   - repo-rename: Rename a repository (backup, recreate, restore)
 //   - graph-rename: Rename a graph (export, import, delete)
+//   - graph-move: Move a graph to another repository under a new name (export, import, delete)
+//   - graph-merge (alias graphs-merge): Merge several source graphs into one target graph (export, append)
+//   - graph-query-import: Import the result of a CONSTRUCT/DESCRIBE query on src into a target graph
+//   - graph-sync: Apply the triple differences between a source and a target graph
+//   - repo-restore-backup: Recreate a repository from a backup retained by repo-rename
+//   - repo-clone: Copy a repository under a new name (server side on the same server)
+//   - server-backup: Back up all repositories of a server into one tar.gz archive
+//   - server-restore: Recreate the repositories of a server-backup archive
+//   - sparql-update: Run a SPARQL UPDATE against a repository (requires SPARQL_UPDATE_ENABLED)
+//   - sparql-query: Run a read-only SELECT or ASK query and return its results
+//
+// When DryRun is set, destructive actions (repo-delete, graph-delete, graphs-delete,
+// repo-rename, graph-rename, graph-move, graph-merge, graph-sync, sparql-update) only validate the request and
+// report the planned operations without modifying any repository.
 type Task struct {
-	Action string      `json:"action" validate:"required"` // The action to perform
-	Src    *Repository `json:"src,omitempty"`              // Source repository/graph (for migration operations)
-	Tgt    *Repository `json:"tgt,omitempty"`              // Target repository/graph (for all operations)
+	Action          string      `json:"action" validate:"required"`  // The action to perform
+	Src             *Repository `json:"src,omitempty"`               // Source repository/graph (for migration operations)
+	Tgt             *Repository `json:"tgt,omitempty"`               // Target repository/graph (for all operations)
+	DeleteSources   bool        `json:"delete_sources,omitempty"`    // Delete the source graphs after a successful merge (for graph-merge)
+	DryRun          bool        `json:"dry_run,omitempty"`           // Only report the planned operations without executing them
+	RetryAttempts   int         `json:"retry_attempts,omitempty"`    // Maximum attempts per GraphDB request (default: GRAPHDB_RETRY_ATTEMPTS or 3)
+	RetryDelayMs    int         `json:"retry_delay_ms,omitempty"`    // Base retry delay in milliseconds, doubled per retry (default: GRAPHDB_RETRY_DELAY_MS or 500)
+	Verify          bool        `json:"verify,omitempty"`            // Compare source and target triple counts after the migration (for repo-migration)
+	ReportGraphs    bool        `json:"report_graphs,omitempty"`     // List the graphs of the migrated repository with their triple counts (for repo-migration)
+	ForceRecreate   bool        `json:"force_recreate,omitempty"`    // Delete and recreate an existing target repository (for repo-migration and server-restore)
+	TimeoutSeconds  int         `json:"timeout_seconds,omitempty"`   // Cancel the task after this many seconds (default: TASK_TIMEOUT_SECONDS, 0 = no timeout)
+	Force           bool        `json:"force,omitempty"`             // Delete the old repository even if some graphs were not transferred (for repo-rename)
+	ContinueOnError bool        `json:"continue_on_error,omitempty"` // Keep deleting the remaining graphs when one fails (for graphs-delete and pattern deletes), skip missing source graphs (for graph-merge), keep a server-backup or server-restore going without its failed repositories
+	IfNotExists     bool        `json:"if_not_exists,omitempty"`     // Succeed without changes if the repository already exists (for repo-create and repos-create)
+	KeepBackup      bool        `json:"keep_backup,omitempty"`       // Retain the config and BRF data of the old repository (for repo-rename)
+	BackupID        string      `json:"backup_id,omitempty"`         // Backup returned by repo-rename with keep_backup (for repo-restore-backup, or to resume repo-rename)
+	ExportFormat    string      `json:"export_format,omitempty"`     // Serialization of the intermediate export, e.g. "n-triples" (for graph-migration, default RDF/XML)
+	ConfirmPattern  bool        `json:"confirm_pattern,omitempty"`   // Confirm deleting everything matched by tgt.pattern (for repo-delete, graph-delete)
+	ClusterAware    bool        `json:"cluster_aware,omitempty"`     // Send the writes to the leader of the GraphDB cluster of tgt.url
+	SkipDiskCheck   bool        `json:"skip_disk_check,omitempty"`   // Skip the free temp space check (for repo-rename, repo-import from src)
+	Limit           int         `json:"limit,omitempty"`             // Maximum result rows (for sparql-query, default and cap: SPARQL_QUERY_MAX_ROWS)
+	SkipUnchanged   bool        `json:"skip_unchanged,omitempty"`    // Skip the import if the files match the last import into the graph (for graph-import)
+	AsyncImport     bool        `json:"async_import,omitempty"`      // Import through the GraphDB import API and report its progress (for graph-import)
+
+	// Compress gzips the intermediate export files of graph-migration, repo-rename,
+	// graph-rename and graph-move. Unset compresses files from EXPORT_COMPRESS_THRESHOLD_MB on.
+	Compress *bool `json:"compress,omitempty"`
 }
 
 // Repository represents the connection details and identifiers for a GraphDB repository or graph.
 // Different fields are required depending on the operation being performed.
 type Repository struct {
-	URL      string `json:"url,omitempty"`       // GraphDB server URL (e.g., "http://localhost:7200")
-	Username string `json:"username,omitempty"`  // GraphDB username for authentication
-	Password string `json:"password,omitempty"`  // GraphDB password for authentication
-	Repo     string `json:"repo,omitempty"`      // Repository name
-	Graph    string `json:"graph,omitempty"`     // Named graph URI or name
-	RepoOld  string `json:"repo_old,omitempty"`  // Old repository name (for repo-rename)
-	RepoNew  string `json:"repo_new,omitempty"`  // New repository name (for repo-rename)
-	GraphOld string `json:"graph_old,omitempty"` // Old graph name (for graph-rename)
-	GraphNew string `json:"graph_new,omitempty"` // New graph name (for graph-rename)
+	URL      string   `json:"url,omitempty"`       // GraphDB server URL (e.g., "http://localhost:7200")
+	Username string   `json:"username,omitempty"`  // GraphDB username for authentication
+	Password string   `json:"password,omitempty"`  // GraphDB password for authentication
+	Token    string   `json:"token,omitempty"`     // GraphDB or OAuth token, used instead of username/password
+	AuthType string   `json:"auth_type,omitempty"` // Token scheme: bearer (default) or gdb
+	Repo     string   `json:"repo,omitempty"`      // Repository name
+	Graph    string   `json:"graph,omitempty"`     // Named graph URI or name, "default" for the default graph
+	RepoOld  string   `json:"repo_old,omitempty"`  // Old repository name (for repo-rename)
+	RepoNew  string   `json:"repo_new,omitempty"`  // New repository name (for repo-rename)
+	GraphOld string   `json:"graph_old,omitempty"` // Old graph name (for graph-rename)
+	GraphNew string   `json:"graph_new,omitempty"` // New graph name (for graph-rename and graph-move)
+	Graphs   []string `json:"graphs,omitempty"`    // Graph URIs (src for graph-merge and a selective repo-migration, tgt for graphs-delete)
+	Repos    []string `json:"repos,omitempty"`     // Repository names (for repos-create, server-backup and server-restore)
+	Format   string   `json:"format,omitempty"`    // RDF format override for uploaded files, e.g. "turtle" (for graph-import)
+	Query    string   `json:"query,omitempty"`     // SPARQL query (CONSTRUCT/DESCRIBE for graph-query-import, SELECT/ASK for sparql-query)
+	Update   string   `json:"update,omitempty"`    // SPARQL UPDATE to run (for sparql-update)
+	Ruleset  string   `json:"ruleset,omitempty"`   // Reasoning ruleset for a generated config, e.g. "rdfs" (for repo-create and repos-create)
+	RepoType string   `json:"repo_type,omitempty"` // Repository type for a generated config: graphdb, free, se (for repo-create and repos-create)
+
+	// Headers are extra HTTP headers sent with every request to the GraphDB
+	// server, e.g. for a proxy in front of it. A token takes precedence over an
+	// Authorization header here, which in turn replaces basic auth.
+	Headers map[string]string `json:"headers,omitempty"`
+	// ExcludeRepos names repositories of the archive that server-restore leaves out
+	ExcludeRepos []string `json:"exclude_repos,omitempty"`
+	// PreserveGraphs imports quad formats (.nq, .trig) with the graph names encoded in the
+	// file instead of forcing them into Graph (for graph-import). Triple formats still use Graph.
+	PreserveGraphs bool `json:"preserve_graphs,omitempty"`
+	// Mode selects how graph-import treats an existing target graph: "replace"
+	// (default) deletes it before the import, "append" adds the data to it.
+	Mode string `json:"mode,omitempty"`
+	// ContentType overrides the MIME type of the format sent to GraphDB on import,
+	// e.g. "application/x-turtle" (for graph-import and the tgt of graph-migration)
+	ContentType string `json:"content_type,omitempty"`
+	// Accept overrides the MIME type requested from GraphDB on export (for the
+	// src of graph-migration)
+	Accept string `json:"accept,omitempty"`
+	// Pattern selects the repositories (repo-delete) or graphs (graph-delete) to
+	// delete instead of Repo or Graph: a glob such as "test-*", or a regular
+	// expression with the prefix "re:". Requires Task.ConfirmPattern. For
+	// server-backup it selects the repositories to back up.
+	Pattern string `json:"pattern,omitempty"`
 }
 
 // MigrationRequest represents the root request structure for GraphDB operations.
 type MigrationRequest struct {
-	Version string `json:"version" validate:"required"` // API version (e.g., "v0.0.1")
-	Tasks   []Task `json:"tasks" validate:"required"`   // List of tasks to execute
+	Version       string `json:"version" validate:"required"` // API version (e.g., "v0.0.1"), see supportedAPIVersions
+	Tasks         []Task `json:"tasks" validate:"required"`   // List of tasks to execute
+	Parallel      bool   `json:"parallel,omitempty"`          // Run tasks on different target repositories concurrently
+	Concurrency   int    `json:"concurrency,omitempty"`       // Maximum number of concurrent task groups (default: defaultTaskConcurrency)
+	SkipPreflight bool   `json:"skip_preflight,omitempty"`    // Skip the reachability check of the GraphDB servers
+	CallbackURL   string `json:"callback_url,omitempty"`      // Run asynchronously and POST the results to this URL
+	TempDir       string `json:"temp_dir,omitempty"`          // Server directory for the temp files of the tasks (default: MIGRATION_TEMP_DIR)
 }
 
-// debugLog logs a message only if debug mode is enabled
+// debugLog logs a message at debug level
 func debugLog(format string, args ...interface{}) {
-	if debugMode {
-		fmt.Printf("DEBUG: "+format+"\n", args...)
-	}
+	serviceLog.Debug(redactText(strings.TrimSpace(fmt.Sprintf(format, args...))))
 }
 
-// debugLogHTTP logs HTTP-related debug messages only if debug mode is enabled
+// debugLogHTTP logs HTTP-related debug messages at debug level
 func debugLogHTTP(format string, args ...interface{}) {
-	if debugMode {
-		fmt.Printf("DEBUG HTTP: "+format+"\n", args...)
-	}
+	serviceLog.Debug(redactText(fmt.Sprintf(format, args...)), "component", "http")
 }
 
 // normalizeURL removes trailing slashes from URLs to prevent double-slash issues
@@ -112,9 +196,7 @@ func (d *debugHTTPTransport) RoundTrip(req *http.Request) (*http.Response, error
 		if readErr != nil {
 			debugLogHTTP("Failed to read error response body: %v", readErr)
 		} else {
-			debugLogHTTP("===== ERROR RESPONSE BODY (Status %d) =====", resp.StatusCode)
-			fmt.Printf("%s\n", string(bodyBytes))
-			debugLogHTTP("===== END ERROR RESPONSE BODY =====")
+			serviceLog.Debug("Error response body", "component", "http", "status", resp.StatusCode, "body", string(bodyBytes))
 
 			// Restore the body for the caller
 			resp.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
@@ -124,21 +206,53 @@ func (d *debugHTTPTransport) RoundTrip(req *http.Request) (*http.Response, error
 	return resp, err
 }
 
-// enableHTTPDebugLogging wraps the HTTP client with debug logging
+// contextHTTPTransport binds requests without their own context to a task context
+type contextHTTPTransport struct {
+	Transport http.RoundTripper
+	ctx       context.Context
+}
+
+// RoundTrip implements http.RoundTripper interface and attaches the task context
+func (t *contextHTTPTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Context() == context.Background() {
+		req = req.WithContext(t.ctx)
+	}
+	return t.Transport.RoundTrip(req)
+}
+
+// withContext returns a copy of the HTTP client whose requests are cancelled with ctx.
+func withContext(ctx context.Context, client *http.Client) *http.Client {
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	wrapped := *client
+	wrapped.Transport = &contextHTTPTransport{
+		Transport: transport,
+		ctx:       ctx,
+	}
+	return &wrapped
+}
+
+// enableHTTPDebugLogging returns a copy of the HTTP client with debug logging
 func enableHTTPDebugLogging(client *http.Client) *http.Client {
 	if client == nil {
 		client = &http.Client{}
 	}
 
-	if client.Transport == nil {
-		client.Transport = http.DefaultTransport
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
 	}
 
-	client.Transport = &debugHTTPTransport{
-		Transport: client.Transport,
+	// Copy the client so shared clients like http.DefaultClient are not wrapped repeatedly
+	wrapped := *client
+	wrapped.Transport = &debugHTTPTransport{
+		Transport: transport,
 	}
 
-	return client
+	return &wrapped
 }
 
 // md5Hash generates an MD5 hash of the given text string.
@@ -147,6 +261,30 @@ func md5Hash(text string) string {
 	return fmt.Sprintf("%x", hash)
 }
 
+// fileHashAlgorithm returns the algorithm used to hash uploaded files,
+// selected via the FILE_HASH_ALGORITHM env var ("md5" or "sha256", default "md5").
+func fileHashAlgorithm() string {
+	if strings.EqualFold(os.Getenv("FILE_HASH_ALGORITHM"), "sha256") {
+		return "sha256"
+	}
+	return "md5"
+}
+
+// copyWithHash copies src to dst while hashing the streamed content with the
+// configured algorithm. It returns the number of bytes copied and the hex digest.
+func copyWithHash(dst io.Writer, src io.Reader) (int64, string, error) {
+	var hasher hash.Hash = md5.New()
+	if fileHashAlgorithm() == "sha256" {
+		hasher = sha256.New()
+	}
+
+	written, err := io.Copy(dst, io.TeeReader(src, hasher))
+	if err != nil {
+		return written, "", err
+	}
+	return written, hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
 // getFileNames extracts the filenames from a slice of multipart file headers.
 func getFileNames(fileHeaders []*multipart.FileHeader) []string {
 	names := make([]string, len(fileHeaders))
@@ -156,34 +294,55 @@ func getFileNames(fileHeaders []*multipart.FileHeader) []string {
 	return names
 }
 
-// updateRepositoryNameInConfig updates repository name references in a GraphDB TTL configuration file.
+// extractRepositoryID returns the repository ID declared in a GraphDB TTL configuration file.
+// A file that is no repository configuration returns a *repositoryConfigError.
+func extractRepositoryID(configFile string) (string, error) {
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read config file: %w", err)
+	}
+	return parseRepositoryConfig(content)
+}
+
+// updateRepositoryNameInConfig updates repository name references in a GraphDB TTL configuration file:
+// the repositoryID statement (prefixed or as full IRI), the repository IRI
+// <http://www.openrdf.org/config/repository#oldName> and the prefixed name
+// repo:oldName. Only whole names are replaced, so renaming "foo" leaves
+// repo:foobar alone. The file is only written if the updated configuration
+// declares newName as its repository ID.
 func updateRepositoryNameInConfig(configFile, oldName, newName string) error {
+	if strings.ContainsAny(newName, "\"\\\n") {
+		return fmt.Errorf("invalid repository name '%s'", newName)
+	}
+
 	// Read the configuration file
 	content, err := os.ReadFile(configFile)
 	if err != nil {
 		return fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	// Convert to string for processing
-	configContent := string(content)
+	// $ in the new name must not expand in the regexp replacements
+	replacement := strings.ReplaceAll(newName, "$", "$$")
+	quotedOld := regexp.QuoteMeta(oldName)
 
 	// Replace repository ID references in the TTL file
-	replacements := map[string]string{
-		fmt.Sprintf(`rep:repositoryID "%s"`, oldName):                         fmt.Sprintf(`rep:repositoryID "%s"`, newName),
-		fmt.Sprintf(`<http://www.openrdf.org/config/repository#%s>`, oldName): fmt.Sprintf(`<http://www.openrdf.org/config/repository#%s>`, newName),
-		fmt.Sprintf(`repo:%s`, oldName):                                       fmt.Sprintf(`repo:%s`, newName),
-	}
-
-	// Apply replacements
-	for old, new := range replacements {
-		configContent = strings.ReplaceAll(configContent, old, new)
+	configContent := string(content)
+	repositoryID := regexp.MustCompile(`((?:rep:repositoryID|<http://www\.openrdf\.org/config/repository#repositoryID>)\s+)"` + quotedOld + `"`)
+	configContent = repositoryID.ReplaceAllString(configContent, `${1}"`+replacement+`"`)
+	// The repository IRI, also in @base declarations
+	configContent = strings.ReplaceAll(configContent,
+		fmt.Sprintf(`<http://www.openrdf.org/config/repository#%s>`, oldName),
+		fmt.Sprintf(`<http://www.openrdf.org/config/repository#%s>`, newName))
+	// repo:oldName as a whole prefixed name, ended by whitespace, punctuation or a final dot
+	prefixedName := regexp.MustCompile(`(^|[\s;,(\[])repo:` + quotedOld + `([\s;,)\]]|\.\s|\.$|$)`)
+	configContent = prefixedName.ReplaceAllString(configContent, `${1}repo:`+replacement+`${2}`)
+
+	if repoID, err := parseRepositoryConfig([]byte(configContent)); err != nil {
+		return fmt.Errorf("failed to rename repository in config file: %w", err)
+	} else if repoID != newName {
+		return fmt.Errorf("failed to rename repository in config file: it declares repository ID '%s' instead of '%s'", repoID, newName)
 	}
 
-	// Handle the repository node declaration if it exists
-	basePattern := fmt.Sprintf(`@base <http://www.openrdf.org/config/repository#%s>`, oldName)
-	newBasePattern := fmt.Sprintf(`@base <http://www.openrdf.org/config/repository#%s>`, newName)
-	configContent = strings.ReplaceAll(configContent, basePattern, newBasePattern)
-
 	// Write the updated content back to the file
 	err = os.WriteFile(configFile, []byte(configContent), 0644)
 	if err != nil {
@@ -194,13 +353,132 @@ func updateRepositoryNameInConfig(configFile, oldName, newName string) error {
 }
 
 // getGraphTripleCounts retrieves the triple counts for two graphs in a GraphDB repository.
-func getGraphTripleCounts(url, username, password, repo, oldGraph, newGraph string) (int, int) {
-	// Simplified implementation - returns -1 to indicate counts are not available
-	oldCount := -1
-	newCount := -1
+// A count of -1 indicates that the count could not be determined.
+func getGraphTripleCounts(client *http.Client, url, username, password, repo, oldGraph, newGraph string) (int, int) {
+	oldCount, err := countGraphTriples(client, url, username, password, repo, oldGraph)
+	if err != nil {
+		debugLog("Failed to count triples in graph %s: %v", oldGraph, err)
+	}
+	newCount, err := countGraphTriples(client, url, username, password, repo, newGraph)
+	if err != nil {
+		debugLog("Failed to count triples in graph %s: %v", newGraph, err)
+	}
 	return oldCount, newCount
 }
 
+// plannedOperation describes a single step a dry run would execute.
+// The graph and triple count are omitted when empty or unknown.
+func plannedOperation(operation, repo, graph string, triples int) map[string]interface{} {
+	op := map[string]interface{}{
+		"operation":  operation,
+		"repository": repo,
+	}
+	if graph != "" {
+		op["graph"] = graph
+	}
+	if triples >= 0 {
+		op["triples"] = triples
+	}
+	return op
+}
+
+// setDryRunResult marks a task result as a dry run and attaches the planned operations.
+func setDryRunResult(result map[string]interface{}, message string, operations []map[string]interface{}) {
+	if operations == nil {
+		operations = []map[string]interface{}{}
+	}
+	result["dry_run"] = true
+	result["message"] = message
+	result["planned_operations"] = operations
+}
+
+// requireTaskRepository checks that the repository of a task endpoint exists.
+// role ("src" or "tgt") is used in the error message.
+func requireTaskRepository(api graphDBAPI, repo *Repository, role string) error {
+	repos, err := api.Repositories(repo.URL, repo.Username, repo.Password)
+	if err != nil {
+		return err
+	}
+	for _, bind := range repos.Results.Bindings {
+		if bind.Id["value"] == repo.Repo {
+			return nil
+		}
+	}
+	return newTaskError(ErrRepoNotFound, "could not find required %s repository %s", role, repo.Repo)
+}
+
+// graphListed reports whether graph is among the graphs returned by ListGraphs
+func graphListed(graphs *db.GraphDBResponse, graph string) bool {
+	if isDefaultGraph(graph) {
+		// GraphDB does not list the default graph, which always exists
+		return true
+	}
+	for _, bind := range graphs.Results.Bindings {
+		if bind.ContextID.Value == graph {
+			return true
+		}
+	}
+	return false
+}
+
+// addResultWarning adds a warning to a task result, keeping earlier warnings.
+func addResultWarning(result map[string]interface{}, warning string) {
+	if previous, ok := result["warning"].(string); ok && previous != "" {
+		warning = previous + "; " + warning
+	}
+	result["warning"] = warning
+}
+
+// recordBlankNodes counts the blank node triples of a graph that is about to be
+// copied to another repository. If there are any, their number and a warning are
+// added to result, since blank nodes get new internal identifiers in the copy.
+// It returns the count, 0 if it cannot be determined.
+func recordBlankNodes(client *http.Client, url, username, password, repo, graph string, result map[string]interface{}) int {
+	count, err := countGraphBlankNodeTriples(client, url, username, password, repo, graph)
+	if err != nil {
+		debugLog("Failed to count blank node triples in graph %s: %v", graph, err)
+		return 0
+	}
+	if count > 0 {
+		result["blank_node_triples"] = count
+		addResultWarning(result, fmt.Sprintf("Graph '%s' contains %d triples with blank nodes; the copy is equivalent but blank node identifiers are not preserved", graph, count))
+	}
+	return count
+}
+
+// verifyBlankNodes checks that a graph copied from a graph with want blank node
+// triples has as many: the export is a single document imported in one request,
+// so an import that merged or duplicated blank nodes shows in the count.
+func verifyBlankNodes(client *http.Client, url, username, password, repo, graph string, want int) error {
+	if want <= 0 {
+		return nil
+	}
+	count, err := countGraphBlankNodeTriples(client, url, username, password, repo, graph)
+	if err != nil {
+		debugLog("Failed to count blank node triples in graph %s: %v", graph, err)
+		return nil
+	}
+	if count != want {
+		return fmt.Errorf("graph '%s' has %d triples with blank nodes after the import instead of %d, blank nodes were merged or duplicated", graph, count, want)
+	}
+	return nil
+}
+
+// copyGraphInRepository copies a graph into another graph of the same repository
+// with SPARQL COPY. The statements never leave GraphDB, so blank nodes keep
+// their identity instead of being relabelled by an export and import.
+func copyGraphInRepository(client *http.Client, url, username, password, repo, from, to string) error {
+	fromRef, err := sparqlGraphRef(from)
+	if err != nil {
+		return err
+	}
+	toRef, err := sparqlGraphRef(to)
+	if err != nil {
+		return err
+	}
+	return sparqlUpdate(client, url, username, password, repo, fmt.Sprintf("COPY %s TO %s", fromRef, toRef))
+}
+
 // getFileType determines the RDF serialization format based on the file extension.
 func getFileType(filename string) string {
 	filename = strings.ToLower(filename)
@@ -222,9 +500,115 @@ func getFileType(filename string) string {
 		return "trig"
 	case strings.HasSuffix(filename, ".nq"):
 		return "n-quads"
+	case strings.HasSuffix(filename, ".ttls"):
+		return "turtle-star"
+	case strings.HasSuffix(filename, ".trigs"):
+		return "trig-star"
 	default:
-		return "unknown"
+		return registeredFileType(filename)
+	}
+}
+
+// rdfFormatExtensions maps the file types returned by getFileType to the
+// file extension used when importing a file of that type. RegisterRDFFormat adds to it.
+var rdfFormatExtensions = map[string]string{
+	"binary-rdf":  ".brf",
+	"rdf-xml":     ".rdf",
+	"turtle":      ".ttl",
+	"n-triples":   ".nt",
+	"n3":          ".n3",
+	"json-ld":     ".jsonld",
+	"trig":        ".trig",
+	"n-quads":     ".nq",
+	"turtle-star": ".ttls",
+	"trig-star":   ".trigs",
+}
+
+// rdfContentTypes maps the file types returned by getFileType to the MIME type
+// sent to and requested from GraphDB. RegisterRDFFormat adds to it.
+var rdfContentTypes = map[string]string{
+	"binary-rdf":  "application/x-binary-rdf",
+	"rdf-xml":     "application/rdf+xml",
+	"turtle":      "text/turtle",
+	"n-triples":   "application/n-triples",
+	"n3":          "text/n3",
+	"json-ld":     "application/ld+json",
+	"trig":        "application/trig",
+	"n-quads":     "application/n-quads",
+	"turtle-star": "application/x-turtlestar",
+	"trig-star":   "application/x-trigstar",
+}
+
+// rdfQuadFormats are the file types carrying named graphs of their own
+var rdfQuadFormats = map[string]bool{
+	"trig":      true,
+	"n-quads":   true,
+	"trig-star": true,
+}
+
+// Import modes of graph-import
+const (
+	importModeReplace = "replace"
+	importModeAppend  = "append"
+)
+
+// graphImportMode returns the import mode of a graph-import target, defaulting to replace
+func graphImportMode(tgt *Repository) (string, error) {
+	switch tgt.Mode {
+	case "", importModeReplace:
+		return importModeReplace, nil
+	case importModeAppend:
+		return importModeAppend, nil
+	}
+	return "", fmt.Errorf("invalid mode '%s' (supported: %s, %s)", tgt.Mode, importModeReplace, importModeAppend)
+}
+
+// migrationExportFormat validates the export format of a graph-migration task.
+// An empty format keeps the default RDF/XML export; quad formats are rejected
+// because the data is imported into a single target graph.
+func migrationExportFormat(task Task) (string, error) {
+	format := strings.ToLower(task.ExportFormat)
+	if format == "" {
+		return "", nil
+	}
+	if _, ok := rdfContentTypes[format]; !ok || isQuadFormat(format) {
+		var formats []string
+		for _, f := range sortedKeys(rdfFormatSet()) {
+			if !isQuadFormat(f) {
+				formats = append(formats, f)
+			}
+		}
+		return "", fmt.Errorf("invalid export_format '%s' (supported: %s)", task.ExportFormat, strings.Join(formats, ", "))
+	}
+	return format, nil
+}
+
+// isQuadFormat reports whether a file type carries named graphs of its own
+func isQuadFormat(fileType string) bool {
+	return rdfQuadFormats[fileType]
+}
+
+// resolveImportFormat determines the RDF type of an uploaded file and the extension
+// its temp file needs for import. A non-empty format overrides the detection.
+func resolveImportFormat(filename, format string) (string, string, error) {
+	if format != "" {
+		ext, ok := rdfFormatExtensions[strings.ToLower(format)]
+		if !ok {
+			formats := make([]string, 0, len(rdfFormatExtensions))
+			for name := range rdfFormatExtensions {
+				formats = append(formats, name)
+			}
+			sort.Strings(formats)
+			return "", "", fmt.Errorf("unsupported format '%s' for file '%s'. Supported formats: %s", format, filename, strings.Join(formats, ", "))
+		}
+		return strings.ToLower(format), ext, nil
+	}
+
+	fileType := getFileType(filename)
+	if fileType == "unknown" {
+		return "", "", fmt.Errorf("unsupported file type for '%s'. Supported extensions: %s (or set a format override)", filename, supportedRDFExtensions())
 	}
+	return fileType, filepath.Ext(filename), nil
 }
 
 // getRepositoryNames extracts repository names from GraphDB API response bindings.
@@ -239,6 +623,8 @@ func getRepositoryNames(bindings []db.GraphDBBinding) []string {
 }
 
 // URL2ServiceRobust parses a URL string and extracts the service portion (host:port).
+// The port is kept when the URL has one so Ziti intercepts identified by host:port
+// match; otherwise only the host is returned.
 func URL2ServiceRobust(urlStr string) (string, error) {
 	// Add scheme if missing to help url.Parse work correctly
 	if !strings.HasPrefix(urlStr, "http://") && !strings.HasPrefix(urlStr, "https://") {
@@ -250,17 +636,134 @@ func URL2ServiceRobust(urlStr string) (string, error) {
 		return "", err
 	}
 
+	if port := parsedURL.Port(); port != "" {
+		return net.JoinHostPort(parsedURL.Hostname(), port), nil
+	}
 	return parsedURL.Hostname(), nil
 }
+
+// ProgressFunc receives progress updates from long running tasks.
+// stage describes the current step (e.g. "Exporting graph"), current and total
+// count the items processed within that stage.
+type ProgressFunc func(stage string, current, total int)
+
+// processTask executes a single task; its progress is only logged.
 func processTask(task Task, files map[string][]*multipart.FileHeader, taskIndex int) (map[string]interface{}, error) {
+	return processTaskContext(context.Background(), task, files, taskIndex, nil)
+}
+
+// processTaskWithProgress executes a single task and reports the progress of
+// multi-step actions (repo-rename, graph-migration, graph-merge) to progress.
+// Progress events are logged at debug level as well, also with a nil progress.
+func processTaskWithProgress(task Task, files map[string][]*multipart.FileHeader, taskIndex int, progress ProgressFunc) (map[string]interface{}, error) {
+	return processTaskContext(context.Background(), task, files, taskIndex, progress)
+}
+
+// taskTimeout returns the effective timeout of a task: TimeoutSeconds if set,
+// otherwise the TASK_TIMEOUT_SECONDS env var. Zero means no timeout.
+func taskTimeout(task Task) time.Duration {
+	seconds := task.TimeoutSeconds
+	if seconds <= 0 {
+		seconds, _ = strconv.Atoi(os.Getenv("TASK_TIMEOUT_SECONDS"))
+	}
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// errTaskTimeout is wrapped by the error of a task cancelled by its timeout
+var errTaskTimeout = errors.New("task timed out")
+
+// processTaskContext executes a single task bound to ctx. The task timeout is
+// applied on top of ctx; when it expires all GraphDB requests of the task are
+// cancelled and a timeout error naming the effective timeout is returned.
+// A task aborted by cancelling ctx returns an error wrapping errTaskCancelled.
+// URL credentials are masked in the result and the error.
+func processTaskContext(ctx context.Context, task Task, files map[string][]*multipart.FileHeader, taskIndex int, progress ProgressFunc) (map[string]interface{}, error) {
+	timeout := taskTimeout(task)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	result, err := executeTask(ctx, task, files, taskIndex, progress)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, redactError(fmt.Errorf("%w after %s: %w", errTaskTimeout, timeout, err))
+	}
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		return nil, redactError(fmt.Errorf("%w: %w", errTaskCancelled, err))
+	}
+	if err != nil {
+		return nil, redactError(err)
+	}
+	redactResult(result)
+	return result, nil
+}
+
+// taskRun is the state of a task execution shared with its action handler.
+// The clients are bound to ctx and retry transient failures; handlers replace
+// them with Ziti clients from zitiClient when an identity file is configured.
+type taskRun struct {
+	ctx        context.Context
+	task       Task
+	files      map[string][]*multipart.FileHeader
+	taskIndex  int
+	progress   ProgressFunc
+	log        *slog.Logger
+	srcClient  *http.Client
+	tgtClient  *http.Client
+	zitiClient func(serviceURL string) (*http.Client, error)
+	tempDir    string                 // Directory for the temp files of the task, see tempFile
+	result     map[string]interface{} // Handlers add their output to this result
+	listings   *taskListings          // Listing cache of the request, nil without one; see graphDB
+	timings    taskTimings            // Durations of the steps timed with timeStep
+}
+
+// executeTask performs the action of a task with its registered ActionHandler.
+// All HTTP requests made for the task are bound to ctx.
+func executeTask(ctx context.Context, task Task, files map[string][]*multipart.FileHeader, taskIndex int, progress ProgressFunc) (map[string]interface{}, error) {
+	log := taskLogger(task, taskIndex)
+
+	// Every progress event is logged, also for callers that do not consume it
+	report := progress
+	progress = func(stage string, current, total int) {
+		log.Debug("Task progress", "stage", stage, "current", current, "total", total)
+		if report != nil {
+			report(stage, current, total)
+		}
+	}
+
 	defer func() {
 		if r := recover(); r != nil {
-			fmt.Printf("PANIC RECOVERED in processTask for action %s: %v\n", task.Action, r)
+			log.Error("Panic recovered in processTask", "panic", fmt.Sprint(r))
 		}
 	}()
 
-	srcClient := http.DefaultClient
-	tgtClient := http.DefaultClient
+	// Retry transient GraphDB failures (5xx, connection resets) on every client used by the task,
+	// and bind their requests to the task context so a timeout cancels them
+	retrier := newTaskRetrier(retryPolicyForTask(task))
+	// and authenticate with the src/tgt tokens when they are set
+	zitiClient := func(serviceURL string) (*http.Client, error) {
+		client, err := graphDBClients.get(identityFile, serviceURL)
+		if err != nil {
+			return nil, err
+		}
+		return withContext(ctx, withTaskAuth(task, retrier.wrap(client))), nil
+	}
+
+	srcClient := withContext(ctx, withTaskAuth(task, retrier.wrap(http.DefaultClient)))
+	tgtClient := withContext(ctx, withTaskAuth(task, retrier.wrap(http.DefaultClient)))
+
+	// The retries are recorded on the task of the session, also when it fails
+	if sessionID := sessionIDFromContext(ctx); sessionID != "" && migrationLogger != nil {
+		defer func() {
+			if retries := retrier.Retries(); retries > 0 {
+				migrationLogger.SetTaskRetries(sessionID, taskIndex, retries)
+			}
+		}()
+	}
 
 	// Enable HTTP debug logging if debug mode is active
 	if debugMode {
@@ -274,978 +777,1982 @@ func processTask(task Task, files map[string][]*multipart.FileHeader, taskIndex
 		"action": task.Action,
 		"status": "completed",
 	}
+	run := &taskRun{
+		ctx:        ctx,
+		task:       task,
+		files:      files,
+		taskIndex:  taskIndex,
+		progress:   progress,
+		log:        log,
+		srcClient:  srcClient,
+		tgtClient:  tgtClient,
+		zitiClient: zitiClient,
+		tempDir:    tempDirFromContext(ctx),
+		result:     result,
+		listings:   newTaskListings(listingCacheFromContext(ctx), task),
+	}
 
-	switch task.Action {
-	case "repo-migration":
-		if identityFile != "" {
-			srcURL, err := URL2ServiceRobust(task.Src.URL)
-			if err != nil {
-				return nil, err
-			}
-			srcClient, err = db.GraphDBZitiClient(identityFile, srcURL)
-			if err != nil {
-				return nil, err
-			}
-			if debugMode {
-				srcClient = enableHTTPDebugLogging(srcClient)
-			}
-			tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
-			if err != nil {
-				return nil, err
-			}
-			tgtClient, err = db.GraphDBZitiClient(identityFile, tgtURL)
-			if err != nil {
-				return nil, err
-			}
-			if debugMode {
-				tgtClient = enableHTTPDebugLogging(tgtClient)
-			}
-		}
-		db.HttpClient = srcClient
-		srcGraphDB, err := db.GraphDBRepositories(task.Src.URL, task.Src.Username, task.Src.Password)
+	handler, ok := actionHandlers[task.Action]
+	if !ok {
+		return nil, fmt.Errorf("invalid action: %s", task.Action)
+	}
+	if task.ClusterAware {
+		resolveClusterLeader(run)
+	}
+	if cache := listingCacheFromContext(ctx); cache != nil {
+		defer cache.invalidateTask(task)
+	}
+	if err := handler.Execute(run); err != nil {
+		return nil, err
+	}
+
+	if retries := retrier.Retries(); retries > 0 {
+		result["retry_count"] = retries
+	}
+	if timings := run.timings.milliseconds(); timings != nil {
+		result["timings"] = timings
+	}
+
+	return result, nil
+}
+
+// executeRepoMigrationTask executes the repo-migration action.
+func executeRepoMigrationTask(run *taskRun) error {
+	task, result, srcClient, tgtClient, zitiClient := run.task, run.result, run.srcClient, run.tgtClient, run.zitiClient
+
+	if identityFile != "" {
+		srcURL, err := URL2ServiceRobust(task.Src.URL)
 		if err != nil {
-			return nil, err
-		}
-		foundRepo := false
-		confFile := ""
-		dataFile := ""
-		for _, bind := range srcGraphDB.Results.Bindings {
-			if bind.Id["value"] == task.Src.Repo {
-				foundRepo = true
-				var err error
-				confFile, err = db.GraphDBRepositoryConf(task.Src.URL, task.Src.Username, task.Src.Password, bind.Id["value"])
-				if err != nil {
-					return nil, fmt.Errorf("failed to download repository config: %w", err)
-				}
-				dataFile, err = db.GraphDBRepositoryBrf(task.Src.URL, task.Src.Username, task.Src.Password, bind.Id["value"])
-				if err != nil {
-					return nil, fmt.Errorf("failed to download repository data: %w", err)
-				}
-			}
+			return err
 		}
-		if !foundRepo {
-			return nil, errors.New("could not find required src repository " + task.Src.Repo)
-		}
-		db.HttpClient = tgtClient
-		tgtGraphDB, err := db.GraphDBRepositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+		srcClient, err = zitiClient(srcURL)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		for _, bind := range tgtGraphDB.Results.Bindings {
-			if bind.Id["value"] == task.Src.Repo {
-				err := db.GraphDBDeleteRepository(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Src.Repo)
-				if err != nil {
-					return nil, err
-				}
-			}
+		if debugMode {
+			srcClient = enableHTTPDebugLogging(srcClient)
 		}
-		err = db.GraphDBRestoreConf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, confFile)
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		err = db.GraphDBRestoreBrf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, dataFile)
+		tgtClient, err = zitiClient(tgtURL)
 		if err != nil {
-			return nil, err
+			return err
 		}
-
-		// Get data file size
-		dataSize := int64(0)
-		if fileInfo, err := os.Stat(dataFile); err == nil {
-			dataSize = fileInfo.Size()
+		if debugMode {
+			tgtClient = enableHTTPDebugLogging(tgtClient)
 		}
-
-		_ = os.Remove(confFile)
-		_ = os.Remove(dataFile)
-		result["message"] = "Repository migrated successfully"
-		result["src_repo"] = task.Src.Repo
-		result["tgt_repo"] = task.Tgt.Repo
-		result["data_size"] = dataSize
-
-	case "graph-migration":
-		if identityFile != "" {
-			srcURL, err := URL2ServiceRobust(task.Src.URL)
-			if err != nil {
-				return nil, err
-			}
-			srcClient, err = db.GraphDBZitiClient(identityFile, srcURL)
-			if err != nil {
-				return nil, err
-			}
-			tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
-			if err != nil {
-				return nil, err
-			}
-			tgtClient, err = db.GraphDBZitiClient(identityFile, tgtURL)
+	}
+	done := run.timeStep(stepListRepositories)
+	srcGraphDB, err := run.graphDB(srcClient).Repositories(task.Src.URL, task.Src.Username, task.Src.Password)
+	done()
+	if err != nil {
+		return err
+	}
+	foundRepo := false
+	confFile := ""
+	for _, bind := range srcGraphDB.Results.Bindings {
+		if bind.Id["value"] == task.Src.Repo {
+			foundRepo = true
+			confFile = run.tempFile(fmt.Sprintf("repo_migration_%s.ttl", uuid.New().String()))
+			done := run.timeStep(stepDownloadConfig)
+			err := graphDBDownloadRepositoryConfig(srcClient, task.Src.URL, task.Src.Username, task.Src.Password, bind.Id["value"], confFile)
+			done()
 			if err != nil {
-				return nil, err
+				return fmt.Errorf("failed to download repository config: %w", err)
 			}
 		}
-		db.HttpClient = srcClient
-		srcGraphDB, err := db.GraphDBRepositories(task.Src.URL, task.Src.Username, task.Src.Password)
+	}
+	if !foundRepo {
+		return newTaskError(ErrRepoNotFound, "could not find required src repository %s", task.Src.Repo)
+	}
+	defer func() { _ = os.Remove(confFile) }() // Clean up config file
+
+	// With src.graphs only the listed graphs are transferred; all must exist
+	selective := len(task.Src.Graphs) > 0
+	if selective {
+		done := run.timeStep(stepListGraphs)
+		skipped, err := checkSelectedGraphs(run, srcClient)
+		done()
 		if err != nil {
-			return nil, err
+			return err
 		}
-		foundRepo := false
-		graphFile := md5Hash(task.Src.Graph) + ".brf"
-		for _, bind := range srcGraphDB.Results.Bindings {
-			if bind.Id["value"] == task.Src.Repo {
-				foundRepo = true
-				srcGraphDB, err := db.GraphDBListGraphs(task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo)
-				if err != nil {
-					return nil, err
-				}
-				foundGraph := false
-				for _, bind := range srcGraphDB.Results.Bindings {
-					if bind.ContextID.Value == task.Tgt.Graph {
-						foundGraph = true
-						err := db.GraphDBExportGraphRdf(task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo, task.Src.Graph, graphFile)
-						if err != nil {
-							return nil, err
-						}
-					}
-				}
-				if !foundGraph {
-					return nil, errors.New("could not find required src graph " + task.Src.Graph + " in repository " + task.Src.Repo)
-				}
-			}
+		result["skipped_graphs"] = skipped
+	}
+
+	// Restore the configuration under the target name, not the one of the source
+	configRepoID, err := extractRepositoryID(confFile)
+	if err != nil {
+		return fmt.Errorf("invalid configuration of repository '%s': %w", task.Src.Repo, err)
+	}
+	if configRepoID != task.Tgt.Repo {
+		if err := updateRepositoryNameInConfig(confFile, configRepoID, task.Tgt.Repo); err != nil {
+			return fmt.Errorf("failed to update repository name in config: %w", err)
 		}
-		if !foundRepo {
-			return nil, errors.New("could not find required src repository " + task.Src.Repo)
+	}
+
+	// An existing target repository is only replaced with force_recreate
+	done = run.timeStep(stepListRepositories)
+	tgtGraphDB, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	done()
+	if err != nil {
+		return err
+	}
+	for _, bind := range tgtGraphDB.Results.Bindings {
+		if bind.Id["value"] != task.Tgt.Repo {
+			continue
 		}
-		db.HttpClient = tgtClient
-		tgtGraphDB, err := db.GraphDBRepositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+		if !task.ForceRecreate {
+			return fmt.Errorf("target repository '%s' already exists (set force_recreate to replace it)", task.Tgt.Repo)
+		}
+		done := run.timeStep(stepDeleteTarget)
+		err := run.graphDB(tgtClient).DeleteRepository(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
+		done()
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("failed to delete target repository '%s': %w", task.Tgt.Repo, err)
 		}
-		foundRepo = false
-		for _, bind := range tgtGraphDB.Results.Bindings {
-			if bind.Id["value"] == task.Tgt.Repo {
-				foundRepo = true
-				tgtGraphDB, err := db.GraphDBListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
-				if err != nil {
-					return nil, err
-				}
-				for _, bind := range tgtGraphDB.Results.Bindings {
-					if bind.ContextID.Value == task.Tgt.Graph {
-						err := db.GraphDBDeleteGraph(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Graph)
-						if err != nil {
-							return nil, err
-						}
-					}
-				}
-				err = db.GraphDBImportGraphRdf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Graph, graphFile)
-				if err != nil {
-					return nil, err
-				}
+		result["recreated"] = true
+	}
+	done = run.timeStep(stepRestoreConfig)
+	err = run.graphDB(tgtClient).RestoreConf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, confFile)
+	done()
+	if err != nil {
+		return err
+	}
+
+	// Stream the repository data (BRF) from source to target without a local copy,
+	// or transfer the selected graphs one by one
+	var dataSize int64
+	if selective {
+		dataSize, err = migrateSelectedGraphs(run, srcClient, tgtClient)
+	} else {
+		done := run.timeStep(stepTransferData)
+		dataSize, err = graphDBStreamRepositoryData(
+			srcClient, task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo,
+			tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, run.progress,
+		)
+		done()
+	}
+	if err != nil {
+		return err
+	}
+
+	result["message"] = "Repository migrated successfully"
+	if selective {
+		result["message"] = "Repository migrated with the selected graphs"
+	}
+	result["src_repo"] = task.Src.Repo
+	result["tgt_repo"] = task.Tgt.Repo
+	result["data_size"] = dataSize
+
+	// Optionally verify the migration by comparing the triple counts, of the
+	// transferred graphs only for a selective migration
+	if task.Verify {
+		countTriples := func(client *http.Client, repo *Repository) (int, error) {
+			defer run.timeStep(stepVerify)()
+			if selective {
+				transferred, _ := result["transferred_graphs"].([]string)
+				return selectedGraphTriples(client, repo, transferred)
 			}
+			return countRepositoryTriples(client, repo.URL, repo.Username, repo.Password, repo.Repo)
 		}
-		if !foundRepo {
-			return nil, errors.New("could not find required tgt repository " + task.Tgt.Repo)
+		srcTriples, err := countTriples(srcClient, task.Src)
+		if err != nil {
+			return fmt.Errorf("failed to count triples in source repository '%s': %w", task.Src.Repo, err)
 		}
-
-		// Get graph file size
-		dataSize := int64(0)
-		if fileInfo, err := os.Stat(graphFile); err == nil {
-			dataSize = fileInfo.Size()
+		tgtTriples, err := countTriples(tgtClient, task.Tgt)
+		if err != nil {
+			return fmt.Errorf("failed to count triples in target repository '%s': %w", task.Tgt.Repo, err)
 		}
 
-		_ = os.Remove(graphFile) // Clean up temporary file
-		result["message"] = "Graph migrated successfully"
-		result["src_graph"] = task.Src.Graph
-		result["tgt_graph"] = task.Tgt.Graph
-		result["data_size"] = dataSize
-
-	case "repo-delete":
-		debugLog("repo-delete action started")
-		debugLog("Target URL: %s", task.Tgt.URL)
-		debugLog("Target Repo: %s", task.Tgt.Repo)
-		debugLog("Username: %s", task.Tgt.Username)
-
-		if identityFile != "" {
-			debugLog("Using Ziti identity file: %s", identityFile)
-			tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
-			if err != nil {
-				debugLog("Failed to parse target URL: %v", err)
-				return nil, err
-			}
-			debugLog("Parsed Ziti service URL: %s", tgtURL)
-			tgtClient, err = db.GraphDBZitiClient(identityFile, tgtURL)
-			if err != nil {
-				debugLog("Failed to create Ziti client: %v", err)
-				return nil, err
-			}
-			if debugMode {
-				tgtClient = enableHTTPDebugLogging(tgtClient)
-			}
-			debugLog("Ziti client created successfully")
+		result["src_triples"] = srcTriples
+		result["tgt_triples"] = tgtTriples
+		result["verified"] = srcTriples == tgtTriples
+		if srcTriples != tgtTriples {
+			result["status"] = "completed_with_warning"
+			result["warning"] = fmt.Sprintf("Triple count mismatch: source repository has %d triples, target repository has %d triples", srcTriples, tgtTriples)
 		}
+	}
 
-		db.HttpClient = tgtClient
-
-		debugLog("Fetching list of repositories...")
-		tgtGraphDB, err := db.GraphDBRepositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	// Optionally report the graphs of the target repository, one count query per graph
+	if task.ReportGraphs {
+		done := run.timeStep(stepReportGraphs)
+		graphs, err := repositoryGraphCounts(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
+		done()
 		if err != nil {
-			debugLog("ERROR: Failed to fetch repositories: %v", err)
-			debugLog("Error type: %T", err)
-			return nil, fmt.Errorf("failed to fetch repositories from %s: %w", task.Tgt.URL, err)
+			return fmt.Errorf("failed to list graphs in target repository '%s': %w", task.Tgt.Repo, err)
 		}
+		result["graphs"] = graphs
+	}
+	return nil
+}
 
-		debugLog("Found %d repositories", len(tgtGraphDB.Results.Bindings))
-
-		repoFound := false
-		for i, bind := range tgtGraphDB.Results.Bindings {
-			repoID := bind.Id["value"]
-			debugLog("Repository %d: %s", i, repoID)
-
-			if repoID == task.Tgt.Repo {
-				repoFound = true
-				debugLog("Found target repository: %s", task.Tgt.Repo)
-				debugLog("Attempting to delete repository...")
-				debugLog("DELETE URL: %s/repositories/%s", task.Tgt.URL, task.Tgt.Repo)
-
-				err := db.GraphDBDeleteRepository(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
-				if err != nil {
-					debugLog("ERROR: GraphDBDeleteRepository failed: %v", err)
-					debugLog("Error type: %T", err)
-					debugLog("Error string: %s", err.Error())
-
-					// Try to extract more details from the error
-					if strings.Contains(err.Error(), "400") {
-						debugLog("===== 400 Bad Request Error =====")
-						debugLog("Full error details: %+v", err)
-					}
-
-					return nil, fmt.Errorf("failed to delete repository %s: %w", task.Tgt.Repo, err)
-				}
-				debugLog("Repository %s deleted successfully", task.Tgt.Repo)
-				break
-			}
-		}
-
-		if !repoFound {
-			debugLog("WARNING: Repository %s not found in repository list", task.Tgt.Repo)
-			return nil, fmt.Errorf("repository %s not found on server %s", task.Tgt.Repo, task.Tgt.URL)
+// repositoryGraphCounts lists the named graphs of a repository with their
+// triple counts. A count of -1 indicates that the count could not be determined.
+func repositoryGraphCounts(client *http.Client, url, username, password, repo string) ([]map[string]interface{}, error) {
+	graphsList, err := graphDBWith(client).ListGraphs(url, username, password, repo)
+	if err != nil {
+		return nil, err
+	}
+	graphs := make([]map[string]interface{}, 0, len(graphsList.Results.Bindings))
+	for _, bind := range graphsList.Results.Bindings {
+		count, err := countGraphTriples(client, url, username, password, repo, bind.ContextID.Value)
+		if err != nil {
+			debugLog("Failed to count triples in graph %s: %v", bind.ContextID.Value, err)
 		}
+		graphs = append(graphs, map[string]interface{}{"graph": bind.ContextID.Value, "triples": count})
+	}
+	return graphs, nil
+}
 
-		result["message"] = "Repository deleted successfully"
-		result["repo"] = task.Tgt.Repo
-		debugLog("repo-delete action completed successfully")
+// executeGraphMigrationTask executes the graph-migration action.
+func executeGraphMigrationTask(run *taskRun) error {
+	task, progress, result, srcClient, tgtClient, zitiClient := run.task, run.progress, run.result, run.srcClient, run.tgtClient, run.zitiClient
 
-	case "graph-delete":
-		if identityFile != "" {
-			tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
-			if err != nil {
-				return nil, err
-			}
-			tgtClient, err = db.GraphDBZitiClient(identityFile, tgtURL)
-			if err != nil {
-				return nil, err
-			}
+	if identityFile != "" {
+		srcURL, err := URL2ServiceRobust(task.Src.URL)
+		if err != nil {
+			return err
 		}
-		db.HttpClient = tgtClient
-		tgtGraphDB, err := db.GraphDBListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
+		srcClient, err = zitiClient(srcURL)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		for _, bind := range tgtGraphDB.Results.Bindings {
-			if bind.ContextID.Value == task.Tgt.Graph {
-				err := db.GraphDBDeleteGraph(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Graph)
-				if err != nil {
-					return nil, err
-				}
-			}
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
+		if err != nil {
+			return err
 		}
-		result["message"] = "Graph deleted successfully"
-		result["graph"] = task.Tgt.Graph
-
-	case "repo-import":
-		if identityFile != "" {
-			tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
+		tgtClient, err = zitiClient(tgtURL)
+		if err != nil {
+			return err
+		}
+	}
+	done := run.timeStep(stepListRepositories)
+	srcGraphDB, err := run.graphDB(srcClient).Repositories(task.Src.URL, task.Src.Username, task.Src.Password)
+	done()
+	if err != nil {
+		return err
+	}
+	exportFormat, err := migrationExportFormat(task)
+	if err != nil {
+		return err
+	}
+	if isRDFStarFormat(exportFormat) {
+		warnUnlessRDFStar(srcClient, task.Src, result)
+		warnUnlessRDFStar(tgtClient, task.Tgt, result)
+	}
+	foundRepo := false
+	graphFile := run.tempFile(md5Hash(task.Src.Graph) + ".brf")
+	contentType := rdfMediaType("rdf-xml", task.Src.Accept)
+	if exportFormat != "" {
+		graphFile = run.tempFile(md5Hash(task.Src.Graph) + rdfFormatExtensions[exportFormat])
+		contentType = rdfMediaType(exportFormat, task.Src.Accept)
+	}
+	// The export is imported as the type it was requested in unless tgt overrides it
+	importType := contentType
+	if task.Tgt.ContentType != "" {
+		importType = task.Tgt.ContentType
+	}
+	var compression exportCompression
+	var blankNodes int
+	for _, bind := range srcGraphDB.Results.Bindings {
+		if bind.Id["value"] == task.Src.Repo {
+			foundRepo = true
+			done := run.timeStep(stepListGraphs)
+			srcGraphDB, err := run.graphDB(srcClient).ListGraphs(task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo)
+			done()
 			if err != nil {
-				return nil, err
+				return err
 			}
-			tgtClient, err = db.GraphDBZitiClient(identityFile, tgtURL)
-			if err != nil {
-				return nil, err
+			foundGraph := false
+			if graphListed(srcGraphDB, task.Tgt.Graph) {
+				foundGraph = true
+				blankNodes = recordBlankNodes(srcClient, task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo, task.Src.Graph, result)
+				// The graph is exported as a single document and imported in one
+				// request, so blank node labels keep their document scope
+				if err := run.ctx.Err(); err != nil {
+					return err
+				}
+				progress("Exporting graph", 1, 1)
+				done := run.timeStep(stepExportGraphs)
+				if exportFormat != "" || task.Src.Accept != "" {
+					_, err = graphDBExportGraphToFile(srcClient, task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo, task.Src.Graph, contentType, graphFile)
+				} else {
+					err = run.graphDB(srcClient).ExportGraphRdf(task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo, task.Src.Graph, graphFile)
+				}
+				if err != nil {
+					done()
+					return err
+				}
+				importFile, err := compression.compressExport(task, graphFile)
+				done()
+				if err != nil {
+					_ = os.Remove(graphFile)
+					return err
+				}
+				graphFile = importFile
 			}
-		}
-		db.HttpClient = tgtClient
-		debugLog("Starting repo-import processing")
-
-		// Check if target repository exists
-		debugLog("Fetching repositories from %s", task.Tgt.URL)
-		tgtGraphDB, err := db.GraphDBRepositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
-		if err != nil {
-			return nil, err
-		}
-
-		foundRepo := false
-		for _, bind := range tgtGraphDB.Results.Bindings {
-			if bind.Id["value"] == task.Tgt.Repo {
-				foundRepo = true
-				break
+			if !foundGraph {
+				return newTaskError(ErrGraphNotFound, "could not find required src graph %s in repository %s", task.Src.Graph, task.Src.Repo)
 			}
 		}
-
-		if !foundRepo {
-			fmt.Printf("ERROR: Repository '%s' not found\n", task.Tgt.Repo)
-			return nil, fmt.Errorf("repository '%s' not found. Available repositories: %v", task.Tgt.Repo, getRepositoryNames(tgtGraphDB.Results.Bindings))
-		}
-
-		debugLog("Repository '%s' found in GraphDB", task.Tgt.Repo)
-
-		// Get BRF data file from source repository (if specified) or use a local file
-		// This follows the same pattern as repo-migration
-		if task.Src != nil && task.Src.Repo != "" {
-			// Import from another repository's BRF file
-			srcGraphDB, err := db.GraphDBRepositories(task.Src.URL, task.Src.Username, task.Src.Password)
+	}
+	if !foundRepo {
+		return newTaskError(ErrRepoNotFound, "could not find required src repository %s", task.Src.Repo)
+	}
+	done = run.timeStep(stepListRepositories)
+	tgtGraphDB, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	done()
+	if err != nil {
+		return err
+	}
+	foundRepo = false
+	for _, bind := range tgtGraphDB.Results.Bindings {
+		if bind.Id["value"] == task.Tgt.Repo {
+			foundRepo = true
+			done := run.timeStep(stepListGraphs)
+			tgtGraphDB, err := run.graphDB(tgtClient).ListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
+			done()
 			if err != nil {
-				return nil, err
+				return err
 			}
-
-			srcFoundRepo := false
-			dataFile := ""
-			for _, bind := range srcGraphDB.Results.Bindings {
-				if bind.Id["value"] == task.Src.Repo {
-					srcFoundRepo = true
-					var err error
-					dataFile, err = db.GraphDBRepositoryBrf(task.Src.URL, task.Src.Username, task.Src.Password, bind.Id["value"])
-					if err != nil {
-						return nil, fmt.Errorf("failed to download repository data: %w", err)
-					}
-					break
+			if graphListed(tgtGraphDB, task.Tgt.Graph) {
+				done := run.timeStep(stepDeleteTarget)
+				err := run.graphDB(tgtClient).DeleteGraph(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Graph)
+				done()
+				if err != nil {
+					return err
 				}
 			}
-
-			if !srcFoundRepo {
-				return nil, fmt.Errorf("source repository '%s' not found", task.Src.Repo)
+			if err := run.ctx.Err(); err != nil {
+				return err
 			}
-
-			debugLog("Importing BRF data from %s to repository %s", task.Src.Repo, task.Tgt.Repo)
-			err = db.GraphDBRestoreBrf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, dataFile)
+			progress("Importing graph", 1, 1)
+			done = run.timeStep(stepImportGraphs)
+			err = importExportedGraph(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Graph, graphFile, importType)
+			done()
 			if err != nil {
-				return nil, err
+				return err
+			}
+			if err := verifyBlankNodes(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Graph, blankNodes); err != nil {
+				return err
 			}
+		}
+	}
+	if !foundRepo {
+		return newTaskError(ErrRepoNotFound, "could not find required tgt repository %s", task.Tgt.Repo)
+	}
 
-			// Clean up the temporary BRF file
-			_ = os.Remove(dataFile)
+	_ = os.Remove(graphFile) // Clean up temporary file
+	result["message"] = "Graph migrated successfully"
+	result["src_graph"] = task.Src.Graph
+	result["tgt_graph"] = task.Tgt.Graph
+	result["data_size"] = compression.uncompressedBytes
+	compression.report(result)
+	if exportFormat != "" {
+		result["export_format"] = exportFormat
+	}
+	if task.Src.Accept != "" || task.Tgt.ContentType != "" {
+		result["export_content_type"] = contentType
+		result["import_content_type"] = importType
+	}
+	return nil
+}
 
-			result["message"] = "Repository import completed successfully"
-			result["source_repository"] = task.Src.Repo
-			result["target_repository"] = task.Tgt.Repo
-		} else {
-			// Handle file uploads if using multipart form
-			if files != nil {
-				fileKey := fmt.Sprintf("task_%d_files", taskIndex)
-				if taskFiles, exists := files[fileKey]; exists && len(taskFiles) > 0 {
-					// Process the first BRF file
-					fileHeader := taskFiles[0]
+// executeRepoDeleteTask executes the repo-delete action.
+func executeRepoDeleteTask(run *taskRun) error {
+	task, result, tgtClient, zitiClient := run.task, run.result, run.tgtClient, run.zitiClient
 
-					file, err := fileHeader.Open()
-					if err != nil {
-						return nil, fmt.Errorf("failed to open file %s: %w", fileHeader.Filename, err)
-					}
-					defer func() { _ = file.Close() }()
+	debugLog("repo-delete action started")
+	debugLog("Target URL: %s", task.Tgt.URL)
+	debugLog("Target Repo: %s", task.Tgt.Repo)
+	debugLog("Username: %s", task.Tgt.Username)
 
-					// Save file temporarily with unique UUID-based filename to avoid conflicts
-					fileExt := filepath.Ext(fileHeader.Filename)
-					tempFileName := filepath.Join(os.TempDir(), fmt.Sprintf("repo_import_%s%s", uuid.New().String(), fileExt))
-					defer func() { _ = os.Remove(tempFileName) }()
+	if identityFile != "" {
+		debugLog("Using Ziti identity file: %s", identityFile)
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
+		if err != nil {
+			debugLog("Failed to parse target URL: %v", err)
+			return err
+		}
+		debugLog("Parsed Ziti service URL: %s", tgtURL)
+		tgtClient, err = zitiClient(tgtURL)
+		if err != nil {
+			debugLog("Failed to create Ziti client: %v", err)
+			return err
+		}
+		if debugMode {
+			tgtClient = enableHTTPDebugLogging(tgtClient)
+		}
+		debugLog("Ziti client created successfully")
+	}
+	if task.Tgt.Pattern != "" {
+		return executeRepoDeleteByPattern(run, tgtClient)
+	}
 
-					tempFile, err := os.Create(tempFileName)
-					if err != nil {
-						return nil, fmt.Errorf("failed to create temp file: %w", err)
-					}
-					defer func() { _ = tempFile.Close() }()
+	debugLog("Fetching list of repositories...")
+	tgtGraphDB, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	if err != nil {
+		debugLog("ERROR: Failed to fetch repositories: %v", err)
+		debugLog("Error type: %T", err)
+		return fmt.Errorf("failed to fetch repositories from %s: %w", task.Tgt.URL, err)
+	}
 
-					_, err = tempFile.ReadFrom(file)
-					if err != nil {
-						return nil, fmt.Errorf("failed to copy file: %w", err)
-					}
-					_ = tempFile.Close()
+	debugLog("Found %d repositories", len(tgtGraphDB.Results.Bindings))
 
-					// Import the BRF file
-					debugLog("Importing BRF file %s to repository %s", fileHeader.Filename, task.Tgt.Repo)
-					err = db.GraphDBRestoreBrf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, tempFileName)
-					if err != nil {
-						return nil, fmt.Errorf("failed to import BRF file: %w", err)
-					}
+	repoFound := false
+	for i, bind := range tgtGraphDB.Results.Bindings {
+		repoID := bind.Id["value"]
+		debugLog("Repository %d: %s", i, repoID)
 
-					result["message"] = "Repository import completed successfully"
-					result["imported_file"] = fileHeader.Filename
-					result["target_repository"] = task.Tgt.Repo
-				} else {
-					return nil, fmt.Errorf("no BRF files provided for import")
-				}
-			} else {
-				return nil, fmt.Errorf("no source repository or files specified for import")
+		if repoID == task.Tgt.Repo {
+			repoFound = true
+			debugLog("Found target repository: %s", task.Tgt.Repo)
+			if task.DryRun {
+				break
 			}
-		}
+			debugLog("Attempting to delete repository...")
+			debugLog("DELETE URL: %s/repositories/%s", task.Tgt.URL, task.Tgt.Repo)
 
-	case "repo-create":
-		if identityFile != "" {
-			tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
-			if err != nil {
-				return nil, err
-			}
-			tgtClient, err = db.GraphDBZitiClient(identityFile, tgtURL)
+			err := run.graphDB(tgtClient).DeleteRepository(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
 			if err != nil {
-				return nil, err
+				debugLog("ERROR: GraphDBDeleteRepository failed: %v", err)
+				debugLog("Error type: %T", err)
+				debugLog("Error string: %s", err.Error())
+
+				// Try to extract more details from the error
+				if strings.Contains(err.Error(), "400") {
+					debugLog("===== 400 Bad Request Error =====")
+					debugLog("Full error details: %+v", err)
+				}
+
+				return fmt.Errorf("failed to delete repository %s: %w", task.Tgt.Repo, err)
 			}
+			debugLog("Repository %s deleted successfully", task.Tgt.Repo)
+			break
 		}
-		db.HttpClient = tgtClient
-		repoName := task.Tgt.Repo
+	}
 
-		// Check if repository already exists
-		existingRepos, err := db.GraphDBRepositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	if !repoFound {
+		debugLog("WARNING: Repository %s not found in repository list", task.Tgt.Repo)
+		return newTaskError(ErrRepoNotFound, "repository %s not found on server %s", task.Tgt.Repo, task.Tgt.URL)
+	}
+
+	if task.DryRun {
+		graphsList, err := run.graphDB(tgtClient).ListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
 		if err != nil {
-			return nil, err
-		}
-		for _, bind := range existingRepos.Results.Bindings {
-			if bind.Id["value"] == repoName {
-				return nil, fmt.Errorf("repository '%s' already exists", repoName)
-			}
+			return fmt.Errorf("failed to list graphs in repository '%s': %w", task.Tgt.Repo, err)
 		}
-
-		// Require configuration file upload
-		if files == nil {
-			return nil, fmt.Errorf("repo-create requires a configuration file to be uploaded")
+		var operations []map[string]interface{}
+		for _, bind := range graphsList.Results.Bindings {
+			count, _ := countGraphTriples(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, bind.ContextID.Value)
+			operations = append(operations, plannedOperation("delete-graph", task.Tgt.Repo, bind.ContextID.Value, count))
 		}
+		operations = append(operations, plannedOperation("delete-repository", task.Tgt.Repo, "", -1))
+		setDryRunResult(result, "Dry run: repository would be deleted", operations)
+		result["repo"] = task.Tgt.Repo
+		return nil
+	}
 
-		fileKey := fmt.Sprintf("task_%d_config", taskIndex)
-		taskFiles, exists := files[fileKey]
-		if !exists || len(taskFiles) == 0 {
-			return nil, fmt.Errorf("repo-create requires a configuration file with key 'task_%d_config'", taskIndex)
-		}
+	result["message"] = "Repository deleted successfully"
+	result["repo"] = task.Tgt.Repo
+	debugLog("repo-delete action completed successfully")
+	return nil
+}
 
-		// Use the first uploaded configuration file
-		fileHeader := taskFiles[0]
+// executeGraphDeleteTask executes the graph-delete action.
+func executeGraphDeleteTask(run *taskRun) error {
+	task, result, tgtClient, zitiClient := run.task, run.result, run.tgtClient, run.zitiClient
 
-		file, err := fileHeader.Open()
+	if identityFile != "" {
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
 		if err != nil {
-			return nil, fmt.Errorf("failed to open config file %s: %w", fileHeader.Filename, err)
+			return err
 		}
-		defer func() { _ = file.Close() }()
-
-		// Save uploaded config to temporary file with unique UUID-based filename to avoid conflicts
-		fileExt := filepath.Ext(fileHeader.Filename)
-		configFile := filepath.Join(os.TempDir(), fmt.Sprintf("repo_create_%s%s", uuid.New().String(), fileExt))
-		defer func() { _ = os.Remove(configFile) }()
-
-		tempFile, err := os.Create(configFile)
+		tgtClient, err = zitiClient(tgtURL)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create temp config file: %w", err)
-		}
-		defer func() { _ = tempFile.Close() }()
-
-		// Copy uploaded file to temp file
-		if _, err := file.Seek(0, 0); err != nil {
-			return nil, fmt.Errorf("failed to seek config file: %w", err)
+			return err
 		}
-		if _, err := tempFile.ReadFrom(file); err != nil {
-			return nil, fmt.Errorf("failed to copy config file: %w", err)
+	}
+	if task.Tgt.Pattern != "" {
+		return executeGraphDeleteByPattern(run, tgtClient)
+	}
+	tgtGraphDB, err := run.graphDB(tgtClient).ListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
+	if err != nil {
+		return err
+	}
+	if task.DryRun {
+		var operations []map[string]interface{}
+		if graphListed(tgtGraphDB, task.Tgt.Graph) {
+			count, _ := countGraphTriples(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Graph)
+			operations = append(operations, plannedOperation("delete-graph", task.Tgt.Repo, task.Tgt.Graph, count))
 		}
-		_ = tempFile.Close()
-
-		// Update the repository name in config file to match the requested name
-		err = updateRepositoryNameInConfig(configFile, "PLACEHOLDER", repoName)
+		setDryRunResult(result, "Dry run: graph would be deleted", operations)
+		result["graph"] = task.Tgt.Graph
+		return nil
+	}
+	if graphListed(tgtGraphDB, task.Tgt.Graph) {
+		err := run.graphDB(tgtClient).DeleteGraph(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Graph)
 		if err != nil {
-			// Try without replacement if the config file doesn't have placeholders
-			fmt.Printf("Warning: could not update repository name in config: %v\n", err)
+			return err
 		}
+	}
+	result["message"] = "Graph deleted successfully"
+	result["graph"] = task.Tgt.Graph
+	return nil
+}
+
+// executeGraphsDeleteTask executes the graphs-delete action.
+//
+// Delete several graphs of one repository. Each graph gets an entry in
+// graph_results; with continue_on_error a failing graph does not stop the rest.
+func executeGraphsDeleteTask(run *taskRun) error {
+	task, progress, log, result, tgtClient, zitiClient := run.task, run.progress, run.log, run.result, run.tgtClient, run.zitiClient
 
-		// Create the repository using the configuration file
-		err = db.GraphDBRestoreConf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, configFile)
+	if len(task.Tgt.Graphs) == 0 {
+		return fmt.Errorf("graphs-delete requires at least one graph in tgt.graphs")
+	}
+	if identityFile != "" {
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create repository '%s': %w", repoName, err)
+			return err
 		}
-
-		// Verify the repository was created
-		verifyRepos, err := db.GraphDBRepositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+		tgtClient, err = zitiClient(tgtURL)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		repoCreated := false
-		for _, bind := range verifyRepos.Results.Bindings {
-			if bind.Id["value"] == repoName {
-				repoCreated = true
-				break
+	}
+
+	// Check the repository and list its graphs once for all deletions
+	if err := requireTaskRepository(run.graphDB(tgtClient), task.Tgt, "tgt"); err != nil {
+		return err
+	}
+	tgtGraphs, err := run.graphDB(tgtClient).ListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
+	if err != nil {
+		return fmt.Errorf("failed to list graphs in repository '%s': %w", task.Tgt.Repo, err)
+	}
+
+	if task.DryRun {
+		var operations []map[string]interface{}
+		for _, graphURI := range task.Tgt.Graphs {
+			if graphListed(tgtGraphs, graphURI) {
+				count, _ := countGraphTriples(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, graphURI)
+				operations = append(operations, plannedOperation("delete-graph", task.Tgt.Repo, graphURI, count))
 			}
 		}
+		setDryRunResult(result, "Dry run: graphs would be deleted", operations)
+		result["graphs"] = task.Tgt.Graphs
+		return nil
+	}
+
+	graphResults := make([]map[string]interface{}, 0, len(task.Tgt.Graphs))
+	deletedGraphs := make([]string, 0, len(task.Tgt.Graphs))
+	var failedGraphs []string
+	for i, graphURI := range task.Tgt.Graphs {
+		if err := run.ctx.Err(); err != nil {
+			return err
+		}
+		progress("Deleting graphs", i+1, len(task.Tgt.Graphs))
+
+		var deleteErr error
+		if !graphListed(tgtGraphs, graphURI) {
+			deleteErr = newTaskError(ErrGraphNotFound, "graph '%s' not found in repository '%s'", graphURI, task.Tgt.Repo)
+		} else {
+			deleteErr = run.graphDB(tgtClient).DeleteGraph(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, graphURI)
+		}
 
-		if !repoCreated {
-			return nil, fmt.Errorf("repository '%s' was not created successfully", repoName)
+		if deleteErr != nil {
+			if !task.ContinueOnError {
+				return fmt.Errorf("failed to delete graph '%s' after deleting %d of %d graphs: %w", graphURI, len(deletedGraphs), len(task.Tgt.Graphs), deleteErr)
+			}
+			log.Warn("Failed to delete graph", "graph", graphURI, "error", deleteErr)
+			failedGraphs = append(failedGraphs, graphURI)
+			graphResults = append(graphResults, map[string]interface{}{"graph": graphURI, "status": "failed", "error": deleteErr.Error()})
+			continue
 		}
+		deletedGraphs = append(deletedGraphs, graphURI)
+		graphResults = append(graphResults, map[string]interface{}{"graph": graphURI, "status": "deleted"})
+	}
+
+	result["message"] = "Graphs deleted successfully"
+	if len(failedGraphs) > 0 {
+		result["status"] = "partial"
+		result["message"] = fmt.Sprintf("Deleted %d of %d graphs", len(deletedGraphs), len(task.Tgt.Graphs))
+		result["failed_graphs"] = failedGraphs
+	}
+	result["repository"] = task.Tgt.Repo
+	result["deleted_graphs"] = deletedGraphs
+	result["graph_results"] = graphResults
+	return nil
+}
 
-		result["message"] = "Repository created successfully"
-		result["repo"] = repoName
-		result["config_file"] = fileHeader.Filename
+// executeRepoImportTask executes the repo-import action.
+func executeRepoImportTask(run *taskRun) error {
+	task, files, taskIndex, log, result, srcClient, tgtClient, zitiClient := run.task, run.files, run.taskIndex, run.log, run.result, run.srcClient, run.tgtClient, run.zitiClient
 
-	case "graph-import":
-		if identityFile != "" {
-			tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
+	if identityFile != "" {
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
+		if err != nil {
+			return err
+		}
+		tgtClient, err = zitiClient(tgtURL)
+		if err != nil {
+			return err
+		}
+		if task.Src != nil && task.Src.URL != "" {
+			srcURL, err := URL2ServiceRobust(task.Src.URL)
 			if err != nil {
-				return nil, err
+				return err
 			}
-			tgtClient, err = db.GraphDBZitiClient(identityFile, tgtURL)
+			srcClient, err = zitiClient(srcURL)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		}
-		db.HttpClient = tgtClient
-		debugLog("Starting graph-import processing")
+	}
+	debugLog("Starting repo-import processing")
 
-		debugLog("Fetching repositories from %s", task.Tgt.URL)
-		tgtGraphDB, err := db.GraphDBRepositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
-		if err != nil {
-			return nil, err
-		}
+	// Check if target repository exists
+	debugLog("Fetching repositories from %s", task.Tgt.URL)
+	tgtGraphDB, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	if err != nil {
+		return err
+	}
 
-		if tgtGraphDB.Results.Bindings == nil {
-			fmt.Printf("ERROR: GraphDB returned nil bindings from %s\n", task.Tgt.URL)
-			return nil, fmt.Errorf("failed to get repositories from GraphDB at %s - nil response", task.Tgt.URL)
+	foundRepo := false
+	for _, bind := range tgtGraphDB.Results.Bindings {
+		if bind.Id["value"] == task.Tgt.Repo {
+			foundRepo = true
+			break
 		}
+	}
 
-		debugLog("Found %d repositories in GraphDB", len(tgtGraphDB.Results.Bindings))
+	if !foundRepo {
+		log.Error("Repository not found")
+		return newTaskError(ErrRepoNotFound, "repository '%s' not found. Available repositories: %v", task.Tgt.Repo, getRepositoryNames(tgtGraphDB.Results.Bindings))
+	}
 
-		// List all available repositories for debugging
-		if len(tgtGraphDB.Results.Bindings) == 0 {
-			fmt.Printf("WARNING: No repositories found in GraphDB at %s\n", task.Tgt.URL)
-			debugLog("Attempting to create repository '%s' or continue assuming it exists", task.Tgt.Repo)
-			// Continue with import attempt - the repository might exist but not be listed
-		} else {
-			fmt.Printf("DEBUG: Available repositories: ")
-			for i, bind := range tgtGraphDB.Results.Bindings {
-				if i > 0 {
-					fmt.Printf(", ")
-				}
-				fmt.Printf("'%s'", bind.Id["value"])
-			}
-			fmt.Printf("\n")
+	debugLog("Repository '%s' found in GraphDB", task.Tgt.Repo)
+
+	// Get BRF data file from source repository (if specified) or use a local file
+	// This follows the same pattern as repo-migration
+	if task.Src != nil && task.Src.Repo != "" {
+		// Import from another repository's BRF file
+		srcGraphDB, err := run.graphDB(srcClient).Repositories(task.Src.URL, task.Src.Username, task.Src.Password)
+		if err != nil {
+			return err
 		}
 
-		foundRepo := false
-		for _, bind := range tgtGraphDB.Results.Bindings {
-			if bind.Id["value"] == task.Tgt.Repo {
-				foundRepo = true
+		srcFoundRepo := false
+		dataFile := ""
+		for _, bind := range srcGraphDB.Results.Bindings {
+			if bind.Id["value"] == task.Src.Repo {
+				srcFoundRepo = true
+				if err := checkExportDiskSpace(run, srcClient, task.Src, task.Src.Repo); err != nil {
+					return err
+				}
+				dataFile = run.tempFile(fmt.Sprintf("repo_import_%s.brf", uuid.New().String()))
+				if _, err := graphDBDownloadRepositoryData(srcClient, task.Src.URL, task.Src.Username, task.Src.Password, bind.Id["value"], dataFile); err != nil {
+					return fmt.Errorf("failed to download repository data: %w", err)
+				}
 				break
 			}
 		}
 
-		if !foundRepo && len(tgtGraphDB.Results.Bindings) > 0 {
-			fmt.Printf("ERROR: Repository '%s' not found in list of %d repositories\n", task.Tgt.Repo, len(tgtGraphDB.Results.Bindings))
-			return nil, fmt.Errorf("repository '%s' not found. Available repositories: %v", task.Tgt.Repo, getRepositoryNames(tgtGraphDB.Results.Bindings))
+		if !srcFoundRepo {
+			return newTaskError(ErrRepoNotFound, "source repository '%s' not found", task.Src.Repo)
 		}
 
-		// If we reach here, either the repo was found, or the repository list was empty
-		// In case of empty list, we'll attempt the import anyway
-		if foundRepo {
-			debugLog("Repository '%s' found in GraphDB", task.Tgt.Repo)
-		} else {
-			debugLog("Repository list was empty, attempting import to '%s' anyway", task.Tgt.Repo)
-		}
+		debugLog("Importing BRF data from %s to repository %s", task.Src.Repo, task.Tgt.Repo)
+		upload, err := graphDBRestoreRepositoryData(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, dataFile, run.progress)
 
-		// Try to list graphs (this might fail if repository doesn't exist)
-		debugLog("Listing graphs in repository: %s", task.Tgt.Repo)
-		graphsResponse, err := db.GraphDBListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
+		// Clean up the temporary BRF file
+		_ = os.Remove(dataFile)
 		if err != nil {
-			fmt.Printf("WARNING: Failed to list graphs (repository might not exist): %v\n", err)
-			// Continue with import - we'll try to import anyway
-		} else if graphsResponse.Results.Bindings == nil {
-			fmt.Printf("WARNING: GraphDB returned nil response for listing graphs\n")
-		} else {
-			debugLog("Found %d graphs in repository", len(graphsResponse.Results.Bindings))
-			// Check if target graph exists and delete it if found
-			for _, bind := range graphsResponse.Results.Bindings {
-				if bind.ContextID.Value == task.Tgt.Graph {
-					debugLog("Deleting existing graph: %s", task.Tgt.Graph)
-					err := db.GraphDBDeleteGraph(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Graph)
-					if err != nil {
-						fmt.Printf("WARNING: Failed to delete existing graph: %v\n", err)
-						// Don't fail the operation, continue with import
-					}
-					break
-				}
-			}
+			return err
 		}
+		upload.setResult(result)
 
-		// Handle uploaded files for import
+		result["message"] = "Repository import completed successfully"
+		result["source_repository"] = task.Src.Repo
+		result["target_repository"] = task.Tgt.Repo
+	} else {
+		// Handle file uploads if using multipart form
 		if files != nil {
 			fileKey := fmt.Sprintf("task_%d_files", taskIndex)
-			debugLog("Looking for files with key: %s", fileKey)
-
-			if taskFiles, exists := files[fileKey]; exists {
-				debugLog("Found %d files to import", len(taskFiles))
-				result["uploaded_files"] = len(taskFiles)
-				result["file_names"] = getFileNames(taskFiles)
-
-				// Process each uploaded file for import
-				for i, fileHeader := range taskFiles {
-					debugLog("Processing file %d: %s (size: %d bytes)", i, fileHeader.Filename, fileHeader.Size)
-
-					func() {
-						defer func() {
-							if r := recover(); r != nil {
-								fmt.Printf("PANIC in file processing %s: %v\n", fileHeader.Filename, r)
-							}
-						}()
-
-						file, err := fileHeader.Open()
-						if err != nil {
-							fmt.Printf("ERROR: Failed to open file %s: %v\n", fileHeader.Filename, err)
-							return
-						}
-						defer func() { _ = file.Close() }()
-
-						// Save file temporarily with unique UUID-based filename to avoid conflicts
-						fileExt := filepath.Ext(fileHeader.Filename)
-						tempFileName := filepath.Join(os.TempDir(), fmt.Sprintf("graph_import_%s%s", uuid.New().String(), fileExt))
-						debugLog("Creating temp file: %s", tempFileName)
-
-						tempFile, err := os.Create(tempFileName)
-						if err != nil {
-							fmt.Printf("ERROR: Failed to create temp file: %v\n", err)
-							return
-						}
-						defer func() { _ = tempFile.Close() }()
-						defer func() {
-							debugLog("Removing temp file: %s", tempFileName)
-							_ = os.Remove(tempFileName)
-						}()
-
-						// Copy uploaded file to temp file
-						if _, err := file.Seek(0, 0); err != nil {
-							fmt.Printf("ERROR: Failed to seek file: %v\n", err)
-							return
-						}
+			if taskFiles, exists := files[fileKey]; exists && len(taskFiles) > 0 {
+				// Process the first BRF file
+				fileHeader := taskFiles[0]
 
-						debugLog("Copying file content to temp file")
-						bytesWritten, err := tempFile.ReadFrom(file)
-						if err != nil {
-							fmt.Printf("ERROR: Failed to copy file: %v\n", err)
-							return
-						}
-						_ = tempFile.Close()
+				file, err := fileHeader.Open()
+				if err != nil {
+					return fmt.Errorf("failed to open file %s: %w", fileHeader.Filename, err)
+				}
+				defer func() { _ = file.Close() }()
 
-						debugLog("Copied %d bytes to temp file", bytesWritten)
+				// Save file temporarily with unique UUID-based filename to avoid conflicts
+				fileExt := filepath.Ext(fileHeader.Filename)
+				tempFileName := run.tempFile(fmt.Sprintf("repo_import_%s%s", uuid.New().String(), fileExt))
+				defer func() { _ = os.Remove(tempFileName) }()
 
-						// Determine import method based on file extension
-						filename := strings.ToLower(fileHeader.Filename)
+				tempFile, err := os.Create(tempFileName)
+				if err != nil {
+					return fmt.Errorf("failed to create temp file: %w", err)
+				}
+				defer func() { _ = tempFile.Close() }()
 
-						debugLog("Importing text RDF file: %s", fileHeader.Filename)
-						err = db.GraphDBImportGraphRdf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Graph, tempFileName)
-						if err != nil {
-							fmt.Printf("ERROR: Failed to import RDF file %s: %v\n", fileHeader.Filename, err)
-							return
-						}
+				fileSize, fileHash, err := copyWithHash(tempFile, file)
+				if err != nil {
+					return fmt.Errorf("failed to copy file: %w", err)
+				}
+				_ = tempFile.Close()
 
-						debugLog("Successfully imported file: %s", fileHeader.Filename)
-						result[fmt.Sprintf("file_%d_processed", i)] = fileHeader.Filename
-						result[fmt.Sprintf("file_%d_type", i)] = getFileType(filename)
-					}()
+				// Import the BRF file
+				debugLog("Importing BRF file %s to repository %s", fileHeader.Filename, task.Tgt.Repo)
+				upload, err := graphDBRestoreRepositoryData(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, tempFileName, run.progress)
+				if err != nil {
+					return fmt.Errorf("failed to import BRF file: %w", err)
 				}
+				upload.setResult(result)
+				run.recordImportedFile(fileHeader.Filename, fileSize, fileHash)
+
+				result["message"] = "Repository import completed successfully"
+				result["imported_file"] = fileHeader.Filename
+				result["file_hash"] = fileHash
+				result["hash_algorithm"] = fileHashAlgorithm()
+				result["target_repository"] = task.Tgt.Repo
 			} else {
-				return nil, fmt.Errorf("graph-import action requires files to be uploaded with key 'task_%d_files'", taskIndex)
+				return fmt.Errorf("no BRF files provided for import")
 			}
 		} else {
-			return nil, fmt.Errorf("graph-import action requires files to be uploaded")
+			return fmt.Errorf("no source repository or files specified for import")
 		}
+	}
+	return nil
+}
 
-		result["message"] = "Graph imported successfully"
-		result["graph"] = task.Tgt.Graph
-
-	case "repo-rename":
-		// GraphDB doesn't have a direct rename API, so we need to:
-		// 1. Create backup of old repository (config + individual graphs)
-		// 2. Create new repository with new name
-		// 3. Restore individual graphs to new repository
-		// 4. Delete old repository
-
-		if identityFile != "" {
-			tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
-			if err != nil {
-				return nil, err
-			}
-			tgtClient, err = db.GraphDBZitiClient(identityFile, tgtURL)
-			if err != nil {
-				return nil, err
-			}
-		}
-		oldRepoName := task.Tgt.RepoOld
-		newRepoName := task.Tgt.RepoNew
-		db.HttpClient = tgtClient
+// executeRepoCreateTask executes the repo-create action.
+func executeRepoCreateTask(run *taskRun) error {
+	task, files, taskIndex, result, tgtClient, zitiClient := run.task, run.files, run.taskIndex, run.result, run.tgtClient, run.zitiClient
 
-		// Step 1: Check if source repository exists
-		srcGraphDB, err := db.GraphDBRepositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	if identityFile != "" {
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
 		if err != nil {
-			return nil, err
-		}
-		foundRepo := false
-		for _, bind := range srcGraphDB.Results.Bindings {
-			if bind.Id["value"] == oldRepoName {
-				foundRepo = true
-				break
-			}
+			return err
 		}
-		if !foundRepo {
-			return nil, fmt.Errorf("source repository '%s' not found", oldRepoName)
+		tgtClient, err = zitiClient(tgtURL)
+		if err != nil {
+			return err
 		}
+	}
+	repoName := task.Tgt.Repo
 
-		// Step 2: Check if target repository already exists
-		for _, bind := range srcGraphDB.Results.Bindings {
-			if bind.Id["value"] == newRepoName {
-				return nil, fmt.Errorf("target repository '%s' already exists", newRepoName)
+	// Check if repository already exists
+	existingRepos, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	if err != nil {
+		return err
+	}
+	for _, bind := range existingRepos.Results.Bindings {
+		if bind.Id["value"] == repoName {
+			if task.IfNotExists {
+				result["message"] = "Repository already exists"
+				result["repo"] = repoName
+				result["skipped"] = true
+				return nil
 			}
+			return fmt.Errorf("repository '%s' already exists", repoName)
 		}
+	}
+
+	fileKey := fmt.Sprintf("task_%d_config", taskIndex)
+	taskFiles := files[fileKey]
+	var configFile, configSource string
 
-		// Step 3: Get list of all graphs in the source repository
-		graphsList, err := db.GraphDBListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, oldRepoName)
+	if len(taskFiles) == 0 {
+		// Without an uploaded config, generate one from the requested ruleset
+		if task.Tgt.Ruleset == "" {
+			return fmt.Errorf("repo-create requires a configuration file with key 'task_%d_config' or a ruleset", taskIndex)
+		}
+		config, err := generateRepositoryConfig(repoName, task.Tgt.Ruleset, task.Tgt.RepoType)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list graphs in repository '%s': %w", oldRepoName, err)
+			return err
+		}
+		configFile = run.tempFile(fmt.Sprintf("repo_create_%s.ttl", uuid.New().String()))
+		defer func() { _ = os.Remove(configFile) }()
+		if err := os.WriteFile(configFile, []byte(config), 0600); err != nil {
+			return fmt.Errorf("failed to write generated config file: %w", err)
 		}
+		configSource = "generated"
+		result["ruleset"] = task.Tgt.Ruleset
+	} else {
+		// Use the first uploaded configuration file
+		fileHeader := taskFiles[0]
 
-		// Step 4: Create backup of repository configuration
-		confFile, err := db.GraphDBRepositoryConf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, oldRepoName)
+		file, err := fileHeader.Open()
 		if err != nil {
-			return nil, fmt.Errorf("failed to backup configuration for repository '%s': %w", oldRepoName, err)
+			return fmt.Errorf("failed to open config file %s: %w", fileHeader.Filename, err)
 		}
-		defer func() { _ = os.Remove(confFile) }() // Clean up config file
-
-		// Step 5: Export each graph individually
-		graphBackups := make(map[string]string) // map[graphURI]fileName
-		var graphExportErrors []string
+		defer func() { _ = file.Close() }()
 
-		for _, bind := range graphsList.Results.Bindings {
-			graphURI := bind.ContextID.Value
-			if graphURI == "" {
-				continue // Skip empty graph URIs
-			}
+		// Save uploaded config to temporary file with unique UUID-based filename to avoid conflicts
+		configFile = run.tempFile(fmt.Sprintf("repo_create_%s%s", uuid.New().String(), filepath.Ext(fileHeader.Filename)))
+		defer func() { _ = os.Remove(configFile) }()
 
-			// Create a unique filename for each graph using UUID to avoid conflicts
-			graphFileName := filepath.Join(os.TempDir(), fmt.Sprintf("repo_rename_%s.rdf", uuid.New().String()))
+		tempFile, err := os.Create(configFile)
+		if err != nil {
+			return fmt.Errorf("failed to create temp config file: %w", err)
+		}
+		defer func() { _ = tempFile.Close() }()
 
-			err := db.GraphDBExportGraphRdf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, oldRepoName, graphURI, graphFileName)
-			if err != nil {
-				graphExportErrors = append(graphExportErrors, fmt.Sprintf("failed to export graph '%s': %v", graphURI, err))
-				continue
-			}
+		// Copy uploaded file to temp file
+		if _, err := file.Seek(0, 0); err != nil {
+			return fmt.Errorf("failed to seek config file: %w", err)
+		}
+		if _, err := tempFile.ReadFrom(file); err != nil {
+			return fmt.Errorf("failed to copy config file: %w", err)
+		}
+		_ = tempFile.Close()
 
-			// Verify the export file was created and has content
-			if fileInfo, err := os.Stat(graphFileName); err != nil || fileInfo.Size() == 0 {
-				graphExportErrors = append(graphExportErrors, fmt.Sprintf("graph '%s' export file is empty or missing", graphURI))
-				_ = os.Remove(graphFileName) // Clean up empty file
-				continue
+		// Rename the repository ID declared in the config to the requested name
+		configRepoID, err := extractRepositoryID(configFile)
+		if err != nil {
+			return fmt.Errorf("invalid config file %s: %w", fileHeader.Filename, err)
+		}
+		if configRepoID != repoName {
+			debugLog("Renaming repository ID in config from '%s' to '%s'", configRepoID, repoName)
+			if err := updateRepositoryNameInConfig(configFile, configRepoID, repoName); err != nil {
+				return fmt.Errorf("failed to set repository name in config file %s: %w", fileHeader.Filename, err)
 			}
-
-			graphBackups[graphURI] = graphFileName
 		}
+		configSource = fileHeader.Filename
+	}
 
-		// Clean up graph backup files when done
-		defer func() {
-			for _, fileName := range graphBackups {
-				_ = os.Remove(fileName)
-			}
-		}()
+	// Create the repository using the configuration file
+	err = run.graphDB(tgtClient).RestoreConf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, configFile)
+	if err != nil {
+		return fmt.Errorf("failed to create repository '%s': %w", repoName, err)
+	}
 
-		// Report any export errors but continue if we have at least some graphs
-		if len(graphExportErrors) > 0 && len(graphBackups) == 0 {
-			return nil, fmt.Errorf("failed to export any graphs: %s", strings.Join(graphExportErrors, "; "))
+	// Verify the repository was created
+	verifyRepos, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	if err != nil {
+		return err
+	}
+	repoCreated := false
+	for _, bind := range verifyRepos.Results.Bindings {
+		if bind.Id["value"] == repoName {
+			repoCreated = true
+			break
 		}
+	}
+
+	if !repoCreated {
+		return fmt.Errorf("repository '%s' was not created successfully", repoName)
+	}
+
+	result["message"] = "Repository created successfully"
+	result["repo"] = repoName
+	result["config_file"] = configSource
+	return nil
+}
+
+// executeGraphImportTask executes the graph-import action.
+func executeGraphImportTask(run *taskRun) error {
+	task, files, taskIndex, log, result, tgtClient, zitiClient := run.task, run.files, run.taskIndex, run.log, run.result, run.tgtClient, run.zitiClient
 
-		// Step 6: Modify the configuration file to use the new repository name
-		err = updateRepositoryNameInConfig(confFile, oldRepoName, newRepoName)
+	if identityFile != "" {
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
 		if err != nil {
-			return nil, fmt.Errorf("failed to update repository name in config: %w", err)
+			return err
 		}
-
-		// Step 7: Create new repository with the updated configuration
-		err = db.GraphDBRestoreConf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, confFile)
+		tgtClient, err = zitiClient(tgtURL)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create new repository '%s': %w", newRepoName, err)
+			return err
 		}
+	}
+	mode, err := graphImportMode(task.Tgt)
+	if err != nil {
+		return err
+	}
 
-		// Step 8: Import each graph into the new repository
-		var graphImportErrors []string
-		successfulImports := 0
-
-		for graphURI, fileName := range graphBackups {
-			err := db.GraphDBImportGraphRdf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, newRepoName, graphURI, fileName)
+	// Reject unsupported files before touching the repository
+	rdfStar := false
+	if files != nil {
+		for _, fileHeader := range files[fmt.Sprintf("task_%d_files", taskIndex)] {
+			fileType, _, err := resolveImportFormat(fileHeader.Filename, task.Tgt.Format)
 			if err != nil {
-				graphImportErrors = append(graphImportErrors, fmt.Sprintf("failed to import graph '%s': %v", graphURI, err))
-				continue
+				return err
+			}
+			if task.Tgt.Graph == "" && !(task.Tgt.PreserveGraphs && isQuadFormat(fileType)) {
+				return fmt.Errorf("a target graph is required to import '%s' (only quad formats with preserve_graphs can omit it)", fileHeader.Filename)
 			}
-			successfulImports++
+			rdfStar = rdfStar || isRDFStarFormat(fileType)
 		}
+	}
+	if rdfStar {
+		warnUnlessRDFStar(tgtClient, task.Tgt, result)
+	}
+
+	debugLog("Starting graph-import processing")
+
+	debugLog("Fetching repositories from %s", task.Tgt.URL)
+	tgtGraphDB, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	if err != nil {
+		return err
+	}
+
+	if tgtGraphDB.Results.Bindings == nil {
+		log.Error("GraphDB returned nil bindings", "url", task.Tgt.URL)
+		return fmt.Errorf("failed to get repositories from GraphDB at %s - nil response", task.Tgt.URL)
+	}
+
+	debugLog("Found %d repositories in GraphDB", len(tgtGraphDB.Results.Bindings))
+
+	// List all available repositories for debugging
+	if len(tgtGraphDB.Results.Bindings) == 0 {
+		log.Warn("No repositories found in GraphDB", "url", task.Tgt.URL)
+		debugLog("Attempting to create repository '%s' or continue assuming it exists", task.Tgt.Repo)
+		// Continue with import attempt - the repository might exist but not be listed
+	} else {
+		log.Debug("Available repositories", "repositories", getRepositoryNames(tgtGraphDB.Results.Bindings))
+	}
 
-		// Step 9: Verify that graphs were imported successfully
-		if successfulImports == 0 && len(graphBackups) > 0 {
-			// If no graphs were imported, clean up the new repository
-			_ = db.GraphDBDeleteRepository(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, newRepoName)
-			return nil, fmt.Errorf("failed to import any graphs to new repository: %s", strings.Join(graphImportErrors, "; "))
+	foundRepo := false
+	for _, bind := range tgtGraphDB.Results.Bindings {
+		if bind.Id["value"] == task.Tgt.Repo {
+			foundRepo = true
+			break
 		}
+	}
+
+	if !foundRepo && len(tgtGraphDB.Results.Bindings) > 0 {
+		log.Error("Repository not found in repository list", "repositories", len(tgtGraphDB.Results.Bindings))
+		return newTaskError(ErrRepoNotFound, "repository '%s' not found. Available repositories: %v", task.Tgt.Repo, getRepositoryNames(tgtGraphDB.Results.Bindings))
+	}
+
+	// If we reach here, either the repo was found, or the repository list was empty
+	// In case of empty list, we'll attempt the import anyway
+	if foundRepo {
+		debugLog("Repository '%s' found in GraphDB", task.Tgt.Repo)
+	} else {
+		debugLog("Repository list was empty, attempting import to '%s' anyway", task.Tgt.Repo)
+	}
 
-		// Step 10: Delete the old repository
-		err = db.GraphDBDeleteRepository(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, oldRepoName)
+	// Files matching the last import into the graph are not imported again
+	var contentHash string
+	if task.SkipUnchanged && files != nil {
+		skipped, hash, err := skipUnchangedImport(run, tgtClient, files[fmt.Sprintf("task_%d_files", taskIndex)])
 		if err != nil {
-			// Log warning but don't fail the operation since the new repo is already created
-			fmt.Printf("Warning: failed to delete old repository '%s': %v\n", oldRepoName, err)
-			result["warning"] = fmt.Sprintf("New repository created successfully, but failed to delete old repository: %v", err)
+			return err
 		}
-
-		result["message"] = "Repository renamed successfully"
-		result["old_name"] = oldRepoName
-		result["new_name"] = newRepoName
-		result["total_graphs"] = len(graphsList.Results.Bindings)
-		result["exported_graphs"] = len(graphBackups)
-		result["imported_graphs"] = successfulImports
-
-		// Add warnings if there were any issues
-		if len(graphExportErrors) > 0 {
-			if result["warning"] != nil {
-				result["warning"] = fmt.Sprintf("%s; Export issues: %s", result["warning"], strings.Join(graphExportErrors, "; "))
-			} else {
-				result["warning"] = fmt.Sprintf("Some graphs had export issues: %s", strings.Join(graphExportErrors, "; "))
-			}
+		if skipped {
+			return nil
 		}
+		contentHash = hash
+	}
 
-		if len(graphImportErrors) > 0 {
-			if result["warning"] != nil {
-				result["warning"] = fmt.Sprintf("%s; Import issues: %s", result["warning"], strings.Join(graphImportErrors, "; "))
-			} else {
-				result["warning"] = fmt.Sprintf("Some graphs had import issues: %s", strings.Join(graphImportErrors, "; "))
+	// Try to list graphs (this might fail if repository doesn't exist)
+	debugLog("Listing graphs in repository: %s", task.Tgt.Repo)
+	graphsResponse, err := run.graphDB(tgtClient).ListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
+	if err != nil {
+		log.Warn("Failed to list graphs (repository might not exist)", "error", err)
+		// Continue with import - we'll try to import anyway
+	} else if graphsResponse.Results.Bindings == nil {
+		log.Warn("GraphDB returned nil response for listing graphs")
+	} else if mode == importModeReplace {
+		debugLog("Found %d graphs in repository", len(graphsResponse.Results.Bindings))
+		// Check if target graph exists and delete it if found
+		if graphListed(graphsResponse, task.Tgt.Graph) {
+			debugLog("Deleting existing graph: %s", task.Tgt.Graph)
+			err := run.graphDB(tgtClient).DeleteGraph(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Graph)
+			if err != nil {
+				log.Warn("Failed to delete existing graph", "graph", task.Tgt.Graph, "error", err)
+				// Don't fail the operation, continue with import
 			}
 		}
+	}
 
-	case "graph-rename":
-		// GraphDB doesn't have a direct graph rename API, so we need to:
-		// 1. Export the old graph to a temporary file
-		// 2. Import the data into the new graph
-		// 3. Delete the old graph
+	// Handle uploaded files for import
+	if files != nil {
+		fileKey := fmt.Sprintf("task_%d_files", taskIndex)
+		debugLog("Looking for files with key: %s", fileKey)
+
+		if taskFiles, exists := files[fileKey]; exists {
+			debugLog("Found %d files to import", len(taskFiles))
+			result["uploaded_files"] = len(taskFiles)
+			result["file_names"] = getFileNames(taskFiles)
+
+			// Process each uploaded file for import
+			importedFiles := 0
+			asyncImport := task.AsyncImport
+			for i, fileHeader := range taskFiles {
+				debugLog("Processing file %d: %s (size: %d bytes)", i, fileHeader.Filename, fileHeader.Size)
+
+				func() {
+					defer func() {
+						if r := recover(); r != nil {
+							log.Error("Panic in file processing", "file", fileHeader.Filename, "panic", fmt.Sprint(r))
+						}
+					}()
 
-		if identityFile != "" {
-			tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
-			if err != nil {
-				return nil, err
+					file, err := fileHeader.Open()
+					if err != nil {
+						log.Error("Failed to open file", "file", fileHeader.Filename, "error", err)
+						return
+					}
+					defer func() { _ = file.Close() }()
+
+					// Save file temporarily with unique UUID-based filename to avoid conflicts.
+					// The extension reflects the format override so the import uses the right type.
+					fileType, fileExt, err := resolveImportFormat(fileHeader.Filename, task.Tgt.Format)
+					if err != nil {
+						log.Error("Unsupported file", "file", fileHeader.Filename, "error", err)
+						return
+					}
+					tempFileName := run.tempFile(fmt.Sprintf("graph_import_%s%s", uuid.New().String(), fileExt))
+					debugLog("Creating temp file: %s", tempFileName)
+
+					tempFile, err := os.Create(tempFileName)
+					if err != nil {
+						log.Error("Failed to create temp file", "file", fileHeader.Filename, "error", err)
+						return
+					}
+					defer func() { _ = tempFile.Close() }()
+					defer func() {
+						debugLog("Removing temp file: %s", tempFileName)
+						_ = os.Remove(tempFileName)
+					}()
+
+					// Copy uploaded file to temp file
+					if _, err := file.Seek(0, 0); err != nil {
+						log.Error("Failed to seek file", "file", fileHeader.Filename, "error", err)
+						return
+					}
+
+					debugLog("Copying file content to temp file")
+					bytesWritten, fileHash, err := copyWithHash(tempFile, file)
+					if err != nil {
+						log.Error("Failed to copy file", "file", fileHeader.Filename, "error", err)
+						return
+					}
+					_ = tempFile.Close()
+
+					debugLog("Copied %d bytes to temp file", bytesWritten)
+
+					if asyncImport {
+						var imported bool
+						imported, err = importGraphFileAsync(run, tgtClient, tempFileName, fileType, i)
+						asyncImport = imported || err != nil
+					}
+					if asyncImport {
+						// The GraphDB import API imported the file or returned its error
+					} else if task.Tgt.PreserveGraphs && isQuadFormat(fileType) {
+						// Quad formats keep the graph names encoded in the file
+						debugLog("Importing %s file with its own graph names: %s", fileType, fileHeader.Filename)
+						err = graphDBImportStatements(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, tempFileName, rdfMediaType(fileType, task.Tgt.ContentType))
+						result[fmt.Sprintf("file_%d_graphs_preserved", i)] = true
+					} else if mode == importModeAppend {
+						// POST adds the triples and keeps the existing graph content
+						debugLog("Appending RDF file to graph %s: %s", task.Tgt.Graph, fileHeader.Filename)
+						err = graphDBAppendGraphRdf(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Graph, tempFileName, rdfMediaType(fileType, task.Tgt.ContentType))
+					} else if task.Tgt.ContentType != "" || isRDFStarFormat(fileType) {
+						// The existing graph was deleted above, so adding the triples replaces it
+						contentType := rdfMediaType(fileType, task.Tgt.ContentType)
+						debugLog("Importing RDF file as %s: %s", contentType, fileHeader.Filename)
+						err = graphDBAppendGraphRdf(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Graph, tempFileName, contentType)
+					} else {
+						debugLog("Importing text RDF file: %s", fileHeader.Filename)
+						err = run.graphDB(tgtClient).ImportGraphRdf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Graph, tempFileName)
+					}
+					if err != nil {
+						log.Error("Failed to import RDF file", "file", fileHeader.Filename, "error", err)
+						return
+					}
+
+					debugLog("Successfully imported file: %s", fileHeader.Filename)
+					result[fmt.Sprintf("file_%d_processed", i)] = fileHeader.Filename
+					result[fmt.Sprintf("file_%d_type", i)] = fileType
+					result[fmt.Sprintf("file_%d_hash", i)] = fileHash
+					result["hash_algorithm"] = fileHashAlgorithm()
+					run.recordImportedFile(fileHeader.Filename, bytesWritten, fileHash)
+					importedFiles++
+				}()
 			}
-			tgtClient, err = db.GraphDBZitiClient(identityFile, tgtURL)
-			if err != nil {
-				return nil, err
+			if task.AsyncImport {
+				result["async_import"] = asyncImport
+			}
+			if contentHash != "" && importedFiles == len(taskFiles) {
+				recordGraphImport(run, taskFiles, contentHash)
 			}
+		} else {
+			return fmt.Errorf("graph-import action requires files to be uploaded with key 'task_%d_files'", taskIndex)
 		}
-		oldGraphName := task.Tgt.GraphOld
-		newGraphName := task.Tgt.GraphNew
-		repoName := task.Tgt.Repo
-		db.HttpClient = tgtClient
+	} else {
+		return fmt.Errorf("graph-import action requires files to be uploaded")
+	}
+
+	result["message"] = "Graph imported successfully"
+	result["graph"] = task.Tgt.Graph
+	result["mode"] = mode
+	return nil
+}
 
-		// Step 1: Check if repository exists
-		tgtGraphDB, err := db.GraphDBRepositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+// executeRepoRenameTask executes the repo-rename action.
+//
+// GraphDB doesn't have a direct rename API, so we need to:
+// 1. Create backup of old repository (config + individual graphs)
+// 2. Create new repository with new name
+// 3. Restore individual graphs to new repository
+// 4. Delete old repository
+//
+// With keep_backup the graph exports are retained in the backup; a rename that
+// did not transfer every graph can then be resumed by passing its backup_id.
+func executeRepoRenameTask(run *taskRun) error {
+	task, progress, log, result, tgtClient, zitiClient := run.task, run.progress, run.log, run.result, run.tgtClient, run.zitiClient
+
+	if identityFile != "" {
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		foundRepo := false
-		for _, bind := range tgtGraphDB.Results.Bindings {
-			if bind.Id["value"] == repoName {
-				foundRepo = true
-				break
-			}
+		tgtClient, err = zitiClient(tgtURL)
+		if err != nil {
+			return err
+		}
+	}
+	if task.BackupID != "" {
+		return resumeRepoRename(run, tgtClient)
+	}
+	oldRepoName := task.Tgt.RepoOld
+	newRepoName := task.Tgt.RepoNew
+
+	// Step 1: Check if source repository exists
+	done := run.timeStep(stepListRepositories)
+	srcGraphDB, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	done()
+	if err != nil {
+		return err
+	}
+	foundRepo := false
+	for _, bind := range srcGraphDB.Results.Bindings {
+		if bind.Id["value"] == oldRepoName {
+			foundRepo = true
+			break
 		}
-		if !foundRepo {
-			return nil, fmt.Errorf("repository '%s' not found", repoName)
+	}
+	if !foundRepo {
+		return newTaskError(ErrRepoNotFound, "source repository '%s' not found", oldRepoName)
+	}
+
+	// Step 2: Check if target repository already exists
+	for _, bind := range srcGraphDB.Results.Bindings {
+		if bind.Id["value"] == newRepoName {
+			return fmt.Errorf("target repository '%s' already exists", newRepoName)
 		}
+	}
+
+	// Step 3: Get list of all graphs in the source repository
+	done = run.timeStep(stepListGraphs)
+	graphsList, err := run.graphDB(tgtClient).ListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, oldRepoName)
+	done()
+	if err != nil {
+		return fmt.Errorf("failed to list graphs in repository '%s': %w", oldRepoName, err)
+	}
+	// The default graph is transferred like a named graph when it has statements
+	graphs := repositoryGraphs(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, oldRepoName, graphsList)
+
+	if task.DryRun {
+		var operations []map[string]interface{}
+		var imports []map[string]interface{}
+		for _, graphURI := range graphs {
+			count, _ := countGraphTriples(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, oldRepoName, graphURI)
+			operations = append(operations, plannedOperation("export-graph", oldRepoName, graphURI, count))
+			imports = append(imports, plannedOperation("import-graph", newRepoName, graphURI, count))
+		}
+		if task.KeepBackup {
+			operations = append(operations, plannedOperation("backup-repository", oldRepoName, "", -1))
+		}
+		operations = append(operations, plannedOperation("create-repository", newRepoName, "", -1))
+		operations = append(operations, imports...)
+		operations = append(operations, plannedOperation("delete-repository", oldRepoName, "", -1))
+		setDryRunResult(result, "Dry run: repository would be renamed", operations)
+		result["old_name"] = oldRepoName
+		result["new_name"] = newRepoName
+		result["total_graphs"] = len(graphs)
+		return nil
+	}
+
+	// The graph exports are kept in the temp directory, or with keep_backup in
+	// the backup, until all are imported
+	if err := checkExportDiskSpace(run, tgtClient, task.Tgt, oldRepoName); err != nil {
+		return err
+	}
 
-		// Step 2: Check if source graph exists
-		graphsResponse, err := db.GraphDBListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repoName)
+	// Step 4: Create backup of repository configuration
+	confFile := run.tempFile(fmt.Sprintf("repo_rename_%s.ttl", uuid.New().String()))
+	done = run.timeStep(stepDownloadConfig)
+	err = graphDBDownloadRepositoryConfig(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, oldRepoName, confFile)
+	done()
+	if err != nil {
+		return fmt.Errorf("failed to backup configuration for repository '%s': %w", oldRepoName, err)
+	}
+	defer func() { _ = os.Remove(confFile) }() // Clean up config file
+
+	// Optionally retain the config and data of the old repository so the rename
+	// can be undone, and the graph exports so an interrupted rename can be resumed
+	var backup *repoBackupManifest
+	if task.KeepBackup {
+		progress("Backing up repository", 1, 1)
+		backup, err = saveRepoBackup(run, oldRepoName, confFile)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list graphs in repository '%s': %w", repoName, err)
+			return err
 		}
+		backup.RenamedTo = newRepoName
+		result["backup_id"] = backup.ID
+	}
 
-		foundOldGraph := false
-		foundNewGraph := false
-		for _, bind := range graphsResponse.Results.Bindings {
-			if bind.ContextID.Value == oldGraphName {
-				foundOldGraph = true
+	// Step 5: Export each graph individually
+	graphBackups := make(map[string]string) // map[graphURI]fileName
+	var compression exportCompression
+	var graphExportErrors []string
+	var failedGraphs []string // Graphs that were not transferred to the new repository
+
+	for i, graphURI := range graphs {
+		if err := run.ctx.Err(); err != nil {
+			return err
+		}
+		progress("Exporting graph", i+1, len(graphs))
+
+		done := run.timeStep(stepExportGraphs)
+		importFileName, err := exportGraphForRename(run, tgtClient, &compression, oldRepoName, graphURI)
+		done()
+		if err == nil && backup != nil {
+			var kept string
+			if kept, err = backup.keepGraphExport(graphURI, importFileName); err != nil {
+				_ = os.Remove(importFileName)
 			}
-			if bind.ContextID.Value == newGraphName {
-				foundNewGraph = true
+			importFileName = kept
+		}
+		if err != nil {
+			graphExportErrors = append(graphExportErrors, err.Error())
+			failedGraphs = append(failedGraphs, graphURI)
+			if backup != nil {
+				backup.setGraphStatus(graphURI, renameGraphFailed, err)
 			}
+			continue
 		}
 
-		if !foundOldGraph {
-			return nil, fmt.Errorf("source graph '%s' not found in repository '%s'", oldGraphName, repoName)
+		graphBackups[graphURI] = importFileName
+	}
+
+	// Clean up graph backup files when done; retained exports stay in the backup
+	defer func() {
+		if backup != nil {
+			return
+		}
+		for _, fileName := range graphBackups {
+			_ = os.Remove(fileName)
+		}
+	}()
+	if backup != nil {
+		if err := backup.save(); err != nil {
+			return err
 		}
+	}
+
+	// Report any export errors but continue if we have at least some graphs
+	if len(graphExportErrors) > 0 && len(graphBackups) == 0 {
+		return fmt.Errorf("failed to export any graphs: %s", strings.Join(graphExportErrors, "; "))
+	}
+
+	// Step 6: Modify the configuration file to use the new repository name
+	err = updateRepositoryNameInConfig(confFile, oldRepoName, newRepoName)
+	if err != nil {
+		return fmt.Errorf("failed to update repository name in config: %w", err)
+	}
 
-		if foundNewGraph {
-			return nil, fmt.Errorf("target graph '%s' already exists in repository '%s'", newGraphName, repoName)
+	// Step 7: Create new repository with the updated configuration
+	if err := run.ctx.Err(); err != nil {
+		return err
+	}
+	progress("Creating repository", 1, 1)
+	done = run.timeStep(stepRestoreConfig)
+	err = run.graphDB(tgtClient).RestoreConf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, confFile)
+	done()
+	if err != nil {
+		return fmt.Errorf("failed to create new repository '%s': %w", newRepoName, err)
+	}
+
+	// Step 8: Import each graph into the new repository
+	var graphImportErrors []string
+	successfulImports := 0
+	imported := 0
+
+	for graphURI, fileName := range graphBackups {
+		imported++
+		if err := run.ctx.Err(); err != nil {
+			return err
 		}
+		progress("Importing graph", imported, len(graphBackups))
+		done := run.timeStep(stepImportGraphs)
+		err := importExportedGraph(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, newRepoName, graphURI, fileName, rdfContentTypes["rdf-xml"])
+		done()
+		if err != nil {
+			graphImportErrors = append(graphImportErrors, fmt.Sprintf("failed to import graph '%s': %v", graphURI, err))
+			failedGraphs = append(failedGraphs, graphURI)
+			if backup != nil {
+				backup.setGraphStatus(graphURI, renameGraphFailed, err)
+			}
+			continue
+		}
+		if backup != nil {
+			backup.setGraphStatus(graphURI, renameGraphImported, nil)
+		}
+		successfulImports++
+	}
+	if backup != nil {
+		if err := backup.save(); err != nil {
+			log.Warn("Failed to update backup manifest", "backup_id", backup.ID, "error", err)
+		}
+	}
 
-		// Step 3: Export the old graph to a temporary file with unique UUID to avoid conflicts
-		tempFileName := filepath.Join(os.TempDir(), fmt.Sprintf("graph_rename_%s.rdf", uuid.New().String()))
-		defer func() { _ = os.Remove(tempFileName) }() // Clean up temporary file
+	// Step 9: Verify that graphs were imported successfully
+	if successfulImports == 0 && len(graphBackups) > 0 {
+		// If no graphs were imported, clean up the new repository
+		_ = run.graphDB(tgtClient).DeleteRepository(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, newRepoName)
+		return fmt.Errorf("failed to import any graphs to new repository: %s", strings.Join(graphImportErrors, "; "))
+	}
 
-		err = db.GraphDBExportGraphRdf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repoName, oldGraphName, tempFileName)
+	// Step 10: Delete the old repository, unless some graphs were not transferred.
+	// In that case the old repository is kept to avoid data loss unless Force is set.
+	sort.Strings(failedGraphs)
+	partial := len(failedGraphs) > 0
+	oldRepoDeleted := false
+	if partial && !task.Force {
+		log.Warn("Keeping old repository because some graphs were not transferred", "old_repo", oldRepoName, "failed_graphs", len(failedGraphs))
+		result["message"] = "Repository partially renamed, old repository kept because some graphs were not transferred"
+	} else {
+		if err := run.ctx.Err(); err != nil {
+			return err
+		}
+		progress("Deleting old repository", 1, 1)
+		done = run.timeStep(stepDeleteSource)
+		err = run.graphDB(tgtClient).DeleteRepository(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, oldRepoName)
+		done()
 		if err != nil {
-			return nil, fmt.Errorf("failed to export graph '%s': %w", oldGraphName, err)
+			// Log warning but don't fail the operation since the new repo is already created
+			log.Warn("Failed to delete old repository", "old_repo", oldRepoName, "error", err)
+			result["warning"] = fmt.Sprintf("New repository created successfully, but failed to delete old repository: %v", err)
+		} else {
+			oldRepoDeleted = true
+		}
+		result["message"] = "Repository renamed successfully"
+	}
+
+	if partial {
+		result["status"] = "partial"
+		result["failed_graphs"] = failedGraphs
+	}
+	result["old_repository_deleted"] = oldRepoDeleted
+	result["old_name"] = oldRepoName
+	result["new_name"] = newRepoName
+	result["total_graphs"] = len(graphs)
+	result["exported_graphs"] = len(graphBackups)
+	result["imported_graphs"] = successfulImports
+	compression.report(result)
+
+	// Add warnings if there were any issues
+	if len(graphExportErrors) > 0 {
+		if result["warning"] != nil {
+			result["warning"] = fmt.Sprintf("%s; Export issues: %s", result["warning"], strings.Join(graphExportErrors, "; "))
+		} else {
+			result["warning"] = fmt.Sprintf("Some graphs had export issues: %s", strings.Join(graphExportErrors, "; "))
+		}
+	}
+
+	if len(graphImportErrors) > 0 {
+		if result["warning"] != nil {
+			result["warning"] = fmt.Sprintf("%s; Import issues: %s", result["warning"], strings.Join(graphImportErrors, "; "))
+		} else {
+			result["warning"] = fmt.Sprintf("Some graphs had import issues: %s", strings.Join(graphImportErrors, "; "))
 		}
+	}
+	return nil
+}
 
-		// Step 4: Verify the export file was created and has content
-		fileInfo, err := os.Stat(tempFileName)
+// executeGraphRenameTask executes the graph-rename action.
+//
+// GraphDB doesn't have a direct graph rename API, so we need to:
+// 1. Export the old graph to a temporary file
+// 2. Import the data into the new graph
+// 3. Delete the old graph
+//
+// A graph with blank nodes is copied with SPARQL COPY instead of steps 1 and 2,
+// so its blank nodes stay the same.
+func executeGraphRenameTask(run *taskRun) error {
+	task, log, result, tgtClient, zitiClient := run.task, run.log, run.result, run.tgtClient, run.zitiClient
+
+	if identityFile != "" {
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
 		if err != nil {
-			return nil, fmt.Errorf("failed to verify exported file: %w", err)
+			return err
+		}
+		tgtClient, err = zitiClient(tgtURL)
+		if err != nil {
+			return err
+		}
+	}
+	oldGraphName := task.Tgt.GraphOld
+	newGraphName := task.Tgt.GraphNew
+	repoName := task.Tgt.Repo
+
+	// Step 1: Check if repository exists
+	tgtGraphDB, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	if err != nil {
+		return err
+	}
+	foundRepo := false
+	for _, bind := range tgtGraphDB.Results.Bindings {
+		if bind.Id["value"] == repoName {
+			foundRepo = true
+			break
+		}
+	}
+	if !foundRepo {
+		return newTaskError(ErrRepoNotFound, "repository '%s' not found", repoName)
+	}
+
+	// Step 2: Check if source graph exists
+	graphsResponse, err := run.graphDB(tgtClient).ListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repoName)
+	if err != nil {
+		return fmt.Errorf("failed to list graphs in repository '%s': %w", repoName, err)
+	}
+
+	foundOldGraph := false
+	foundNewGraph := false
+	for _, bind := range graphsResponse.Results.Bindings {
+		if bind.ContextID.Value == oldGraphName {
+			foundOldGraph = true
 		}
-		if fileInfo.Size() == 0 {
-			return nil, fmt.Errorf("exported graph file is empty - graph '%s' may be empty", oldGraphName)
+		if bind.ContextID.Value == newGraphName {
+			foundNewGraph = true
 		}
+	}
+
+	if !foundOldGraph {
+		return newTaskError(ErrGraphNotFound, "source graph '%s' not found in repository '%s'", oldGraphName, repoName)
+	}
 
-		// Step 5: Import the data into the new graph
-		err = db.GraphDBImportGraphRdf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repoName, newGraphName, tempFileName)
+	if foundNewGraph {
+		return fmt.Errorf("target graph '%s' already exists in repository '%s'", newGraphName, repoName)
+	}
+
+	if task.DryRun {
+		count, _ := countGraphTriples(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repoName, oldGraphName)
+		setDryRunResult(result, "Dry run: graph would be renamed", []map[string]interface{}{
+			plannedOperation("export-graph", repoName, oldGraphName, count),
+			plannedOperation("import-graph", repoName, newGraphName, count),
+			plannedOperation("delete-graph", repoName, oldGraphName, count),
+		})
+		result["repository"] = repoName
+		result["old_name"] = oldGraphName
+		result["new_name"] = newGraphName
+		return nil
+	}
+
+	// Step 3: Copy the old graph into the new one. A graph with blank nodes is
+	// copied inside GraphDB, which keeps its blank nodes as they are.
+	var fileSize int64
+	blankNodes, err := countGraphBlankNodeTriples(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repoName, oldGraphName)
+	if err != nil {
+		debugLog("Failed to count blank node triples in graph %s: %v", oldGraphName, err)
+	}
+	if blankNodes > 0 {
+		result["blank_node_triples"] = blankNodes
+		if err := copyGraphInRepository(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repoName, oldGraphName, newGraphName); err != nil {
+			return fmt.Errorf("failed to copy graph '%s' to '%s': %w", oldGraphName, newGraphName, err)
+		}
+	} else {
+		fileSize, err = exportImportGraphRename(run, tgtClient, repoName, oldGraphName, newGraphName)
 		if err != nil {
-			return nil, fmt.Errorf("failed to import graph data to '%s': %w", newGraphName, err)
+			return err
+		}
+	}
+
+	// Step 4: Verify the new graph was created successfully
+	verifyGraphs, err := run.graphDB(tgtClient).ListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repoName)
+	if err != nil {
+		return fmt.Errorf("failed to verify new graph creation: %w", err)
+	}
+
+	newGraphExists := false
+	for _, bind := range verifyGraphs.Results.Bindings {
+		if bind.ContextID.Value == newGraphName {
+			newGraphExists = true
+			break
 		}
+	}
+
+	if !newGraphExists {
+		return fmt.Errorf("new graph '%s' was not created successfully", newGraphName)
+	}
+
+	// Step 5: Get triple counts for verification
+	oldGraphTriples, newGraphTriples := getGraphTripleCounts(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repoName, oldGraphName, newGraphName)
+
+	// Step 6: Delete the old graph
+	err = run.graphDB(tgtClient).DeleteGraph(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repoName, oldGraphName)
+	if err != nil {
+		// Log warning but don't fail since new graph is already created
+		log.Warn("Failed to delete old graph", "old_graph", oldGraphName, "error", err)
+		addResultWarning(result, fmt.Sprintf("New graph created successfully, but failed to delete old graph: %v", err))
+	}
+
+	result["message"] = "Graph renamed successfully"
+	result["repository"] = repoName
+	result["old_name"] = oldGraphName
+	result["new_name"] = newGraphName
+	if blankNodes <= 0 {
+		result["file_size_bytes"] = fileSize
+	}
+
+	// Add triple count verification if available
+	if oldGraphTriples >= 0 && newGraphTriples >= 0 {
+		result["old_graph_triples"] = oldGraphTriples
+		result["new_graph_triples"] = newGraphTriples
+		if oldGraphTriples != newGraphTriples {
+			addResultWarning(result, fmt.Sprintf("Triple count mismatch: old graph had %d triples, new graph has %d triples", oldGraphTriples, newGraphTriples))
+		}
+	}
+	return nil
+}
+
+// exportImportGraphRename copies a graph of graph-rename through a temporary
+// file: the graph is exported as a single RDF/XML document and imported in one
+// request. It returns the size of the export.
+func exportImportGraphRename(run *taskRun, tgtClient *http.Client, repoName, oldGraphName, newGraphName string) (int64, error) {
+	task := run.task
+
+	// Export the old graph to a temporary file with unique UUID to avoid conflicts
+	tempFileName := run.tempFile(fmt.Sprintf("graph_rename_%s.rdf", uuid.New().String()))
+	defer func() { _ = os.Remove(tempFileName) }() // Clean up temporary file
+
+	err := run.graphDB(tgtClient).ExportGraphRdf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repoName, oldGraphName, tempFileName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to export graph '%s': %w", oldGraphName, err)
+	}
+
+	// Verify the export file was created and has content
+	fileInfo, err := os.Stat(tempFileName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to verify exported file: %w", err)
+	}
+	if fileInfo.Size() == 0 {
+		return 0, fmt.Errorf("exported graph file is empty - graph '%s' may be empty", oldGraphName)
+	}
+
+	var compression exportCompression
+	importFileName, err := compression.compressExport(task, tempFileName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compress exported graph: %w", err)
+	}
+	defer func() { _ = os.Remove(importFileName) }()
+	compression.report(run.result)
+
+	// Import the data into the new graph
+	err = importExportedGraph(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repoName, newGraphName, importFileName, rdfContentTypes["rdf-xml"])
+	if err != nil {
+		return 0, fmt.Errorf("failed to import graph data to '%s': %w", newGraphName, err)
+	}
+	return fileInfo.Size(), nil
+}
 
-		// Step 6: Verify the new graph was created successfully
-		verifyGraphs, err := db.GraphDBListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repoName)
+// executeGraphMergeTask executes the graph-merge action.
+//
+// GraphDB doesn't have a graph merge API, so for each source graph we:
+// 1. Export the source graph to a temporary file
+// 2. Append the data to the target graph (existing triples are kept)
+// 3. Optionally delete the source graphs once everything was merged
+func executeGraphMergeTask(run *taskRun) error {
+	task, progress, result, srcClient, tgtClient, zitiClient := run.task, run.progress, run.result, run.srcClient, run.tgtClient, run.zitiClient
+
+	if task.Src == nil || task.Tgt == nil {
+		return fmt.Errorf("graph-merge requires both src and tgt")
+	}
+	if len(task.Src.Graphs) == 0 {
+		return fmt.Errorf("graph-merge requires at least one source graph in src.graphs")
+	}
+	if task.Tgt.Graph == "" {
+		return fmt.Errorf("graph-merge requires a target graph in tgt.graph")
+	}
+
+	if identityFile != "" {
+		srcURL, err := URL2ServiceRobust(task.Src.URL)
+		if err != nil {
+			return err
+		}
+		srcClient, err = zitiClient(srcURL)
+		if err != nil {
+			return err
+		}
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
 		if err != nil {
-			return nil, fmt.Errorf("failed to verify new graph creation: %w", err)
+			return err
 		}
+		tgtClient, err = zitiClient(tgtURL)
+		if err != nil {
+			return err
+		}
+	}
 
-		newGraphExists := false
-		for _, bind := range verifyGraphs.Results.Bindings {
-			if bind.ContextID.Value == newGraphName {
-				newGraphExists = true
-				break
+	// Step 1: Check that the source repository and all source graphs exist
+	srcGraphDB, err := run.graphDB(srcClient).Repositories(task.Src.URL, task.Src.Username, task.Src.Password)
+	if err != nil {
+		return err
+	}
+	foundRepo := false
+	for _, bind := range srcGraphDB.Results.Bindings {
+		if bind.Id["value"] == task.Src.Repo {
+			foundRepo = true
+			break
+		}
+	}
+	if !foundRepo {
+		return newTaskError(ErrRepoNotFound, "could not find required src repository %s", task.Src.Repo)
+	}
+
+	srcGraphs, err := run.graphDB(srcClient).ListGraphs(task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo)
+	if err != nil {
+		return fmt.Errorf("failed to list graphs in repository '%s': %w", task.Src.Repo, err)
+	}
+	existingGraphs := make(map[string]bool)
+	for _, bind := range srcGraphs.Results.Bindings {
+		existingGraphs[bind.ContextID.Value] = true
+	}
+	// Missing source graphs fail the merge unless continue_on_error is set
+	sourceGraphs := make([]string, 0, len(task.Src.Graphs))
+	skippedGraphs := []string{}
+	for _, graphURI := range task.Src.Graphs {
+		if !existingGraphs[graphURI] {
+			if !task.ContinueOnError {
+				return newTaskError(ErrGraphNotFound, "could not find required src graph %s in repository %s", graphURI, task.Src.Repo)
 			}
+			addResultWarning(result, fmt.Sprintf("source graph %s not found in repository %s, skipped", graphURI, task.Src.Repo))
+			skippedGraphs = append(skippedGraphs, graphURI)
+			continue
 		}
+		sourceGraphs = append(sourceGraphs, graphURI)
+	}
 
-		if !newGraphExists {
-			return nil, fmt.Errorf("new graph '%s' was not created successfully", newGraphName)
+	// Step 2: Check that the target repository exists
+	tgtGraphDB, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	if err != nil {
+		return err
+	}
+	foundRepo = false
+	for _, bind := range tgtGraphDB.Results.Bindings {
+		if bind.Id["value"] == task.Tgt.Repo {
+			foundRepo = true
+			break
 		}
+	}
+	if !foundRepo {
+		return newTaskError(ErrRepoNotFound, "could not find required tgt repository %s", task.Tgt.Repo)
+	}
+
+	if task.DryRun {
+		var operations []map[string]interface{}
+		var deletes []map[string]interface{}
+		for _, graphURI := range sourceGraphs {
+			count, _ := countGraphTriples(srcClient, task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo, graphURI)
+			operations = append(operations, plannedOperation("export-graph", task.Src.Repo, graphURI, count))
+			operations = append(operations, plannedOperation("append-graph", task.Tgt.Repo, task.Tgt.Graph, count))
+			if task.DeleteSources {
+				deletes = append(deletes, plannedOperation("delete-graph", task.Src.Repo, graphURI, count))
+			}
+		}
+		operations = append(operations, deletes...)
+		setDryRunResult(result, "Dry run: graphs would be merged", operations)
+		result["src_graphs"] = task.Src.Graphs
+		result["tgt_graph"] = task.Tgt.Graph
+		result["skipped_graphs"] = skippedGraphs
+		return nil
+	}
 
-		// Step 7: Get triple counts for verification
-		oldGraphTriples, newGraphTriples := getGraphTripleCounts(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repoName, oldGraphName, newGraphName)
+	// Step 3: Export each source graph and append it to the target graph
+	sourceTriples := make(map[string]int)
+	mergedGraphs := make([]string, 0, len(sourceGraphs))
+	sameRepo := normalizeURL(task.Src.URL) == normalizeURL(task.Tgt.URL) && task.Src.Repo == task.Tgt.Repo
+	dataSize := int64(0)
+	totalTriples := 0
 
-		// Step 8: Delete the old graph
-		err = db.GraphDBDeleteGraph(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repoName, oldGraphName)
+	for i, graphURI := range sourceGraphs {
+		if sameRepo && graphURI == task.Tgt.Graph {
+			continue // The target graph already contains its own triples
+		}
+		if err := run.ctx.Err(); err != nil {
+			return err
+		}
+		progress("Merging graph", i+1, len(sourceGraphs))
+
+		count, err := countGraphTriples(srcClient, task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo, graphURI)
 		if err != nil {
-			// Log warning but don't fail since new graph is already created
-			fmt.Printf("Warning: failed to delete old graph '%s': %v\n", oldGraphName, err)
-			result["warning"] = fmt.Sprintf("New graph created successfully, but failed to delete old graph: %v", err)
+			debugLog("Failed to count triples in graph %s: %v", graphURI, err)
 		}
+		sourceTriples[graphURI] = count
 
-		result["message"] = "Graph renamed successfully"
-		result["repository"] = repoName
-		result["old_name"] = oldGraphName
-		result["new_name"] = newGraphName
-		result["file_size_bytes"] = fileInfo.Size()
-
-		// Add triple count verification if available
-		if oldGraphTriples >= 0 && newGraphTriples >= 0 {
-			result["old_graph_triples"] = oldGraphTriples
-			result["new_graph_triples"] = newGraphTriples
-			if oldGraphTriples != newGraphTriples {
-				result["warning"] = fmt.Sprintf("Triple count mismatch: old graph had %d triples, new graph has %d triples", oldGraphTriples, newGraphTriples)
+		// An empty graph has nothing to merge
+		if count == 0 {
+			addResultWarning(result, fmt.Sprintf("source graph %s is empty, skipped", graphURI))
+			skippedGraphs = append(skippedGraphs, graphURI)
+			continue
+		}
+		if count > 0 {
+			totalTriples += count
+		}
+
+		// Create a unique filename for each graph using UUID to avoid conflicts
+		graphFileName := run.tempFile(fmt.Sprintf("graph_merge_%s.rdf", uuid.New().String()))
+
+		err = run.graphDB(srcClient).ExportGraphRdf(task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo, graphURI, graphFileName)
+		if err != nil {
+			_ = os.Remove(graphFileName)
+			return fmt.Errorf("failed to export graph '%s': %w", graphURI, err)
+		}
+		if fileInfo, err := os.Stat(graphFileName); err == nil {
+			dataSize += fileInfo.Size()
+		}
+
+		// GraphDBExportGraphRdf always writes RDF/XML
+		err = graphDBAppendGraphRdf(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Graph, graphFileName, "application/rdf+xml")
+		_ = os.Remove(graphFileName)
+		if err != nil {
+			return fmt.Errorf("failed to merge graph '%s' into '%s': %w", graphURI, task.Tgt.Graph, err)
+		}
+		mergedGraphs = append(mergedGraphs, graphURI)
+	}
+
+	// Step 4: Optionally delete the merged source graphs
+	if task.DeleteSources {
+		var deleteErrors []string
+		deletedGraphs := make([]string, 0, len(mergedGraphs))
+		for _, graphURI := range mergedGraphs {
+			err := run.graphDB(srcClient).DeleteGraph(task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo, graphURI)
+			if err != nil {
+				deleteErrors = append(deleteErrors, fmt.Sprintf("failed to delete source graph '%s': %v", graphURI, err))
+				continue
 			}
+			deletedGraphs = append(deletedGraphs, graphURI)
+		}
+		result["deleted_graphs"] = deletedGraphs
+		if len(deleteErrors) > 0 {
+			// Don't fail since the target graph already contains the merged data
+			result["warning"] = fmt.Sprintf("Graphs merged successfully, but some source graphs could not be deleted: %s", strings.Join(deleteErrors, "; "))
 		}
 	}
 
-	return result, nil
+	result["message"] = "Graphs merged successfully"
+	result["src_graphs"] = task.Src.Graphs
+	result["tgt_graph"] = task.Tgt.Graph
+	result["merged_graphs"] = mergedGraphs
+	result["skipped_graphs"] = skippedGraphs
+	result["source_triples"] = sourceTriples
+	result["total_source_triples"] = totalTriples
+	result["data_size"] = dataSize
+
+	// Add the triple count of the merged graph if available
+	mergedTriples, err := countGraphTriples(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Graph)
+	if err != nil {
+		debugLog("Failed to count triples in graph %s: %v", task.Tgt.Graph, err)
+	} else {
+		result["merged_triples"] = mergedTriples
+	}
+	return nil
+}
+
+// executeGraphQueryImportTask executes the graph-query-import action.
+//
+// Run a CONSTRUCT/DESCRIBE query against the source repository and
+// replace the target graph with the resulting RDF
+func executeGraphQueryImportTask(run *taskRun) error {
+	task, result, srcClient, tgtClient, zitiClient := run.task, run.result, run.srcClient, run.tgtClient, run.zitiClient
+
+	if task.Src == nil || task.Tgt == nil {
+		return fmt.Errorf("graph-query-import requires both src and tgt")
+	}
+	if task.Tgt.Graph == "" {
+		return fmt.Errorf("graph-query-import requires a target graph in tgt.graph")
+	}
+	switch form := sparqlQueryForm(task.Src.Query); form {
+	case "CONSTRUCT", "DESCRIBE":
+	case "":
+		return fmt.Errorf("graph-query-import requires a CONSTRUCT or DESCRIBE query in src.query")
+	default:
+		return fmt.Errorf("graph-query-import only supports CONSTRUCT or DESCRIBE queries, got %s", form)
+	}
+
+	if identityFile != "" {
+		srcURL, err := URL2ServiceRobust(task.Src.URL)
+		if err != nil {
+			return err
+		}
+		srcClient, err = zitiClient(srcURL)
+		if err != nil {
+			return err
+		}
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
+		if err != nil {
+			return err
+		}
+		tgtClient, err = zitiClient(tgtURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Step 1: Check that the target repository exists
+	tgtGraphDB, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	if err != nil {
+		return err
+	}
+	foundRepo := false
+	for _, bind := range tgtGraphDB.Results.Bindings {
+		if bind.Id["value"] == task.Tgt.Repo {
+			foundRepo = true
+			break
+		}
+	}
+	if !foundRepo {
+		return newTaskError(ErrRepoNotFound, "could not find required tgt repository %s", task.Tgt.Repo)
+	}
+
+	// Step 2: Run the query on the source repository
+	queryFileName := run.tempFile(fmt.Sprintf("graph_query_import_%s.rdf", uuid.New().String()))
+	defer func() { _ = os.Remove(queryFileName) }() // Clean up temporary file
+
+	dataSize, err := sparqlConstructToFile(srcClient, task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo, task.Src.Query, queryFileName)
+	if err != nil {
+		return fmt.Errorf("failed to run query on repository '%s': %w", task.Src.Repo, err)
+	}
+
+	// Step 3: Import the query result into the target graph
+	err = run.graphDB(tgtClient).ImportGraphRdf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Graph, queryFileName)
+	if err != nil {
+		return fmt.Errorf("failed to import query result into graph '%s': %w", task.Tgt.Graph, err)
+	}
+
+	result["message"] = "Query result imported successfully"
+	result["src_repo"] = task.Src.Repo
+	result["tgt_graph"] = task.Tgt.Graph
+	result["data_size"] = dataSize
+	if count, err := countGraphTriples(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Graph); err == nil {
+		result["imported_triples"] = count
+	}
+	return nil
+}
+
+// executeGraphSyncTask executes the graph-sync action.
+//
+// Bring the target graph in line with the source graph by applying only the
+// triple differences via SPARQL UPDATE instead of deleting and reimporting it.
+// Triples are compared as exact N-Triples statements; triples with blank nodes
+// cannot be matched between repositories and are left untouched.
+func executeGraphSyncTask(run *taskRun) error {
+	task, progress, result, srcClient, tgtClient, zitiClient := run.task, run.progress, run.result, run.srcClient, run.tgtClient, run.zitiClient
+
+	if task.Src == nil || task.Tgt == nil {
+		return fmt.Errorf("graph-sync requires both src and tgt")
+	}
+	if task.Src.Graph == "" {
+		return fmt.Errorf("graph-sync requires a source graph in src.graph")
+	}
+	tgtGraph := task.Tgt.Graph
+	if tgtGraph == "" {
+		tgtGraph = task.Src.Graph
+	}
+
+	if identityFile != "" {
+		srcURL, err := URL2ServiceRobust(task.Src.URL)
+		if err != nil {
+			return err
+		}
+		srcClient, err = zitiClient(srcURL)
+		if err != nil {
+			return err
+		}
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
+		if err != nil {
+			return err
+		}
+		tgtClient, err = zitiClient(tgtURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Step 1: Check that the source graph and the target repository exist
+	if err := requireTaskRepository(run.graphDB(srcClient), task.Src, "src"); err != nil {
+		return err
+	}
+	srcGraphs, err := run.graphDB(srcClient).ListGraphs(task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo)
+	if err != nil {
+		return fmt.Errorf("failed to list graphs in repository '%s': %w", task.Src.Repo, err)
+	}
+	if !graphListed(srcGraphs, task.Src.Graph) {
+		return newTaskError(ErrGraphNotFound, "could not find required src graph %s in repository %s", task.Src.Graph, task.Src.Repo)
+	}
+	if err := requireTaskRepository(run.graphDB(tgtClient), task.Tgt, "tgt"); err != nil {
+		return err
+	}
+	tgtGraphs, err := run.graphDB(tgtClient).ListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
+	if err != nil {
+		return fmt.Errorf("failed to list graphs in repository '%s': %w", task.Tgt.Repo, err)
+	}
+
+	// Step 2: Export both graphs and compute the difference
+	progress("Comparing graphs", 1, 1)
+	srcTriples, srcBlankNodes, dataSize, err := graphTripleSet(srcClient, task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo, task.Src.Graph)
+	if err != nil {
+		return fmt.Errorf("failed to export source graph '%s': %w", task.Src.Graph, err)
+	}
+	tgtTriples, tgtBlankNodes := make(tripleSet), 0
+	if graphListed(tgtGraphs, tgtGraph) {
+		tgtTriples, tgtBlankNodes, _, err = graphTripleSet(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, tgtGraph)
+		if err != nil {
+			return fmt.Errorf("failed to export target graph '%s': %w", tgtGraph, err)
+		}
+	}
+	additions, removals := diffTripleSets(srcTriples, tgtTriples)
+
+	if task.DryRun {
+		setDryRunResult(result, "Dry run: graph would be synchronized", []map[string]interface{}{
+			plannedOperation("remove-triples", task.Tgt.Repo, tgtGraph, len(removals)),
+			plannedOperation("add-triples", task.Tgt.Repo, tgtGraph, len(additions)),
+		})
+	} else {
+		// Step 3: Apply removals before additions
+		if err := applyGraphTriples(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, tgtGraph, "DELETE DATA", removals, "Removing triples", progress); err != nil {
+			return fmt.Errorf("failed to remove triples from graph '%s': %w", tgtGraph, err)
+		}
+		if err := applyGraphTriples(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, tgtGraph, "INSERT DATA", additions, "Adding triples", progress); err != nil {
+			return fmt.Errorf("failed to add triples to graph '%s': %w", tgtGraph, err)
+		}
+		result["message"] = "Graph synchronized successfully"
+		if count, err := countGraphTriples(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, tgtGraph); err == nil {
+			result["tgt_triples"] = count
+		}
+	}
+
+	result["src_graph"] = task.Src.Graph
+	result["tgt_graph"] = tgtGraph
+	result["added_triples"] = len(additions)
+	result["removed_triples"] = len(removals)
+	result["unchanged_triples"] = len(srcTriples) - len(additions)
+	result["data_size"] = dataSize
+	if srcBlankNodes > 0 || tgtBlankNodes > 0 {
+		result["skipped_blank_node_triples"] = srcBlankNodes + tgtBlankNodes
+		result["warning"] = fmt.Sprintf("Triples with blank nodes are not synchronized: %d in source, %d in target", srcBlankNodes, tgtBlankNodes)
+	}
+	return nil
 }
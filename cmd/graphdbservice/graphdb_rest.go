@@ -0,0 +1,535 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// This file contains direct calls to the GraphDB REST and SPARQL endpoints for
+// operations that are not covered by the eve db package. All functions take the
+// HTTP client explicitly instead of relying on db.HttpClient.
+
+// sparqlValue is a single RDF term in a SPARQL JSON result binding.
+type sparqlValue struct {
+	Type     string `json:"type"`
+	Value    string `json:"value"`
+	Datatype string `json:"datatype,omitempty"`
+	Lang     string `json:"xml:lang,omitempty"`
+}
+
+// sparqlResults is the decoded application/sparql-results+json response of a SELECT query.
+type sparqlResults struct {
+	Head struct {
+		Vars []string `json:"vars"`
+	} `json:"head"`
+	Results struct {
+		Bindings []map[string]sparqlValue `json:"bindings"`
+	} `json:"results"`
+}
+
+// readErrorBody reads a bounded amount of an error response body for inclusion in error messages.
+func readErrorBody(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return strings.TrimSpace(string(body))
+}
+
+// sparqlSelect runs a SPARQL SELECT query against a repository and returns the decoded bindings.
+func sparqlSelect(client *http.Client, serverURL, username, password, repo, query string) (*sparqlResults, error) {
+	endpoint := fmt.Sprintf("%s/repositories/%s", normalizeURL(serverURL), url.PathEscape(repo))
+	form := url.Values{"query": {query}}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/sparql-results+json")
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, graphDBStatusError(resp.StatusCode, "SPARQL query on repository '%s' failed with status %d: %s", repo, resp.StatusCode, readErrorBody(resp))
+	}
+
+	var results sparqlResults
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to decode SPARQL results: %w", err)
+	}
+	return &results, nil
+}
+
+// countGraphTriples returns the number of triples in a named graph or the default graph.
+func countGraphTriples(client *http.Client, serverURL, username, password, repo, graph string) (int, error) {
+	graphIRI, err := sparqlGraphIRI(graph)
+	if err != nil {
+		return -1, err
+	}
+	query := fmt.Sprintf("SELECT (COUNT(*) AS ?count) WHERE { GRAPH %s { ?s ?p ?o } }", graphIRI)
+	return sparqlCount(client, serverURL, username, password, repo, query)
+}
+
+// countGraphBlankNodeTriples returns the number of triples in a named graph whose
+// subject or object is a blank node.
+func countGraphBlankNodeTriples(client *http.Client, serverURL, username, password, repo, graph string) (int, error) {
+	graphIRI, err := sparqlGraphIRI(graph)
+	if err != nil {
+		return -1, err
+	}
+	query := fmt.Sprintf("SELECT (COUNT(*) AS ?count) WHERE { GRAPH %s { ?s ?p ?o FILTER(isBlank(?s) || isBlank(?o)) } }", graphIRI)
+	return sparqlCount(client, serverURL, username, password, repo, query)
+}
+
+// countRepositoryTriples returns the number of triples in a repository. GraphDB
+// evaluates queries without a dataset against the union of the default graph and
+// all named graphs, so every statement is counted once.
+func countRepositoryTriples(client *http.Client, serverURL, username, password, repo string) (int, error) {
+	query := "SELECT (COUNT(*) AS ?count) WHERE { ?s ?p ?o }"
+	return sparqlCount(client, serverURL, username, password, repo, query)
+}
+
+// sparqlCount runs a SELECT query binding ?count and returns the count.
+func sparqlCount(client *http.Client, serverURL, username, password, repo, query string) (int, error) {
+	results, err := sparqlSelect(client, serverURL, username, password, repo, query)
+	if err != nil {
+		return -1, err
+	}
+	if len(results.Results.Bindings) == 0 {
+		return 0, nil
+	}
+	count, err := strconv.Atoi(results.Results.Bindings[0]["count"].Value)
+	if err != nil {
+		return -1, fmt.Errorf("invalid triple count in SPARQL result: %w", err)
+	}
+	return count, nil
+}
+
+// graphDBAppendGraphRdf adds the contents of an RDF file to a named graph using the
+// SPARQL Graph Store protocol (POST), keeping any triples already in the graph.
+// graphDBAPI.ImportGraphRdf replaces the graph content instead.
+func graphDBAppendGraphRdf(client *http.Client, serverURL, username, password, repo, graph, fileName, contentType string) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", fileName, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file %s: %w", fileName, err)
+	}
+
+	return graphDBAppendGraphData(client, serverURL, username, password, repo, graph, file, fileInfo.Size(), contentType)
+}
+
+// graphDBAppendGraphData appends RDF data read from body to a named graph.
+// A negative size sends the body without a Content-Length.
+func graphDBAppendGraphData(client *http.Client, serverURL, username, password, repo, graph string, body io.Reader, size int64, contentType string) error {
+	endpoint := graphStoreURL(serverURL, repo, graph)
+	req, err := http.NewRequest(http.MethodPost, endpoint, body)
+	if err != nil {
+		return err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	req.Header.Set("Content-Type", contentType)
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 300 {
+		return graphDBStatusError(resp.StatusCode, "appending to graph '%s' failed with status %d: %s", graph, resp.StatusCode, readErrorBody(resp))
+	}
+	return nil
+}
+
+// graphDBImportStatements adds the contents of an RDF file to a repository via the
+// statements endpoint without a context, so graph names carried by quad formats
+// (N-Quads, TriG) are preserved. Triples without a graph go to the default graph.
+func graphDBImportStatements(client *http.Client, serverURL, username, password, repo, fileName, contentType string) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", fileName, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file %s: %w", fileName, err)
+	}
+
+	endpoint := fmt.Sprintf("%s/repositories/%s/statements", normalizeURL(serverURL), url.PathEscape(repo))
+	req, err := http.NewRequest(http.MethodPost, endpoint, file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = fileInfo.Size()
+	req.Header.Set("Content-Type", contentType)
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 300 {
+		return graphDBStatusError(resp.StatusCode, "importing statements into repository '%s' failed with status %d: %s", repo, resp.StatusCode, readErrorBody(resp))
+	}
+	return nil
+}
+
+// graphDBExportGraph requests a named graph serialized as contentType using the
+// SPARQL Graph Store protocol. The caller must close the returned body.
+// Unlike db.GraphDBExportGraphRdf, which always writes RDF/XML to a file, the
+// serialization is selectable and the data is not buffered on disk.
+func graphDBExportGraph(client *http.Client, serverURL, username, password, repo, graph, contentType string) (io.ReadCloser, error) {
+	endpoint := graphStoreURL(serverURL, repo, graph)
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", contentType)
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer func() { _ = resp.Body.Close() }()
+		return nil, graphDBStatusError(resp.StatusCode, "exporting graph '%s' failed with status %d: %s", graph, resp.StatusCode, readErrorBody(resp))
+	}
+	return resp.Body, nil
+}
+
+// graphDBExportGraphToFile writes a named graph serialized as contentType to fileName
+// and returns the number of bytes written.
+func graphDBExportGraphToFile(client *http.Client, serverURL, username, password, repo, graph, contentType, fileName string) (int64, error) {
+	body, err := graphDBExportGraph(client, serverURL, username, password, repo, graph, contentType)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = body.Close() }()
+
+	file, err := os.Create(fileName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file %s: %w", fileName, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	written, err := io.Copy(file, body)
+	if err != nil {
+		return written, fmt.Errorf("failed to write graph export to %s: %w", fileName, err)
+	}
+	return written, nil
+}
+
+// graphDBDownloadRepositoryConfig writes the Turtle configuration of a repository
+// to fileName. Unlike db.GraphDBRepositoryConf, which writes to the working
+// directory, the caller chooses where the file is stored.
+func graphDBDownloadRepositoryConfig(client *http.Client, serverURL, username, password, repo, fileName string) error {
+	endpoint := fmt.Sprintf("%s/rest/repositories/%s/download-ttl", normalizeURL(serverURL), url.PathEscape(repo))
+	_, err := graphDBDownloadToFile(client, endpoint, "text/turtle", username, password, fileName)
+	return err
+}
+
+// graphDBDownloadRepositoryData writes all statements of a repository as binary
+// RDF (BRF) to fileName and returns the number of bytes written. Unlike
+// db.GraphDBRepositoryBrf the caller chooses where the file is stored.
+func graphDBDownloadRepositoryData(client *http.Client, serverURL, username, password, repo, fileName string) (int64, error) {
+	endpoint := fmt.Sprintf("%s/repositories/%s/statements", normalizeURL(serverURL), url.PathEscape(repo))
+	return graphDBDownloadToFile(client, endpoint, "application/x-binary-rdf", username, password, fileName)
+}
+
+// graphDBDownloadToFile writes the response of a GET request to fileName. A
+// partially written file is removed when the download fails.
+func graphDBDownloadToFile(client *http.Client, endpoint, accept, username, password, fileName string) (int64, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", accept)
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return 0, graphDBStatusError(resp.StatusCode, "download failed with status %d: %s", resp.StatusCode, readErrorBody(resp))
+	}
+
+	file, err := os.Create(fileName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file %s: %w", fileName, err)
+	}
+	written, err := io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(fileName)
+		return written, fmt.Errorf("failed to write download to %s: %w", fileName, err)
+	}
+	return written, nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.count += int64(n)
+	return n, err
+}
+
+// graphDBStreamRepositoryData streams all statements of the source repository as
+// binary RDF (BRF) directly into the target repository, without writing the data
+// to a temp file or holding it in memory. Named graphs are preserved.
+// The upload progress is reported in MB to progress, which may be nil.
+// It returns the number of bytes transferred.
+func graphDBStreamRepositoryData(srcClient *http.Client, srcURL, srcUser, srcPass, srcRepo string, tgtClient *http.Client, tgtURL, tgtUser, tgtPass, tgtRepo string, progress ProgressFunc) (int64, error) {
+	srcEndpoint := fmt.Sprintf("%s/repositories/%s/statements", normalizeURL(srcURL), url.PathEscape(srcRepo))
+	srcReq, err := http.NewRequest(http.MethodGet, srcEndpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	srcReq.Header.Set("Accept", "application/x-binary-rdf")
+	if srcUser != "" {
+		srcReq.SetBasicAuth(srcUser, srcPass)
+	}
+
+	srcResp, err := srcClient.Do(srcReq)
+	if err != nil {
+		return 0, fmt.Errorf("failed to download repository data: %w", err)
+	}
+	defer func() { _ = srcResp.Body.Close() }()
+
+	if srcResp.StatusCode != http.StatusOK {
+		return 0, graphDBStatusError(srcResp.StatusCode, "downloading data of repository '%s' failed with status %d: %s", srcRepo, srcResp.StatusCode, readErrorBody(srcResp))
+	}
+
+	body := newProgressReader(srcResp.Body, srcResp.ContentLength, progress)
+	tgtEndpoint := fmt.Sprintf("%s/repositories/%s/statements", normalizeURL(tgtURL), url.PathEscape(tgtRepo))
+	tgtReq, err := http.NewRequest(http.MethodPost, tgtEndpoint, body)
+	if err != nil {
+		return 0, err
+	}
+	tgtReq.ContentLength = srcResp.ContentLength // -1 (chunked) if the source did not send a length
+	tgtReq.Header.Set("Content-Type", "application/x-binary-rdf")
+	if tgtUser != "" {
+		tgtReq.SetBasicAuth(tgtUser, tgtPass)
+	}
+
+	tgtResp, err := tgtClient.Do(tgtReq)
+	if err != nil {
+		return body.count.Load(), fmt.Errorf("failed to restore repository data: %w", err)
+	}
+	defer func() { _ = tgtResp.Body.Close() }()
+
+	if tgtResp.StatusCode >= 300 {
+		return body.count.Load(), graphDBStatusError(tgtResp.StatusCode, "restoring data into repository '%s' failed with status %d: %s", tgtRepo, tgtResp.StatusCode, readErrorBody(tgtResp))
+	}
+	return body.count.Load(), nil
+}
+
+// sparqlQueryForm returns the query form (SELECT, CONSTRUCT, DESCRIBE, ASK) of a
+// SPARQL query, skipping comments and PREFIX/BASE declarations. It returns an
+// empty string if no query form is found.
+func sparqlQueryForm(query string) string {
+	var tokens []string
+	for _, line := range strings.Split(query, "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 && !strings.Contains(line[:idx], "<") {
+			line = line[:idx]
+		}
+		tokens = append(tokens, strings.Fields(line)...)
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		switch keyword := strings.ToUpper(tokens[i]); keyword {
+		case "PREFIX":
+			i++ // Skip prefix name and IRI, which may be written without a space
+			if i < len(tokens) && !strings.Contains(tokens[i], "<") {
+				i++
+			}
+		case "BASE":
+			i++ // Skip IRI
+		case "SELECT", "CONSTRUCT", "DESCRIBE", "ASK":
+			return keyword
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// sparqlConstructToFile runs a CONSTRUCT or DESCRIBE query against a repository and
+// writes the resulting RDF/XML to fileName. It returns the number of bytes written.
+func sparqlConstructToFile(client *http.Client, serverURL, username, password, repo, query, fileName string) (int64, error) {
+	endpoint := fmt.Sprintf("%s/repositories/%s", normalizeURL(serverURL), url.PathEscape(repo))
+	form := url.Values{"query": {query}}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/rdf+xml")
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, graphDBStatusError(resp.StatusCode, "SPARQL query on repository '%s' failed with status %d: %s", repo, resp.StatusCode, readErrorBody(resp))
+	}
+
+	file, err := os.Create(fileName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file %s: %w", fileName, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	written, err := io.Copy(file, resp.Body)
+	if err != nil {
+		return written, fmt.Errorf("failed to write query result to %s: %w", fileName, err)
+	}
+	return written, nil
+}
+
+// sparqlUpdate runs a SPARQL UPDATE request against a repository. The update is
+// posted directly as application/sparql-update.
+func sparqlUpdate(client *http.Client, serverURL, username, password, repo, update string) error {
+	endpoint := fmt.Sprintf("%s/repositories/%s/statements", normalizeURL(serverURL), url.PathEscape(repo))
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(update))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/sparql-update")
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 300 {
+		return graphDBStatusError(resp.StatusCode, "SPARQL update on repository '%s' failed with status %d: %s", repo, resp.StatusCode, readErrorBody(resp))
+	}
+	return nil
+}
+
+// GraphDBServerInfo is the version information reported by a GraphDB server.
+type GraphDBServerInfo struct {
+	Version    string `json:"version"`
+	Edition    string `json:"edition"`
+	Workbench  string `json:"workbench,omitempty"`
+	RDF4J      string `json:"rdf4j,omitempty"`
+	Connectors string `json:"connectors,omitempty"`
+}
+
+// MajorVersion returns the major version number, or 0 if the version cannot be parsed.
+func (i *GraphDBServerInfo) MajorVersion() int {
+	major, _, _ := strings.Cut(i.Version, ".")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// SupportsRDFStar reports whether the server reads and writes RDF-star, which
+// GraphDB does from version 9.2 on. An unparsable version reports false.
+func (i *GraphDBServerInfo) SupportsRDFStar() bool {
+	major, rest, _ := strings.Cut(i.Version, ".")
+	minor, _, _ := strings.Cut(rest, ".")
+	majorVersion, err := strconv.Atoi(major)
+	if err != nil {
+		return false
+	}
+	minorVersion, _ := strconv.Atoi(minor)
+	return majorVersion > 9 || majorVersion == 9 && minorVersion >= 2
+}
+
+// graphDBServerInfo queries /rest/info/version of a GraphDB server. It fails if
+// the server does not answer with GraphDB version information.
+func graphDBServerInfo(client *http.Client, serverURL, username, password string) (*GraphDBServerInfo, error) {
+	endpoint := normalizeURL(serverURL) + "/rest/info/version"
+
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, graphDBStatusError(resp.StatusCode, "server %s does not look like GraphDB: version request failed with status %d: %s", serverURL, resp.StatusCode, readErrorBody(resp))
+	}
+
+	var version struct {
+		ProductVersion string `json:"productVersion"`
+		ProductType    string `json:"productType"`
+		Workbench      string `json:"Workbench"`
+		Sesame         string `json:"sesame"`
+		Connectors     string `json:"connectors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil || version.ProductVersion == "" {
+		return nil, fmt.Errorf("server %s does not look like GraphDB: no version information in /rest/info/version", serverURL)
+	}
+
+	return &GraphDBServerInfo{
+		Version:    version.ProductVersion,
+		Edition:    version.ProductType,
+		Workbench:  version.Workbench,
+		RDF4J:      version.Sesame,
+		Connectors: version.Connectors,
+	}, nil
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// graphDBSecurityEnabled asks a GraphDB server whether its security is
+// enabled. GET /rest/security answers true or false and needs no credentials.
+func graphDBSecurityEnabled(client *http.Client, serverURL string, timeout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, normalizeURL(serverURL)+"/rest/security", nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("security status request failed with status %d: %s", resp.StatusCode, readErrorBody(resp))
+	}
+	var enabled bool
+	if err := json.NewDecoder(resp.Body).Decode(&enabled); err != nil {
+		return false, fmt.Errorf("failed to parse security status: %w", err)
+	}
+	return enabled, nil
+}
+
+// checkGraphDBAccess probes the repositories endpoint of the server of repo
+// with the credentials of repo. A transport failure is returned as an
+// *unreachableServerError; a 401 or 403 response as an ErrAuth error
+// explaining whether credentials are missing, rejected or lack permissions.
+func checkGraphDBAccess(client *http.Client, repo *Repository, timeout time.Duration) error {
+	serverURL := normalizeURL(repo.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, serverURL+"/rest/repositories", nil)
+	if err != nil {
+		return &unreachableServerError{URL: serverURL, Err: err}
+	}
+	if repo.Username != "" || repo.Password != "" {
+		req.SetBasicAuth(repo.Username, repo.Password)
+	}
+	repo.setHeaders(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return &unreachableServerError{URL: serverURL, Err: err}
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+		return nil
+	}
+	return graphDBAuthError(client, repo, resp.StatusCode, timeout)
+}
+
+// graphDBAuthError returns the ErrAuth error for a request to the server of
+// repo that was answered with statusCode 401 or 403. The security status of
+// the server tells a missing or wrong login apart from a proxy demanding one.
+func graphDBAuthError(client *http.Client, repo *Repository, statusCode int, timeout time.Duration) error {
+	serverURL := normalizeURL(repo.URL)
+	if statusCode == http.StatusForbidden {
+		user := "the given credentials"
+		if repo.Username != "" {
+			user = fmt.Sprintf("user '%s'", repo.Username)
+		}
+		return &TaskError{Kind: ErrAuth, StatusCode: statusCode, message: fmt.Sprintf("access denied: %s may not access the repositories of GraphDB server %s", user, serverURL)}
+	}
+
+	server := fmt.Sprintf("GraphDB server %s requires authentication", serverURL)
+	if enabled, err := graphDBSecurityEnabled(client, serverURL, timeout); err == nil {
+		if enabled {
+			server = fmt.Sprintf("GraphDB server %s has security enabled", serverURL)
+		} else {
+			server = fmt.Sprintf("GraphDB server %s has security disabled, but a proxy in front of it requires authentication", serverURL)
+		}
+	}
+	var message string
+	switch {
+	case repo.Token != "":
+		message = fmt.Sprintf("authentication failed: %s and rejected the token", server)
+	case repo.Username != "":
+		message = fmt.Sprintf("authentication failed: %s and rejected the credentials of user '%s'", server, repo.Username)
+	default:
+		message = fmt.Sprintf("authentication required: %s and no credentials were given; set username and password or token", server)
+	}
+	return &TaskError{Kind: ErrAuth, StatusCode: statusCode, message: message}
+}
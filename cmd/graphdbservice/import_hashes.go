@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// importHashesFile is the file below MIGRATION_LOG_DIR holding the content hash
+// of the last graph-import into each graph
+const importHashesFile = "import_hashes.json"
+
+// ImportHash records the content of the last successful graph-import into a graph
+type ImportHash struct {
+	Hash       string    `json:"hash"`
+	Algorithm  string    `json:"algorithm"`
+	Files      []string  `json:"files"`
+	SessionID  string    `json:"session_id,omitempty"`
+	ImportedAt time.Time `json:"imported_at"`
+}
+
+// importHashKey identifies a graph across servers. Credentials in the server
+// URL are left out; graph is empty for quad files imported with their own
+// graph names.
+func importHashKey(serverURL, repo, graph string) string {
+	serverURL, _, _ = splitURLCredentials(serverURL)
+	return strings.Join([]string{normalizeURL(serverURL), repo, graph}, "|")
+}
+
+// LastImportHash returns the record of the last graph-import into a graph
+func (l *MigrationLogger) LastImportHash(serverURL, repo, graph string) (ImportHash, bool, error) {
+	l.importMu.Lock()
+	defer l.importMu.Unlock()
+
+	hashes, err := l.readImportHashes()
+	if err != nil {
+		return ImportHash{}, false, err
+	}
+	record, ok := hashes[importHashKey(serverURL, repo, graph)]
+	return record, ok, nil
+}
+
+// RecordImportHash stores the record of a successful graph-import into a graph
+func (l *MigrationLogger) RecordImportHash(serverURL, repo, graph string, record ImportHash) error {
+	l.importMu.Lock()
+	defer l.importMu.Unlock()
+
+	hashes, err := l.readImportHashes()
+	if err != nil {
+		return err
+	}
+	hashes[importHashKey(serverURL, repo, graph)] = record
+
+	data, err := json.MarshalIndent(hashes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode import hashes: %w", err)
+	}
+	path := filepath.Join(l.dir, importHashesFile)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o640); err != nil {
+		return fmt.Errorf("failed to write import hashes: %w", err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// readImportHashes reads the import hashes by graph key. The caller must hold l.importMu.
+func (l *MigrationLogger) readImportHashes() (map[string]ImportHash, error) {
+	hashes := make(map[string]ImportHash)
+	data, err := os.ReadFile(filepath.Join(l.dir, importHashesFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return hashes, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import hashes: %w", err)
+	}
+	if err := json.Unmarshal(data, &hashes); err != nil {
+		return nil, fmt.Errorf("failed to decode import hashes: %w", err)
+	}
+	return hashes, nil
+}
+
+// uploadedFilesHash returns the content hash of the files of a graph-import
+// with the FILE_HASH_ALGORITHM: the hash of a single file, or the hash of the
+// file hashes in upload order for several files
+func uploadedFilesHash(fileHeaders []*multipart.FileHeader) (string, error) {
+	hashes := make([]string, len(fileHeaders))
+	for i, fileHeader := range fileHeaders {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return "", fmt.Errorf("failed to open file %s: %w", fileHeader.Filename, err)
+		}
+		_, hashes[i], err = copyWithHash(io.Discard, file)
+		_ = file.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to hash file %s: %w", fileHeader.Filename, err)
+		}
+	}
+	if len(hashes) == 1 {
+		return hashes[0], nil
+	}
+	_, combined, err := copyWithHash(io.Discard, strings.NewReader(strings.Join(hashes, "\n")))
+	return combined, err
+}
+
+// skipUnchangedImport implements skip_unchanged of graph-import: it hashes the
+// uploaded files and reports whether they match the last import into the
+// target graph, which must still exist. Otherwise it returns the content hash
+// to record with recordGraphImport once the files are imported.
+func skipUnchangedImport(run *taskRun, tgtClient *http.Client, fileHeaders []*multipart.FileHeader) (bool, string, error) {
+	task, result := run.task, run.result
+	if migrationLogger == nil {
+		addResultWarning(result, "skip_unchanged requires migration session logging, the files are imported")
+		return false, "", nil
+	}
+
+	contentHash, err := uploadedFilesHash(fileHeaders)
+	if err != nil {
+		return false, "", err
+	}
+	result["content_hash"] = contentHash
+	result["hash_algorithm"] = fileHashAlgorithm()
+	result["skipped"] = false
+	if sessionID := sessionIDFromContext(run.ctx); sessionID != "" {
+		migrationLogger.SetTaskContentHash(sessionID, run.taskIndex, contentHash, fileHashAlgorithm())
+	}
+
+	last, found, err := migrationLogger.LastImportHash(task.Tgt.URL, task.Tgt.Repo, task.Tgt.Graph)
+	if err != nil {
+		run.log.Warn("Failed to read the last import hash", "error", err)
+		return false, contentHash, nil
+	}
+	if !found || last.Hash != contentHash || last.Algorithm != fileHashAlgorithm() {
+		return false, contentHash, nil
+	}
+	// A graph deleted since the last import is imported again
+	if task.Tgt.Graph != "" {
+		graphs, err := run.graphDB(tgtClient).ListGraphs(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
+		if err != nil {
+			return false, "", err
+		}
+		if !graphListed(graphs, task.Tgt.Graph) {
+			debugLog("Graph %s no longer exists, importing unchanged files again", task.Tgt.Graph)
+			return false, contentHash, nil
+		}
+	}
+
+	result["skipped"] = true
+	result["message"] = "Graph import skipped, the files are unchanged since the last import"
+	result["graph"] = task.Tgt.Graph
+	result["last_imported_at"] = last.ImportedAt
+	if last.SessionID != "" {
+		result["last_session_id"] = last.SessionID
+	}
+	return true, contentHash, nil
+}
+
+// recordImportedFile records an imported file with its hash on the session task
+func (run *taskRun) recordImportedFile(name string, size int64, hash string) {
+	if sessionID := sessionIDFromContext(run.ctx); sessionID != "" && migrationLogger != nil {
+		migrationLogger.AddTaskFile(sessionID, run.taskIndex, TaskFile{Name: name, Size: size, Hash: hash}, fileHashAlgorithm())
+	}
+}
+
+// recordGraphImport records the content hash of files imported into the target
+// graph, for the next graph-import with skip_unchanged
+func recordGraphImport(run *taskRun, fileHeaders []*multipart.FileHeader, contentHash string) {
+	task := run.task
+	record := ImportHash{
+		Hash:       contentHash,
+		Algorithm:  fileHashAlgorithm(),
+		Files:      getFileNames(fileHeaders),
+		SessionID:  sessionIDFromContext(run.ctx),
+		ImportedAt: time.Now().UTC(),
+	}
+	if err := migrationLogger.RecordImportHash(task.Tgt.URL, task.Tgt.Repo, task.Tgt.Graph, record); err != nil {
+		run.log.Warn("Failed to record the import hash", "error", err)
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+
+	"eve.evalgo.org/common"
+	"github.com/labstack/echo/v4"
+)
+
+// Defaults of MAX_IMPORT_FILES and MAX_FILE_SIZE_MB, the file size matches
+// the default BODY_LIMIT of 100M
+const (
+	defaultMaxImportFiles = 100
+	defaultMaxFileSizeMB  = 100
+)
+
+// maxImportFiles returns the most files a graph-import task accepts, 0 = unlimited
+func maxImportFiles() int {
+	return max(common.GetEnvInt("MAX_IMPORT_FILES", defaultMaxImportFiles), 0)
+}
+
+// maxImportFileSize returns the largest file in bytes a graph-import task
+// accepts, 0 = unlimited
+func maxImportFileSize() int64 {
+	return int64(max(common.GetEnvInt("MAX_FILE_SIZE_MB", defaultMaxFileSizeMB), 0)) << 20
+}
+
+// checkImportFileSize rejects an uploaded file larger than MAX_FILE_SIZE_MB
+func checkImportFileSize(fileHeader *multipart.FileHeader) error {
+	if limit := maxImportFileSize(); limit > 0 && fileHeader.Size > limit {
+		return fmt.Errorf("file %s is %d bytes, more than the limit of %d MB (MAX_FILE_SIZE_MB)", fileHeader.Filename, fileHeader.Size, limit>>20)
+	}
+	return nil
+}
+
+// multipartBodyLimit returns the largest multipart body in bytes: MAX_IMPORT_FILES
+// files of MAX_FILE_SIZE_MB plus MULTIPART_MEMORY_MB for the other form fields,
+// 0 = unlimited if either file limit is 0. BODY_LIMIT applies in any case.
+func multipartBodyLimit() int64 {
+	files, size := int64(maxImportFiles()), maxImportFileSize()
+	if files == 0 || size == 0 {
+		return 0
+	}
+	return files*size + multipartMemoryBytes
+}
+
+// parseMultipartForm parses the multipart body of c with the body capped at
+// multipartBodyLimit, so an oversized upload is cut off while it is read
+// instead of being spilled to temp files first. message prefixes other errors.
+func parseMultipartForm(c echo.Context, message string) error {
+	r := c.Request()
+	if limit := multipartBodyLimit(); limit > 0 {
+		r.Body = http.MaxBytesReader(c.Response(), r.Body, limit)
+	}
+	if err := r.ParseMultipartForm(multipartMemoryBytes); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes (MAX_IMPORT_FILES x MAX_FILE_SIZE_MB)", tooLarge.Limit))
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s: %v", message, err))
+	}
+	return nil
+}
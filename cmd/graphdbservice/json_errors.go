@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// jsonSyntaxViolation describes a JSON syntax error of a request body with the
+// line, column and a snippet pointing at the offending character
+func jsonSyntaxViolation(body []byte, err error) validationMessage {
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return validationMessage{Message: fmt.Sprintf("invalid JSON: %v", err)}
+	}
+	line, col, snippet := getErrorLocation(string(body), syntaxErr.Offset)
+	return validationMessage{
+		Message: fmt.Sprintf("invalid JSON at line %d, column %d: %v", line, col, err),
+		Line:    line,
+		Column:  col,
+		Snippet: snippet,
+	}
+}
+
+// errorSnippetWindow bounds how many bytes before and after a JSON error
+// getErrorLocation looks at to build the snippet, so large bodies stay cheap
+const errorSnippetWindow = 200
+
+// getErrorLocation finds the line, column, and context snippet for a JSON error.
+// offset is json.SyntaxError.Offset, the number of bytes read including the
+// offending character. Columns count runes, so multi-byte characters before the
+// error do not shift the pointer.
+//
+// Line and column are counted without copying the input; the snippet is built
+// from at most errorSnippetWindow bytes on each side of the error, so a
+// megabyte body on a single line does not produce a megabyte snippet.
+func getErrorLocation(jsonStr string, offset int64) (line int, col int, snippet string) {
+	// The offending character is the last byte read
+	errPos := len(jsonStr)
+	if offset-1 < int64(len(jsonStr)) {
+		errPos = int(offset - 1)
+	}
+	if errPos < 0 {
+		errPos = 0
+	}
+
+	line = 1 + strings.Count(jsonStr[:errPos], "\n")
+	lineStart := strings.LastIndexByte(jsonStr[:errPos], '\n') + 1
+	col = 1 + utf8.RuneCountInString(jsonStr[lineStart:errPos])
+
+	// Cut the snippet window out of the problematic line, on rune boundaries
+	lo := errPos - errorSnippetWindow
+	if lo < lineStart {
+		lo = lineStart
+	}
+	for lo < errPos && !utf8.RuneStart(jsonStr[lo]) {
+		lo++
+	}
+	hi := errPos + errorSnippetWindow
+	if hi > len(jsonStr) {
+		hi = len(jsonStr)
+	}
+	lineEnd := -1
+	if n := strings.IndexByte(jsonStr[errPos:hi], '\n'); n >= 0 {
+		lineEnd = errPos + n
+		hi = lineEnd
+	}
+	for hi > errPos && hi < len(jsonStr) && !utf8.RuneStart(jsonStr[hi]) {
+		hi--
+	}
+	truncatedStart := lo > lineStart
+	truncatedEnd := lineEnd < 0 && hi < len(jsonStr)
+
+	// Drop the indentation and move the pointer with it
+	lineRunes := []rune(jsonStr[lo:hi])
+	if !truncatedEnd {
+		lineRunes = []rune(strings.TrimRightFunc(string(lineRunes), unicode.IsSpace))
+	}
+	indent := 0
+	if !truncatedStart {
+		for indent < len(lineRunes) && unicode.IsSpace(lineRunes[indent]) {
+			indent++
+		}
+	}
+	lineRunes = lineRunes[indent:]
+	pointerPos := utf8.RuneCountInString(jsonStr[lo:errPos]) - indent
+
+	prefix, suffix := "", ""
+	if truncatedStart {
+		prefix = "..."
+	}
+	if truncatedEnd {
+		suffix = "..."
+	}
+	if len(lineRunes) > 80 {
+		// Truncate long lines but show the error position
+		start := pointerPos - 40
+		if start < 0 {
+			start = 0
+		}
+		end := start + 80
+		if end > len(lineRunes) {
+			end = len(lineRunes)
+		}
+		if start > 0 {
+			prefix = "..."
+		}
+		if end < len(lineRunes) {
+			suffix = "..."
+		}
+		lineRunes = lineRunes[start:end]
+		pointerPos -= start
+	}
+
+	// Keep the pointer within the snippet, e.g. for errors at the end of the input
+	if pointerPos < 0 {
+		pointerPos = 0
+	}
+	if pointerPos > len(lineRunes) {
+		pointerPos = len(lineRunes)
+	}
+
+	// Add a pointer to the error position
+	pointer := strings.Repeat(" ", len(prefix)+pointerPos) + "^"
+	snippet = prefix + string(lineRunes) + suffix + "\n" + pointer
+
+	return line, col, snippet
+}
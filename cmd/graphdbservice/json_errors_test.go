@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestGetErrorLocation tests the error location helper function
+func TestGetErrorLocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		jsonStr string
+		offset  int64
+	}{
+		{
+			name:    "First line error",
+			jsonStr: `{"version": "v0.0.1"`,
+			offset:  20,
+		},
+		{
+			name: "Multi-line error",
+			jsonStr: `{
+  "version": "v0.0.1",
+  "tasks": [
+}`,
+			offset: 40,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, col, snippet := getErrorLocation(tt.jsonStr, tt.offset)
+
+			// Line should be positive
+			if line < 1 {
+				t.Errorf("Expected line >= 1 but got %d", line)
+			}
+
+			// Column should be positive
+			if col < 1 {
+				t.Errorf("Expected column >= 1 but got %d", col)
+			}
+
+			// Snippet should not be empty
+			if snippet == "" {
+				t.Error("Expected non-empty snippet")
+			}
+
+			// Snippet should contain a pointer (^)
+			if !strings.Contains(snippet, "^") {
+				t.Errorf("Expected snippet to contain pointer '^', but got: %s", snippet)
+			}
+
+			// Snippet should contain newline (for multi-line format)
+			if !strings.Contains(snippet, "\n") {
+				t.Errorf("Expected snippet to contain newline for error pointer, but got: %s", snippet)
+			}
+		})
+	}
+}
+
+// TestGetErrorLocationPointer checks that the pointer lands on the offending
+// character, also with multi-byte characters and indentation before it
+func TestGetErrorLocationPointer(t *testing.T) {
+	tests := []struct {
+		name     string
+		jsonStr  string
+		wantLine int
+		wantCol  int
+		wantChar rune
+	}{
+		{
+			name:     "trailing comma in array",
+			jsonStr:  `{"tasks":[{"action":"graph-import"},]}`,
+			wantLine: 1,
+			wantCol:  37,
+			wantChar: ']',
+		},
+		{
+			name:     "unicode before trailing comma",
+			jsonStr:  `{"tasks":[{"graph":"http://example.org/straße/ä"},]}`,
+			wantLine: 1,
+			wantCol:  51,
+			wantChar: ']',
+		},
+		{
+			name: "unicode on an indented line",
+			jsonStr: `{
+  "tasks": [
+    {"graph": "日本語グラフ"},
+  ]
+}`,
+			wantLine: 4,
+			wantCol:  3,
+			wantChar: ']',
+		},
+		{
+			name:     "missing comma after unicode value",
+			jsonStr:  `{"name": "Zoë" "graph": "x"}`,
+			wantLine: 1,
+			wantCol:  16,
+			wantChar: '"',
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v interface{}
+			err := json.Unmarshal([]byte(tt.jsonStr), &v)
+			syntaxErr, ok := err.(*json.SyntaxError)
+			if !ok {
+				t.Fatalf("expected a syntax error but got %v", err)
+			}
+
+			line, col, snippet := getErrorLocation(tt.jsonStr, syntaxErr.Offset)
+			if line != tt.wantLine || col != tt.wantCol {
+				t.Errorf("expected line %d, column %d but got line %d, column %d", tt.wantLine, tt.wantCol, line, col)
+			}
+
+			parts := strings.SplitN(snippet, "\n", 2)
+			if len(parts) != 2 {
+				t.Fatalf("expected snippet and pointer line but got %q", snippet)
+			}
+			code, pointer := []rune(parts[0]), parts[1]
+			pos := strings.Index(pointer, "^")
+			if pos < 0 || pos >= len(code) {
+				t.Fatalf("pointer position %d outside snippet %q", pos, parts[0])
+			}
+			if code[pos] != tt.wantChar {
+				t.Errorf("expected pointer on %q but it is on %q in %q", tt.wantChar, code[pos], parts[0])
+			}
+		})
+	}
+}
+
+// TestGetErrorLocationClamp checks that the pointer never goes past the snippet
+func TestGetErrorLocationClamp(t *testing.T) {
+	jsonStr := `{"graph": "ünïcödé"`
+	_, _, snippet := getErrorLocation(jsonStr, int64(len(jsonStr)+10))
+
+	parts := strings.SplitN(snippet, "\n", 2)
+	if len(parts) != 2 {
+		t.Fatalf("expected snippet and pointer line but got %q", snippet)
+	}
+	if pos := strings.Index(parts[1], "^"); pos > len([]rune(parts[0])) {
+		t.Errorf("pointer position %d exceeds snippet length %d", pos, len([]rune(parts[0])))
+	}
+}
+
+// TestGetErrorLocationLargeBody checks that a 1MB malformed body yields a short
+// snippet with the pointer on the offending character
+func TestGetErrorLocationLargeBody(t *testing.T) {
+	graphs := strings.Repeat(`"http://example.org/graph/ä",`, 1<<20/30)
+	tests := []struct {
+		name     string
+		jsonStr  string
+		wantLine int
+		wantChar rune
+	}{
+		{
+			name:     "single line, error at the end",
+			jsonStr:  `{"version":"v0.0.1","tasks":[{"action":"graphs-delete","tgt":{"graphs":[` + graphs + `]}}]}`,
+			wantLine: 1,
+			wantChar: ']',
+		},
+		{
+			name:     "single line, error in the middle",
+			jsonStr:  `{"graphs":[` + graphs[:len(graphs)/2] + `,` + graphs + `"x"]}`,
+			wantLine: 1,
+			wantChar: ',',
+		},
+		{
+			name:     "many lines",
+			jsonStr:  "{\n" + strings.Repeat("  \"key\": \"value\",\n", 1<<20/18) + "  \"last\": 1,\n}",
+			wantLine: 1<<20/18 + 3,
+			wantChar: '}',
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.jsonStr) < 1<<20 {
+				t.Fatalf("test body has only %d bytes", len(tt.jsonStr))
+			}
+			var v interface{}
+			err := json.Unmarshal([]byte(tt.jsonStr), &v)
+			syntaxErr, ok := err.(*json.SyntaxError)
+			if !ok {
+				t.Fatalf("expected a syntax error but got %v", err)
+			}
+
+			line, _, snippet := getErrorLocation(tt.jsonStr, syntaxErr.Offset)
+			if line != tt.wantLine {
+				t.Errorf("expected line %d but got %d", tt.wantLine, line)
+			}
+			if len(snippet) > 2*(errorSnippetWindow+10) {
+				t.Errorf("snippet has %d bytes, expected it to be bounded", len(snippet))
+			}
+
+			parts := strings.SplitN(snippet, "\n", 2)
+			if len(parts) != 2 {
+				t.Fatalf("expected snippet and pointer line but got %q", snippet)
+			}
+			code := []rune(parts[0])
+			pos := strings.Index(parts[1], "^")
+			if pos < 0 || pos >= len(code) {
+				t.Fatalf("pointer position %d outside snippet %q", pos, parts[0])
+			}
+			if code[pos] != tt.wantChar {
+				t.Errorf("expected pointer on %q but it is on %q in %q", tt.wantChar, code[pos], parts[0])
+			}
+		})
+	}
+
+	// Offsets outside the body must not panic
+	body := `{"graphs":[` + graphs
+	for _, offset := range []int64{-5, 0, 1, int64(len(body)), int64(len(body)) + 1000} {
+		if _, _, snippet := getErrorLocation(body, offset); !strings.Contains(snippet, "^") {
+			t.Errorf("offset %d: expected a pointer in %q", offset, snippet)
+		}
+	}
+}
+
+// TestValidateRequestSchemaSyntaxError checks that a malformed body is reported
+// with the location of the offending character
+func TestValidateRequestSchemaSyntaxError(t *testing.T) {
+	violations := validateRequestSchema([]byte("{\n  \"version\": \"v0.0.1\",\n  \"tasks\": [{\"action\": \"repo-delete\"},]\n}"))
+	if len(violations) != 1 {
+		t.Fatalf("Expected one violation, got %v", violations)
+	}
+	v := violations[0]
+	if v.Line != 3 || v.Column != 39 || !strings.Contains(v.Message, "line 3, column 39") {
+		t.Errorf("Expected the error at line 3, column 39, got %+v", v)
+	}
+	if !strings.HasSuffix(v.Snippet, "\n"+strings.Repeat(" ", 36)+"^") {
+		t.Errorf("Expected the pointer below the closing bracket, got %q", v.Snippet)
+	}
+}
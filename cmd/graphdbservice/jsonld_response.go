@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// mimeJSONLD is the media type of JSON-LD responses of POST /v1/api/action
+const mimeJSONLD = "application/ld+json"
+
+// wantsJSONLD reports whether the client prefers JSON-LD over plain JSON. The
+// Accept header must list application/ld+json with a quality of at least that
+// of application/json; without it the plain JSON response is kept.
+func wantsJSONLD(c echo.Context) bool {
+	jsonLD, plain := -1.0, -1.0
+	for _, part := range strings.Split(c.Request().Header.Get(echo.HeaderAccept), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case mimeJSONLD:
+			jsonLD = max(jsonLD, quality)
+		case echo.MIMEApplicationJSON:
+			plain = max(plain, quality)
+		}
+	}
+	return jsonLD > 0 && jsonLD >= plain
+}
+
+// taskActionJSONLD describes a task and its outcome as Schema.org action of the
+// type of its actionSpec, as the semantic API does. A task without result and
+// error did not run, because an earlier task of a sequential request failed.
+func taskActionJSONLD(task Task, taskIndex int, result map[string]interface{}, err error) map[string]interface{} {
+	actionType := "Action"
+	if spec, ok := actionHandlers[task.Action].(*actionSpec); ok && spec.SchemaType != "" {
+		actionType = spec.SchemaType
+	}
+
+	action := map[string]interface{}{
+		"@type":        actionType,
+		"identifier":   fmt.Sprintf("task-%d", taskIndex),
+		"name":         task.Action,
+		"actionStatus": "CompletedActionStatus",
+	}
+	switch {
+	case err != nil:
+		action["actionStatus"] = "FailedActionStatus"
+		action["error"] = map[string]interface{}{
+			"@type":       "Thing",
+			"name":        "TaskError",
+			"description": err.Error(),
+		}
+	case result == nil:
+		action["actionStatus"] = "PotentialActionStatus"
+	default:
+		action["result"] = result
+	}
+	return action
+}
+
+// migrationResponseJSONLD writes the results of a request as Schema.org ItemList
+// of actions, matching the ItemList response of the semantic API. The status is
+// 200 if all tasks succeeded, 500 if none and 207 otherwise.
+func migrationResponseJSONLD(c echo.Context, req MigrationRequest, sessionID string, results []map[string]interface{}, errs []error) error {
+	actions := make([]map[string]interface{}, len(req.Tasks))
+	var errorMessages []string
+	successful := 0
+	for i, task := range req.Tasks {
+		actions[i] = taskActionJSONLD(task, i, results[i], errs[i])
+		if errs[i] != nil {
+			errorMessages = append(errorMessages, fmt.Sprintf("Task %d failed: %s", i, errs[i].Error()))
+		} else if results[i] != nil {
+			successful++
+		}
+	}
+
+	response := map[string]interface{}{
+		"@context":        "https://schema.org",
+		"@type":           "ItemList",
+		"identifier":      sessionID,
+		"version":         req.Version,
+		"actionStatus":    "CompletedActionStatus",
+		"totalItems":      len(req.Tasks),
+		"successfulItems": successful,
+		"failedItems":     len(errorMessages),
+		"results":         actions,
+	}
+	if len(errorMessages) > 0 {
+		response["errors"] = errorMessages
+		response["actionStatus"] = "FailedActionStatus"
+	}
+
+	statusCode := http.StatusOK
+	if successful == 0 {
+		statusCode = http.StatusInternalServerError
+	} else if successful < len(req.Tasks) {
+		statusCode = http.StatusMultiStatus
+	}
+
+	return writeJSONLD(c, statusCode, response)
+}
+
+// writeJSONLD writes value as JSON with the JSON-LD media type
+func writeJSONLD(c echo.Context, statusCode int, value interface{}) error {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to encode response")
+	}
+	return c.Blob(statusCode, mimeJSONLD, body)
+}
@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"eve.evalgo.org/common"
+	"eve.evalgo.org/db"
+)
+
+// defaultListingCacheTTLSeconds is the default of LISTING_CACHE_TTL_SECONDS
+const defaultListingCacheTTLSeconds = 5
+
+// listingCacheTTL returns how long repository and graph listings are reused
+// within a request, set by LISTING_CACHE_TTL_SECONDS. Zero disables the cache.
+func listingCacheTTL() time.Duration {
+	seconds := common.GetEnvInt("LISTING_CACHE_TTL_SECONDS", defaultListingCacheTTLSeconds)
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// listingCache holds the repository and graph listings fetched by the tasks of
+// one request, so the existence checks of later tasks against the same server
+// reuse them. It lives only as long as the request; entries expire after ttl
+// and are dropped when a task changes the listed repositories or graphs.
+type listingCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cachedListing
+}
+
+// cachedListing is a listing with the time it was fetched
+type cachedListing struct {
+	listing   *db.GraphDBResponse
+	fetchedAt time.Time
+}
+
+func newListingCache(ttl time.Duration) *listingCache {
+	return &listingCache{ttl: ttl, entries: make(map[string]cachedListing)}
+}
+
+// repositoriesKey is the cache key of the repository listing of a server.
+// credentials is the hash of the password and token the listing is fetched
+// with (see credentialsHash), so a listing is never shared between callers
+// that authenticate differently under the same user name.
+func repositoriesKey(serverURL, username, credentials string) string {
+	return normalizeURL(serverURL) + "|" + username + "|" + credentials + "|"
+}
+
+// graphsKey is the cache key of the graph listing of a repository
+func graphsKey(serverURL, username, credentials, repo string) string {
+	return repositoriesKey(serverURL, username, credentials) + repo
+}
+
+// credentialsHash returns a SHA-256 hash of credentials for cache keys, which
+// keeps the secrets themselves out of the cache
+func credentialsHash(credentials ...string) string {
+	hash := sha256.New()
+	for _, credential := range credentials {
+		hash.Write([]byte(credential))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// get returns an unexpired listing
+func (c *listingCache) get(key string) (*db.GraphDBResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Since(entry.fetchedAt) > c.ttl {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.listing, true
+}
+
+// put stores a listing
+func (c *listingCache) put(key string, listing *db.GraphDBResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cachedListing{listing: listing, fetchedAt: time.Now()}
+}
+
+// invalidateServer drops the repository listing and all graph listings of a
+// server, for any user
+func (c *listingCache) invalidateServer(serverURL string) {
+	prefix := normalizeURL(serverURL) + "|"
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// invalidateGraphs drops the graph listings of a repository, for any user.
+// Without a repository the whole server is dropped.
+func (c *listingCache) invalidateGraphs(serverURL, repo string) {
+	if repo == "" {
+		c.invalidateServer(serverURL)
+		return
+	}
+	prefix := normalizeURL(serverURL) + "|"
+	suffix := "|" + repo
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) && strings.HasSuffix(key, suffix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// invalidateTask drops the listings a finished task may have changed: the
+// whole server for repository actions, otherwise the graphs of the src and tgt
+// repositories. It runs for failed tasks as well, which may have changed part.
+func (c *listingCache) invalidateTask(task Task) {
+	for _, repo := range []*Repository{task.Src, task.Tgt} {
+		switch {
+		case repo == nil:
+		case strings.HasPrefix(task.Action, "repo-"):
+			c.invalidateServer(repo.URL)
+		default:
+			c.invalidateGraphs(repo.URL, repo.Repo)
+		}
+	}
+}
+
+// listingCacheContextKey stores the listing cache of a request in a task context
+type listingCacheContextKey struct{}
+
+// withListingCache returns a copy of ctx whose tasks share cache. A nil cache
+// leaves ctx unchanged.
+func withListingCache(ctx context.Context, cache *listingCache) context.Context {
+	if cache == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, listingCacheContextKey{}, cache)
+}
+
+// listingCacheFromContext returns the listing cache of a request, or nil
+func listingCacheFromContext(ctx context.Context) *listingCache {
+	cache, _ := ctx.Value(listingCacheContextKey{}).(*listingCache)
+	return cache
+}
+
+// taskListings is the view of a task on the listing cache. The first listing
+// of a server or repository in a task may come from the cache; later listings
+// in the same task are always fetched, as tasks list again to see their own
+// changes.
+type taskListings struct {
+	cache  *listingCache
+	task   Task
+	mu     sync.Mutex
+	listed map[string]bool
+}
+
+func newTaskListings(cache *listingCache, task Task) *taskListings {
+	if cache == nil {
+		return nil
+	}
+	return &taskListings{cache: cache, task: task, listed: make(map[string]bool)}
+}
+
+// credentials returns the hash of everything a listing request of the task to
+// serverURL authenticates with: the password and, as withTaskAuth sends them,
+// the token and extra headers of the repository on that server
+func (t *taskListings) credentials(serverURL, password string) string {
+	credentials := []string{password}
+	for _, repo := range []*Repository{t.task.Tgt, t.task.Src} {
+		if repo == nil || repo.URL == "" || (repo.Token == "" && len(repo.Headers) == 0) || normalizeURL(repo.URL) != normalizeURL(serverURL) {
+			continue
+		}
+		credentials = append(credentials, repo.AuthType, repo.Token)
+		for _, name := range sortedMapKeys(repo.Headers) {
+			credentials = append(credentials, name, repo.Headers[name])
+		}
+		break
+	}
+	return credentialsHash(credentials...)
+}
+
+// get returns the listing for key from the cache on the first call of the
+// task, and from fetch otherwise. Fetched listings are cached; errors are not.
+func (t *taskListings) get(key string, fetch func() (*db.GraphDBResponse, error)) (*db.GraphDBResponse, error) {
+	t.mu.Lock()
+	first := !t.listed[key]
+	t.listed[key] = true
+	t.mu.Unlock()
+
+	if first {
+		if listing, ok := t.cache.get(key); ok {
+			return listing, nil
+		}
+	}
+	listing, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	t.cache.put(key, listing)
+	return listing, nil
+}
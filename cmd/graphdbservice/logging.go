@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// serviceLog is the structured logger for task processing. It is configured by
+// initLogging from LOG_LEVEL and LOG_FORMAT when the service starts.
+var serviceLog = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+// initLogging configures serviceLog.
+//
+// LOG_LEVEL selects the minimum level (debug, info, warn, error; default info),
+// debug mode always enables debug output. LOG_FORMAT selects "json" (default) for
+// log aggregators or "text" for human-readable console output.
+func initLogging(debug bool) {
+	level := slog.LevelInfo
+	switch strings.ToLower(os.Getenv("LOG_LEVEL")) {
+	case "debug":
+		level = slog.LevelDebug
+	case "warn", "warning":
+		level = slog.LevelWarn
+	case "error":
+		level = slog.LevelError
+	}
+	if debug {
+		level = slog.LevelDebug
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler = slog.NewJSONHandler(os.Stdout, opts)
+	if strings.EqualFold(os.Getenv("LOG_FORMAT"), "text") {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+	serviceLog = slog.New(handler).With("service", "graphdbservice")
+}
+
+// taskLogger returns a logger carrying the identifying fields of a task
+func taskLogger(task Task, taskIndex int) *slog.Logger {
+	log := serviceLog.With("action", task.Action, "task_index", taskIndex)
+	if task.Tgt != nil && task.Tgt.Repo != "" {
+		log = log.With("repo", task.Tgt.Repo)
+	} else if task.Src != nil && task.Src.Repo != "" {
+		log = log.With("repo", task.Src.Repo)
+	}
+	return log
+}
@@ -0,0 +1,798 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// migrationHandler is the HTTP handler for the task based /v1/api/action endpoint.
+// It routes requests to either the JSON or multipart handler based on the Content-Type header.
+//
+// The multipart format is required when uploading configuration files or RDF data files
+// along with the task definitions.
+//
+// @Summary Execute GraphDB tasks
+// @Description Execute a MigrationRequest (version + tasks) sequentially or in parallel
+// @Tags Migration
+// @Accept json,multipart/form-data
+// @Produce json,application/ld+json
+// @Param x-api-key header string true "API Key"
+// @Success 200 {object} map[string]interface{} "Tasks executed successfully"
+// @Success 207 {object} map[string]interface{} "Some tasks failed (JSON-LD responses only)"
+// @Success 202 {object} map[string]interface{} "Tasks accepted for asynchronous execution (callback_url set)"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Task execution failed"
+// @Failure 502 {object} map[string]string "GraphDB server unreachable"
+// @Security ApiKeyAuth
+// @Router /v1/api/action [post]
+func migrationHandler(c echo.Context) error {
+	contentType := c.Request().Header.Get("Content-Type")
+
+	debugLog("Received request with Content-Type: %s", contentType)
+
+	if contentType != "" && strings.HasPrefix(contentType, "multipart/form-data") {
+		return migrationHandlerMultipart(c)
+	}
+	return migrationHandlerJSON(c)
+}
+
+// migrationHandlerJSON processes tasks submitted as a JSON MigrationRequest.
+// Sequential requests stop at the first failing task, parallel requests run all
+// tasks. Results are ordered by task index; see migrationResponse for the status
+// of a request with failed tasks.
+//
+// With callback_url set the request is answered with 202 Accepted and a session ID,
+// the tasks run in the background and the results are POSTed to the callback URL.
+//
+// The body is first checked against the request schema (GET /v1/api/schema);
+// all violations are returned at once before the tasks are validated.
+//
+// With "Accept: application/ld+json" the results are returned as Schema.org
+// ItemList of actions like the semantic API (see migrationResponseJSONLD).
+func migrationHandlerJSON(c echo.Context) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Failed to read request body")
+	}
+	if violations := validateRequestSchema(body); len(violations) > 0 {
+		return requestSchemaViolationError(c, violations)
+	}
+
+	var req MigrationRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := validateMigrationRequest(&req); err != nil {
+		return err
+	}
+
+	if req.CallbackURL != "" {
+		slot, err := admitMigrationSession()
+		if err != nil {
+			return err
+		}
+		sessionID := startMigrationSession(c, req)
+		// Persisted before the session can leave the queue, which removes it again
+		position := slot.position()
+		if position > 0 {
+			persistQueuedSession(sessionID, apiKeyLabel(c), req)
+		}
+		go runAsyncMigration(sessionID, apiKeyLabel(c), req, slot)
+		if wantsJSONLD(c) {
+			return writeJSONLD(c, http.StatusAccepted, map[string]interface{}{
+				"@context":     "https://schema.org",
+				"@type":        "ItemList",
+				"identifier":   sessionID,
+				"version":      req.Version,
+				"actionStatus": "ActiveActionStatus",
+				"totalItems":   len(req.Tasks),
+			})
+		}
+		response := map[string]interface{}{
+			"status":     "accepted",
+			"version":    req.Version,
+			"session_id": sessionID,
+			"tasks":      len(req.Tasks),
+		}
+		if position > 0 {
+			response["queue_position"] = position
+		}
+		return c.JSON(http.StatusAccepted, response)
+	}
+
+	slot, err := admitMigrationSession()
+	if err != nil {
+		return err
+	}
+	sessionID := startMigrationSession(c, req)
+	results, errs := executeMigrationTasks(req, nil, !req.Parallel, sessionID, slot)
+	finishMigrationSession(sessionID)
+	if wantsJSONLD(c) {
+		return migrationResponseJSONLD(c, req, sessionID, results, errs)
+	}
+	return migrationResponse(c, req, sessionID, results, errs)
+}
+
+// Overall status of a request in responses and callbacks
+const (
+	migrationStatusSuccess = "success"
+	migrationStatusPartial = "partial"
+	migrationStatusFailed  = "failed"
+	// Result status of the tasks after a failed task of a sequential request
+	taskStatusNotRun = "not_run"
+)
+
+// defaultTaskConcurrency is the number of task groups of a parallel request
+// run at once when the request sets no concurrency
+const defaultTaskConcurrency = 4
+
+// migrationStatus summarizes the outcome of the tasks of a request: success if
+// every task succeeded, failed if none did and partial otherwise. Tasks that
+// were not run count as not succeeded. A cancelled session without failed tasks
+// is cancelled. The HTTP status follows the JSON-LD responses: 200, 207
+// Multi-Status or 500, and 200 for a cancelled session.
+func migrationStatus(results []map[string]interface{}, errs []error) (string, int) {
+	successful, failed := 0, 0
+	for i, err := range errs {
+		switch {
+		case errors.Is(err, errTaskCancelled):
+		case err != nil:
+			failed++
+		case results[i] != nil && results[i]["status"] != taskStatusNotRun:
+			successful++
+		}
+	}
+	switch {
+	case successful == len(errs):
+		return migrationStatusSuccess, http.StatusOK
+	case failed == 0:
+		// Tasks are only not run after a failed task, so the others were cancelled
+		return sessionStatusCancelled, http.StatusOK
+	case successful == 0:
+		return migrationStatusFailed, http.StatusInternalServerError
+	}
+	return migrationStatusPartial, http.StatusMultiStatus
+}
+
+// migrationResponse writes the results of a synchronous request with the
+// overall status of migrationStatus. Every result carries the status of its
+// task, and failed tasks their error; the results of the other tasks are kept.
+func migrationResponse(c echo.Context, req MigrationRequest, sessionID string, results []map[string]interface{}, errs []error) error {
+	status, statusCode := migrationStatus(results, errs)
+	response := map[string]interface{}{
+		"status":     status,
+		"version":    req.Version,
+		"session_id": sessionID,
+		"results":    results,
+	}
+	var errorMessages []string
+	for i, err := range errs {
+		if err != nil {
+			errorMessages = append(errorMessages, fmt.Sprintf("Task %d failed: %s", i, err.Error()))
+		}
+	}
+	if len(errorMessages) > 0 {
+		response["errors"] = errorMessages
+	}
+	return c.JSON(statusCode, response)
+}
+
+// migrationHandlerMultipart processes tasks submitted as multipart form data.
+//
+// The multipart form must contain:
+//   - "request" field: JSON string containing the MigrationRequest object
+//   - "task_{index}_config" field: Configuration file for task at index (for repo-create)
+//   - "task_{index}_files" field: RDF data files for task at index (for graph-import/repo-import)
+//
+// Failed tasks are reported in the results instead of aborting the request.
+// Results are returned as JSON-LD on request, as for JSON requests.
+func migrationHandlerMultipart(c echo.Context) error {
+	if err := parseMultipartForm(c, "Failed to parse multipart form"); err != nil {
+		return err
+	}
+
+	form := c.Request().MultipartForm
+	if form == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "No multipart form data found")
+	}
+	defer func() { _ = form.RemoveAll() }()
+
+	jsonFields, exists := form.Value["request"]
+	if !exists || len(jsonFields) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Missing 'request' field in form data")
+	}
+
+	if violations := validateRequestSchema([]byte(jsonFields[0])); len(violations) > 0 {
+		return requestSchemaViolationError(c, violations)
+	}
+
+	var req MigrationRequest
+	if err := json.Unmarshal([]byte(jsonFields[0]), &req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid JSON in request field: "+err.Error())
+	}
+
+	// Uploaded files are checked before validateMigrationRequest contacts the servers
+	if err := validateUploadedFiles(req, form.File); err != nil {
+		return err
+	}
+	if err := validateMigrationRequest(&req); err != nil {
+		return err
+	}
+	// Uploaded files only live as long as the request, so they cannot be processed in the background
+	if req.CallbackURL != "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "callback_url is not supported for multipart requests")
+	}
+
+	files := make(map[string][]*multipart.FileHeader)
+	for key, fileHeaders := range form.File {
+		files[key] = fileHeaders
+	}
+
+	slot, err := admitMigrationSession()
+	if err != nil {
+		return err
+	}
+	sessionID := startMigrationSession(c, req)
+	results, errs := executeMigrationTasks(req, files, false, sessionID, slot)
+	finishMigrationSession(sessionID)
+	if wantsJSONLD(c) {
+		return migrationResponseJSONLD(c, req, sessionID, results, errs)
+	}
+	return migrationResponse(c, req, sessionID, results, errs)
+}
+
+// validationMessage is one problem found by POST /v1/api/validate. Field names
+// the offending task field (e.g. "tgt.repo") when the problem is tied to one.
+type validationMessage struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+	// Line, Column and Snippet locate a JSON syntax error in the body
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// taskValidationResult is the validation outcome of one task of a request
+type taskValidationResult struct {
+	Index  int                 `json:"index"`
+	Action string              `json:"action"`
+	Valid  bool                `json:"valid"`
+	Errors []validationMessage `json:"errors,omitempty"`
+}
+
+// validateRequestHandler handles POST /v1/api/validate
+//
+// It accepts the same JSON or multipart body as POST /v1/api/action and reports
+// for every task whether it is valid, without contacting any GraphDB server.
+// Multipart requests are also checked for the file keys each task requires.
+// A body that does not match the request schema is reported with all schema
+// violations and without per-task results.
+func validateRequestHandler(c echo.Context) error {
+	var req MigrationRequest
+	var body []byte
+	var files map[string][]*multipart.FileHeader
+	multipartRequest := strings.HasPrefix(c.Request().Header.Get("Content-Type"), "multipart/form-data")
+
+	if multipartRequest {
+		if err := parseMultipartForm(c, "Failed to parse multipart form"); err != nil {
+			return err
+		}
+		form := c.Request().MultipartForm
+		if form == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "No multipart form data found")
+		}
+		defer func() { _ = form.RemoveAll() }()
+
+		jsonFields, exists := form.Value["request"]
+		if !exists || len(jsonFields) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Missing 'request' field in form data")
+		}
+		body = []byte(jsonFields[0])
+		files = form.File
+	} else {
+		var err error
+		if body, err = io.ReadAll(c.Request().Body); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Failed to read request body")
+		}
+	}
+
+	if violations := validateRequestSchema(body); len(violations) > 0 {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"valid":  false,
+			"errors": violations,
+			"tasks":  []taskValidationResult{},
+		})
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	requestErrors := []validationMessage{}
+	if version, err := normalizeAPIVersion(req.Version); err != nil {
+		requestErrors = append(requestErrors, validationMessage{Field: "version", Message: err.Error()})
+	} else {
+		req.Version = version
+	}
+	if req.CallbackURL != "" {
+		if multipartRequest {
+			requestErrors = append(requestErrors, validationMessage{Field: "callback_url", Message: "callback_url is not supported for multipart requests"})
+		} else if err := validateCallbackURL(req.CallbackURL); err != nil {
+			requestErrors = append(requestErrors, validationMessage{Field: "callback_url", Message: err.Error()})
+		}
+	}
+
+	valid := len(requestErrors) == 0
+	tasks := make([]taskValidationResult, len(req.Tasks))
+	for i, task := range req.Tasks {
+		tasks[i] = validateTaskForRequest(task, i, files, multipartRequest)
+		valid = valid && tasks[i].Valid
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"valid":   valid,
+		"version": req.Version,
+		"errors":  requestErrors,
+		"tasks":   tasks,
+	})
+}
+
+// validateTaskForRequest validates one task of a validation request. File keys
+// are only checked when checkFiles is set, as JSON requests cannot upload files.
+func validateTaskForRequest(task Task, taskIndex int, files map[string][]*multipart.FileHeader, checkFiles bool) taskValidationResult {
+	result := taskValidationResult{Index: taskIndex, Action: task.Action, Valid: true}
+
+	if err := validateTask(task); err != nil {
+		message := validationMessage{Message: err.Error()}
+		var fieldErr *taskFieldError
+		if errors.As(err, &fieldErr) {
+			message.Field = fieldErr.Field
+		}
+		result.Errors = append(result.Errors, message)
+	} else if spec, ok := actionHandlers[task.Action].(*actionSpec); ok && checkFiles {
+		for _, key := range spec.missingFiles(task, taskIndex, files) {
+			result.Errors = append(result.Errors, validationMessage{Field: key, Message: fmt.Sprintf("file '%s' is required for %s", key, task.Action)})
+		}
+		for _, fieldErr := range spec.invalidFiles(taskIndex, files) {
+			result.Errors = append(result.Errors, validationMessage{Field: fieldErr.Field, Message: fieldErr.Message})
+		}
+	}
+
+	result.Valid = len(result.Errors) == 0
+	return result
+}
+
+// validateUploadedFiles checks the content of the files uploaded for the tasks
+// of a multipart request, e.g. that repo-create configs are repository
+// configurations, and lists all invalid files in one error
+func validateUploadedFiles(req MigrationRequest, files map[string][]*multipart.FileHeader) error {
+	var problems []string
+	for i, task := range req.Tasks {
+		spec, ok := actionHandlers[task.Action].(*actionSpec)
+		if !ok {
+			continue
+		}
+		for _, fieldErr := range spec.invalidFiles(i, files) {
+			problems = append(problems, fmt.Sprintf("Task %d: %s", i, fieldErr.Message))
+		}
+	}
+	if len(problems) > 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateMigrationRequest checks the request envelope, every task and, unless
+// skipped, that all referenced GraphDB servers are reachable. The version of
+// req is normalized, see normalizeAPIVersion.
+func validateMigrationRequest(req *MigrationRequest) error {
+	version, err := normalizeAPIVersion(req.Version)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	req.Version = version
+	if len(req.Tasks) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "At least one task is required")
+	}
+	for i, task := range req.Tasks {
+		if err := validateTask(task); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Task %d: %s", i, err.Error()))
+		}
+	}
+	if req.CallbackURL != "" {
+		if err := validateCallbackURL(req.CallbackURL); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+	if req.TempDir != "" {
+		dir, err := resolveRequestTempDir(req.TempDir)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		req.TempDir = dir
+	}
+
+	if !req.SkipPreflight {
+		if err := preflightTaskServers(req.Tasks); err != nil {
+			if errors.Is(err, ErrAuth) {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			return echo.NewHTTPError(http.StatusBadGateway, err.Error())
+		}
+	}
+	return nil
+}
+
+// validateTask validates that a task has the correct structure and required fields
+// based on its action handler. This prevents invalid requests from being processed.
+func validateTask(task Task) error {
+	handler, ok := actionHandlers[task.Action]
+	if !ok {
+		return fmt.Errorf("invalid action: %s", task.Action)
+	}
+	if err := validateRepositoryAuth("src", task.Src); err != nil {
+		return err
+	}
+	if err := validateRepositoryAuth("tgt", task.Tgt); err != nil {
+		return err
+	}
+	if err := validateGraphIRIs("src", task.Src); err != nil {
+		return err
+	}
+	if err := validateGraphIRIs("tgt", task.Tgt); err != nil {
+		return err
+	}
+	if task.Src != nil && task.Tgt != nil && normalizeURL(task.Src.URL) == normalizeURL(task.Tgt.URL) &&
+		task.Src.authorizationHeader() != task.Tgt.authorizationHeader() {
+		return &taskFieldError{Field: "tgt.token", Message: "src and tgt on the same server must use the same token"}
+	}
+	if task.Src != nil && task.Tgt != nil && normalizeURL(task.Src.URL) == normalizeURL(task.Tgt.URL) &&
+		!maps.Equal(task.Src.Headers, task.Tgt.Headers) {
+		return &taskFieldError{Field: "tgt.headers", Message: "src and tgt on the same server must use the same headers"}
+	}
+	return handler.Validate(task)
+}
+
+// taskServerEndpoints returns the src and tgt repositories of the tasks with
+// distinct servers and credentials
+func taskServerEndpoints(tasks []Task) []*Repository {
+	seen := make(map[string]bool)
+	var endpoints []*Repository
+	for _, task := range tasks {
+		for _, repo := range []*Repository{task.Src, task.Tgt} {
+			if repo == nil || repo.URL == "" {
+				continue
+			}
+			key := strings.Join([]string{normalizeURL(repo.URL), repo.Username, repo.Password, repo.authorizationHeader(), fmt.Sprint(repo.Headers)}, "\x00")
+			if !seen[key] {
+				seen[key] = true
+				endpoints = append(endpoints, repo)
+			}
+		}
+	}
+	return endpoints
+}
+
+// taskTargetKey identifies the target repository of a task. Tasks with the same
+// key are never run concurrently to avoid contention on the GraphDB repository.
+func taskTargetKey(task Task) string {
+	if task.Tgt == nil {
+		return ""
+	}
+	repo := task.Tgt.Repo
+	if repo == "" {
+		repo = task.Tgt.RepoOld
+	}
+	return normalizeURL(task.Tgt.URL) + "|" + repo
+}
+
+// startMigrationSession starts a MigrationLogger session for a request and returns
+// its ID. Without session logging a random ID is returned for correlation.
+func startMigrationSession(c echo.Context, req MigrationRequest) string {
+	if migrationLogger == nil {
+		return uuid.New().String()
+	}
+
+	// API traffic is attributed to the label of the API key used
+	username := apiKeyLabel(c)
+	if username == "" {
+		username = "api"
+	}
+	requestJSON, _ := json.Marshal(redactRequest(req))
+	session, err := migrationLogger.StartSession("api", username, c.RealIP(), c.Request().UserAgent(), len(req.Tasks), string(requestJSON))
+	if err != nil {
+		serviceLog.Warn("Failed to persist migration session", "error", err)
+	}
+	return session.ID
+}
+
+// admitMigrationSession reserves a slot for a new session in migrationSessions,
+// answering 503 when the running sessions and the queue are full
+func admitMigrationSession() (*sessionSlot, error) {
+	slot, err := migrationSessions.admit()
+	if err != nil {
+		running, queued := migrationSessions.counts()
+		serviceLog.Warn("Migration session rejected", "running", running, "queued", queued)
+		return nil, echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+	return slot, nil
+}
+
+// setSessionStatus records the status of a logged session
+func setSessionStatus(sessionID, status string) {
+	if migrationLogger == nil || sessionID == "" {
+		return
+	}
+	if err := migrationLogger.SetSessionStatus(sessionID, status); err != nil {
+		serviceLog.Warn("Failed to log session status", "session_id", sessionID, "status", status, "error", err)
+	}
+}
+
+// redactRequest returns a copy of req with all GraphDB passwords, tokens and URL
+// credentials masked, so the request can be stored with its session
+func redactRequest(req MigrationRequest) MigrationRequest {
+	tasks := make([]Task, len(req.Tasks))
+	for i, task := range req.Tasks {
+		task.Src = task.Src.redacted()
+		task.Tgt = task.Tgt.redacted()
+		tasks[i] = task
+	}
+	req.Tasks = tasks
+	req.CallbackURL = redactURL(req.CallbackURL)
+	return req
+}
+
+// finishMigrationSession completes the session of a request once all its tasks
+// ran. The session is marked failed if any task failed.
+func finishMigrationSession(sessionID string) {
+	if migrationLogger == nil {
+		return
+	}
+	if err := migrationLogger.CompleteSession(sessionID); err != nil {
+		serviceLog.Warn("Failed to complete migration session", "session_id", sessionID, "error", err)
+	}
+}
+
+// taskResultMetrics extracts the data size and triple count reported by a task result
+func taskResultMetrics(result map[string]interface{}) (dataSize, tripleCount int64) {
+	toInt64 := func(value interface{}) int64 {
+		switch v := value.(type) {
+		case int64:
+			return v
+		case int:
+			return int64(v)
+		}
+		return 0
+	}
+
+	dataSize = toInt64(result["data_size"])
+	for _, key := range []string{"tgt_triples", "imported_triples", "merged_triples"} {
+		if count := toInt64(result[key]); count > 0 {
+			tripleCount = count
+			break
+		}
+	}
+	return dataSize, tripleCount
+}
+
+// executeMigrationTasks runs the tasks of a request and returns their results and
+// errors ordered by task index. Failed tasks get a result with status "failed".
+//
+// With req.Parallel set, tasks are grouped by target repository: groups run
+// concurrently (limited by req.Concurrency), tasks within a group run in order.
+// Otherwise tasks run sequentially and stopOnError aborts at the first failure.
+//
+// If sessionID is set, task start and outcome are recorded in that MigrationLogger session.
+//
+// The session can be cancelled with POST /v1/api/sessions/:id/cancel while the
+// tasks run; tasks not started by then get a result with status "cancelled".
+//
+// slot is the admission of the session by migrationSessions and is released
+// when the tasks finished. A queued slot is waited for first, with the session
+// recorded as queued; a nil slot runs without limit. A queued session
+// suspended by the shutdown returns errSessionSuspended for every task.
+func executeMigrationTasks(req MigrationRequest, files map[string][]*multipart.FileHeader, stopOnError bool, sessionID string, slot *sessionSlot) ([]map[string]interface{}, []error) {
+	results := make([]map[string]interface{}, len(req.Tasks))
+	errs := make([]error, len(req.Tasks))
+	logSession := migrationLogger != nil && sessionID != ""
+
+	ctx, control, finish := startSessionControl(sessionID)
+	defer finish()
+	if logSession {
+		ctx = withSessionID(ctx, sessionID)
+	}
+	if req.TempDir != "" {
+		ctx = withTempDir(ctx, req.TempDir)
+	}
+	// Tasks of the request share repository and graph listings for a few seconds
+	if ttl := listingCacheTTL(); ttl > 0 {
+		ctx = withListingCache(ctx, newListingCache(ttl))
+	}
+
+	cancelTask := func(i int, log *slog.Logger, err error) {
+		if logSession {
+			if logErr := migrationLogger.CancelTask(sessionID, i, req.Tasks[i].Action); logErr != nil {
+				log.Warn("Failed to log task cancellation", "session_id", sessionID, "error", logErr)
+			}
+		}
+		errs[i] = err
+		results[i] = map[string]interface{}{
+			"action": req.Tasks[i].Action,
+			"status": sessionStatusCancelled,
+			"error":  err.Error(),
+		}
+	}
+
+	// A queued session waits for a free slot; cancelling it removes it from the
+	// queue. At shutdown a queued callback session is suspended instead and
+	// resumed at the next start.
+	defer slot.release()
+	if slot.waits() {
+		control.slot.Store(slot)
+		control.queued.Store(true)
+		setSessionStatus(sessionID, sessionStatusQueued)
+		serviceLog.Info("Migration session queued", "session_id", sessionID, "position", slot.position())
+		err := slot.wait(ctx)
+		control.queued.Store(false)
+		if err != nil && suspendSession(sessionID) {
+			for i := range errs {
+				errs[i] = errSessionSuspended
+			}
+			return results, errs
+		}
+		dequeueSession(sessionID)
+		if err != nil {
+			for i, task := range req.Tasks {
+				cancelTask(i, taskLogger(task, i), errTaskCancelled)
+			}
+			return results, errs
+		}
+		setSessionStatus(sessionID, sessionStatusRunning)
+	}
+
+	runTask := func(i int) {
+		task := req.Tasks[i]
+		log := taskLogger(task, i)
+		if control.stopped.Load() {
+			log.Info("Task skipped, session cancelled")
+			cancelTask(i, log, errTaskCancelled)
+			return
+		}
+		debugLog("Processing task %d: %s", i, task.Action)
+
+		if logSession {
+			var srcURL, tgtURL, repoID, graphID string
+			if task.Src != nil {
+				srcURL, repoID, graphID = task.Src.URL, task.Src.Repo, task.Src.Graph
+			}
+			if task.Tgt != nil {
+				tgtURL = task.Tgt.URL
+				if repoID == "" {
+					repoID, graphID = task.Tgt.Repo, task.Tgt.Graph
+				}
+			}
+			if err := migrationLogger.StartTask(sessionID, i, task.Action, srcURL, tgtURL, repoID, graphID); err != nil {
+				log.Warn("Failed to log task start", "session_id", sessionID, "error", err)
+			}
+		}
+
+		result, err := func() (result map[string]interface{}, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("panic: %v", r)
+				}
+			}()
+			if logSession {
+				return processTaskContext(ctx, task, files, i, migrationLogger.sessionProgress(sessionID, i))
+			}
+			return processTaskContext(ctx, task, files, i, nil)
+		}()
+		if err == nil && result == nil {
+			err = fmt.Errorf("task returned no result")
+		}
+
+		if errors.Is(err, errTaskCancelled) {
+			log.Warn("Task cancelled", "error", err)
+			cancelTask(i, log, err)
+			return
+		}
+		if err != nil {
+			log.Error("Task failed", "error", err)
+			if logSession {
+				if logErr := migrationLogger.FailTask(sessionID, i, taskErrorType(err), err.Error(), 0); logErr != nil {
+					log.Warn("Failed to log task failure", "session_id", sessionID, "error", logErr)
+				}
+			}
+			errs[i] = err
+			results[i] = map[string]interface{}{
+				"action":     task.Action,
+				"status":     "failed",
+				"error":      err.Error(),
+				"error_type": taskErrorType(err),
+			}
+			return
+		}
+		skipped, _ := result["skipped"].(bool)
+		if _, ok := result["status"]; !ok {
+			result["status"] = sessionStatusCompleted
+			if skipped {
+				result["status"] = sessionStatusSkipped
+			}
+		}
+		if logSession && skipped {
+			if err := migrationLogger.SkipTask(sessionID, i); err != nil {
+				log.Warn("Failed to log task completion", "session_id", sessionID, "error", err)
+			}
+		} else if logSession {
+			dataSize, tripleCount := taskResultMetrics(result)
+			timings, _ := result["timings"].(map[string]int64)
+			if err := migrationLogger.CompleteTask(sessionID, i, dataSize, tripleCount, timings); err != nil {
+				log.Warn("Failed to log task completion", "session_id", sessionID, "error", err)
+			}
+		}
+		results[i] = result
+	}
+
+	if !req.Parallel {
+		for i := range req.Tasks {
+			runTask(i)
+			// Remaining tasks of a cancelled session are still visited to report them cancelled
+			if stopOnError && errs[i] != nil && !errors.Is(errs[i], errTaskCancelled) {
+				for j := i + 1; j < len(req.Tasks); j++ {
+					results[j] = map[string]interface{}{"action": req.Tasks[j].Action, "status": taskStatusNotRun}
+				}
+				break
+			}
+		}
+		return results, errs
+	}
+
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultTaskConcurrency
+	}
+
+	// Group tasks by target repository, keeping the request order within each group
+	var groupKeys []string
+	groups := make(map[string][]int)
+	for i, task := range req.Tasks {
+		key := taskTargetKey(task)
+		if _, exists := groups[key]; !exists {
+			groupKeys = append(groupKeys, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	debugLog("Executing %d tasks in %d groups in PARALLEL (concurrency: %d)", len(req.Tasks), len(groupKeys), concurrency)
+
+	sem := make(chan struct{}, concurrency)
+	done := make(chan struct{}, len(groupKeys))
+	for _, key := range groupKeys {
+		go func(indexes []int) {
+			sem <- struct{}{}
+			defer func() {
+				<-sem
+				done <- struct{}{}
+			}()
+			for _, i := range indexes {
+				runTask(i)
+			}
+		}(groups[key])
+	}
+	for range groupKeys {
+		<-done
+	}
+
+	return results, errs
+}
@@ -0,0 +1,662 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session and task states recorded by the MigrationLogger
+const (
+	sessionStatusQueued    = "queued"
+	sessionStatusRunning   = "running"
+	sessionStatusCompleted = "completed"
+	sessionStatusFailed    = "failed"
+	sessionStatusCancelled = "cancelled"
+	// Sessions and tasks still running when the service shut down
+	sessionStatusInterrupted = "interrupted"
+	// Tasks that succeeded without changes, e.g. graph-import with skip_unchanged
+	sessionStatusSkipped = "skipped"
+)
+
+// errSessionNotFound is returned for session IDs that are neither active nor on disk
+var errSessionNotFound = errors.New("migration session not found")
+
+// migrationLogger records migration sessions. It is nil when session logging is disabled.
+var migrationLogger *MigrationLogger
+
+// MigrationTask is the audit record of a single task within a migration session
+type MigrationTask struct {
+	Index        int           `json:"index"`
+	Action       string        `json:"action"`
+	SrcURL       string        `json:"src_url,omitempty"`
+	TgtURL       string        `json:"tgt_url,omitempty"`
+	RepoID       string        `json:"repo_id,omitempty"`
+	GraphID      string        `json:"graph_id,omitempty"`
+	Status       string        `json:"status"`
+	StartTime    time.Time     `json:"start_time"`
+	EndTime      *time.Time    `json:"end_time,omitempty"`
+	DurationMs   int64         `json:"duration_ms,omitempty"`
+	DataSize     int64         `json:"data_size_bytes,omitempty"`
+	TripleCount  int64         `json:"triple_count,omitempty"`
+	ErrorType    string        `json:"error_type,omitempty"`
+	ErrorMessage string        `json:"error_message,omitempty"`
+	RetryCount   int           `json:"retry_count,omitempty"` // Retried GraphDB requests, see retryHTTPTransport
+	Progress     *TaskProgress `json:"progress,omitempty"`
+	// Files are the uploaded files imported by graph-import and repo-import.
+	// ContentHash is the hash over all files of a graph-import with
+	// skip_unchanged; both use the HashAlgorithm of FILE_HASH_ALGORITHM.
+	Files         []TaskFile `json:"files,omitempty"`
+	ContentHash   string     `json:"content_hash,omitempty"`
+	HashAlgorithm string     `json:"hash_algorithm,omitempty"`
+	// Timings are the durations of the steps of the task in milliseconds, for
+	// repo-migration, graph-migration and repo-rename
+	Timings map[string]int64 `json:"timings,omitempty"`
+}
+
+// TaskFile identifies an imported file by its name, size and content hash
+type TaskFile struct {
+	Name string `json:"name"`
+	Size int64  `json:"size_bytes"`
+	Hash string `json:"hash"`
+}
+
+// TaskProgress is the last progress reported by a running multi-step task.
+// Total is 0 while it is not known, e.g. for imports through the GraphDB import API.
+type TaskProgress struct {
+	Stage   string `json:"stage"`
+	Current int    `json:"current"`
+	Total   int    `json:"total"`
+}
+
+// MigrationSession is the audit record of one submitted MigrationRequest
+type MigrationSession struct {
+	ID             string            `json:"id"`
+	UserID         string            `json:"user_id,omitempty"`
+	Username       string            `json:"username,omitempty"`
+	IPAddress      string            `json:"ip_address,omitempty"`
+	UserAgent      string            `json:"user_agent,omitempty"`
+	Status         string            `json:"status"`
+	StartTime      time.Time         `json:"start_time"`
+	EndTime        *time.Time        `json:"end_time,omitempty"`
+	DurationMs     int64             `json:"duration_ms,omitempty"`
+	TotalTasks     int               `json:"total_tasks"`
+	CompletedTasks int               `json:"completed_tasks"`
+	FailedTasks    int               `json:"failed_tasks"`
+	CancelledTasks int               `json:"cancelled_tasks,omitempty"`
+	SkippedTasks   int               `json:"skipped_tasks,omitempty"`
+	TotalDataSize  int64             `json:"total_data_size_bytes"`
+	ErrorMessage   string            `json:"error_message,omitempty"`
+	Tasks          []MigrationTask   `json:"tasks"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	// QueuePosition is the place of a queued session in the queue, 1 for the
+	// session started next. It is not persisted.
+	QueuePosition int `json:"queue_position,omitempty"`
+}
+
+// MigrationSessionSummary is the lightweight view of a session used for listings
+type MigrationSessionSummary struct {
+	ID             string     `json:"id"`
+	Username       string     `json:"username,omitempty"`
+	Status         string     `json:"status"`
+	StartTime      time.Time  `json:"start_time"`
+	EndTime        *time.Time `json:"end_time,omitempty"`
+	DurationMs     int64      `json:"duration_ms,omitempty"`
+	TotalTasks     int        `json:"total_tasks"`
+	CompletedTasks int        `json:"completed_tasks"`
+	FailedTasks    int        `json:"failed_tasks"`
+	CancelledTasks int        `json:"cancelled_tasks,omitempty"`
+	SkippedTasks   int        `json:"skipped_tasks,omitempty"`
+	TotalDataSize  int64      `json:"total_data_size_bytes"`
+}
+
+// sessionRequestKey is the Metadata key holding the request JSON of a session
+const sessionRequestKey = "request_json"
+
+// summaryFileName is the per day file holding the summaries of that day's sessions
+const summaryFileName = "summaries.json"
+
+// MigrationLogger keeps running sessions in memory and persists every session
+// as JSON to <dir>/<YYYY-MM-DD>/<session-id>.json, dated by the session start.
+// Each day directory also holds a summaries.json index used for listings, so
+// paging through sessions does not load their full task details.
+type MigrationLogger struct {
+	dir       string
+	mu        sync.RWMutex
+	active    map[string]*MigrationSession
+	notifiers []Notifier
+	// retentionDays is MIGRATION_LOG_RETENTION_DAYS, 0 if sessions are kept forever
+	retentionDays int
+	// importMu guards the import hashes file, see RecordImportHash
+	importMu sync.Mutex
+}
+
+// NewMigrationLogger creates a logger storing sessions below dir
+func NewMigrationLogger(dir string) (*MigrationLogger, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create migration log directory %s: %w", dir, err)
+	}
+	return &MigrationLogger{
+		dir:    dir,
+		active: make(map[string]*MigrationSession),
+	}, nil
+}
+
+// StartSession registers a new running session and returns it. Credentials in
+// requestJSON are masked before it is stored.
+func (l *MigrationLogger) StartSession(userID, username, ipAddress, userAgent string, totalTasks int, requestJSON string) (*MigrationSession, error) {
+	if masked, err := redactRequestJSON(requestJSON); err == nil {
+		requestJSON = masked
+	} else {
+		requestJSON = redactText(requestJSON)
+	}
+
+	session := &MigrationSession{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		Username:   username,
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+		Status:     sessionStatusRunning,
+		StartTime:  time.Now().UTC(),
+		TotalTasks: totalTasks,
+		Tasks:      []MigrationTask{},
+		Metadata:   map[string]string{sessionRequestKey: requestJSON},
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active[session.ID] = session
+	if err := l.save(session); err != nil {
+		return session, err
+	}
+	return session, l.saveSummary(session)
+}
+
+// StartTask records the start of a task in a running session. Credentials in
+// the URLs are masked.
+func (l *MigrationLogger) StartTask(sessionID string, index int, action, srcURL, tgtURL, repoID, graphID string) error {
+	return l.update(sessionID, func(session *MigrationSession) error {
+		session.Tasks = append(session.Tasks, MigrationTask{
+			Index:     index,
+			Action:    action,
+			SrcURL:    redactURL(srcURL),
+			TgtURL:    redactURL(tgtURL),
+			RepoID:    repoID,
+			GraphID:   graphID,
+			Status:    sessionStatusRunning,
+			StartTime: time.Now().UTC(),
+		})
+		return nil
+	})
+}
+
+// CompleteTask marks a task of a running session as completed. timings may be nil.
+func (l *MigrationLogger) CompleteTask(sessionID string, index int, dataSize, tripleCount int64, timings map[string]int64) error {
+	return l.update(sessionID, func(session *MigrationSession) error {
+		task := session.task(index)
+		if task == nil {
+			return fmt.Errorf("task %d not started in session %s", index, sessionID)
+		}
+		task.finish(sessionStatusCompleted)
+		task.DataSize = dataSize
+		task.TripleCount = tripleCount
+		task.Timings = timings
+		session.CompletedTasks++
+		session.TotalDataSize += dataSize
+		return nil
+	})
+}
+
+// SkipTask marks a task of a running session as skipped: it succeeded without
+// changing anything. Skipped tasks count as completed as well.
+func (l *MigrationLogger) SkipTask(sessionID string, index int) error {
+	return l.update(sessionID, func(session *MigrationSession) error {
+		task := session.task(index)
+		if task == nil {
+			return fmt.Errorf("task %d not started in session %s", index, sessionID)
+		}
+		task.finish(sessionStatusSkipped)
+		session.CompletedTasks++
+		session.SkippedTasks++
+		return nil
+	})
+}
+
+// FailTask marks a task of a running session as failed
+func (l *MigrationLogger) FailTask(sessionID string, index int, errorType, errorMessage string, dataSize int64) error {
+	return l.update(sessionID, func(session *MigrationSession) error {
+		task := session.task(index)
+		if task == nil {
+			return fmt.Errorf("task %d not started in session %s", index, sessionID)
+		}
+		task.finish(sessionStatusFailed)
+		task.ErrorType = errorType
+		task.ErrorMessage = redactText(errorMessage)
+		task.DataSize = dataSize
+		session.FailedTasks++
+		session.TotalDataSize += dataSize
+		return nil
+	})
+}
+
+// SetSessionStatus changes the status of an active session, e.g. to queued
+// while it waits for a free slot and back to running once it starts
+func (l *MigrationLogger) SetSessionStatus(sessionID, status string) error {
+	return l.update(sessionID, func(session *MigrationSession) error {
+		session.Status = status
+		return l.saveSummary(session)
+	})
+}
+
+// CancelTask marks a task of a running session as cancelled. A task that was
+// not started yet is recorded as cancelled without running.
+func (l *MigrationLogger) CancelTask(sessionID string, index int, action string) error {
+	return l.update(sessionID, func(session *MigrationSession) error {
+		task := session.task(index)
+		if task == nil {
+			session.Tasks = append(session.Tasks, MigrationTask{Index: index, Action: action, StartTime: time.Now().UTC()})
+			task = &session.Tasks[len(session.Tasks)-1]
+		}
+		task.finish(sessionStatusCancelled)
+		session.CancelledTasks++
+		return nil
+	})
+}
+
+// UpdateTaskProgress records the progress of a running task. Progress is kept in
+// memory only, the final state of the task is persisted when it finishes.
+func (l *MigrationLogger) UpdateTaskProgress(sessionID string, index int, stage string, current, total int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	session, exists := l.active[sessionID]
+	if !exists {
+		return
+	}
+	if task := session.task(index); task != nil {
+		task.Progress = &TaskProgress{Stage: stage, Current: current, Total: total}
+	}
+}
+
+// SetTaskRetries records the number of retried GraphDB requests of a task of a
+// running session. It is saved with the task when the task finishes.
+func (l *MigrationLogger) SetTaskRetries(sessionID string, index, retries int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	session, exists := l.active[sessionID]
+	if !exists {
+		return
+	}
+	if task := session.task(index); task != nil {
+		task.RetryCount = retries
+	}
+}
+
+// AddTaskFile records a file imported by a task of a running session. It is
+// saved with the task when the task finishes.
+func (l *MigrationLogger) AddTaskFile(sessionID string, index int, file TaskFile, algorithm string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	session, exists := l.active[sessionID]
+	if !exists {
+		return
+	}
+	if task := session.task(index); task != nil {
+		task.Files = append(task.Files, file)
+		task.HashAlgorithm = algorithm
+	}
+}
+
+// SetTaskContentHash records the hash over all files of a task of a running
+// session. It is saved with the task when the task finishes.
+func (l *MigrationLogger) SetTaskContentHash(sessionID string, index int, hash, algorithm string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	session, exists := l.active[sessionID]
+	if !exists {
+		return
+	}
+	if task := session.task(index); task != nil {
+		task.ContentHash = hash
+		task.HashAlgorithm = algorithm
+	}
+}
+
+// sessionProgress returns a ProgressFunc reporting into a task of a session
+func (l *MigrationLogger) sessionProgress(sessionID string, index int) ProgressFunc {
+	return func(stage string, current, total int) {
+		l.UpdateTaskProgress(sessionID, index, stage, current, total)
+	}
+}
+
+// CompleteSession finishes a session. It is marked cancelled if any task was
+// cancelled, otherwise failed if any task failed.
+func (l *MigrationLogger) CompleteSession(sessionID string) error {
+	return l.finish(sessionID, sessionStatusCompleted, "")
+}
+
+// FailSession finishes a session as failed with the given reason
+func (l *MigrationLogger) FailSession(sessionID, errorMessage string) error {
+	return l.finish(sessionID, sessionStatusFailed, redactText(errorMessage))
+}
+
+// ActiveSessionCount returns the number of sessions that have not finished yet
+func (l *MigrationLogger) ActiveSessionCount() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.active)
+}
+
+// InterruptSessions finishes every active session as interrupted with the
+// given reason, marking its running tasks interrupted as well. It is called on
+// shutdown, so notifiers are not told. It returns the IDs of the sessions.
+func (l *MigrationLogger) InterruptSessions(reason string) ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now().UTC()
+	var ids []string
+	var errs []error
+	for id, session := range l.active {
+		for i := range session.Tasks {
+			if session.Tasks[i].Status == sessionStatusRunning {
+				session.Tasks[i].finish(sessionStatusInterrupted)
+			}
+		}
+		session.EndTime = &now
+		session.DurationMs = now.Sub(session.StartTime).Milliseconds()
+		session.Status = sessionStatusInterrupted
+		session.ErrorMessage = reason
+
+		delete(l.active, id)
+		ids = append(ids, id)
+		if err := l.save(session); err != nil {
+			errs = append(errs, err)
+		} else if err := l.saveSummary(session); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	sort.Strings(ids)
+	return ids, errors.Join(errs...)
+}
+
+// GetSession returns a copy of a session, looking at running sessions first
+// and falling back to the persisted sessions on disk.
+func (l *MigrationLogger) GetSession(sessionID string) (*MigrationSession, error) {
+	l.mu.RLock()
+	if session, exists := l.active[sessionID]; exists {
+		snapshot := session.clone()
+		l.mu.RUnlock()
+		return snapshot, nil
+	}
+	l.mu.RUnlock()
+
+	return l.load(sessionID)
+}
+
+// finish ends a running session, persists it and removes it from memory
+func (l *MigrationLogger) finish(sessionID, status, errorMessage string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	session, exists := l.active[sessionID]
+	if !exists {
+		return errSessionNotFound
+	}
+
+	now := time.Now().UTC()
+	session.EndTime = &now
+	session.DurationMs = now.Sub(session.StartTime).Milliseconds()
+	session.Status = status
+	switch {
+	case status != sessionStatusCompleted:
+	case session.CancelledTasks > 0:
+		session.Status = sessionStatusCancelled
+	case session.FailedTasks > 0:
+		session.Status = sessionStatusFailed
+	}
+	session.ErrorMessage = errorMessage
+
+	delete(l.active, sessionID)
+	if len(l.notifiers) > 0 {
+		go l.notify(session.clone())
+	}
+	if err := l.save(session); err != nil {
+		return err
+	}
+	return l.saveSummary(session)
+}
+
+// update applies fn to a running session and persists the result
+func (l *MigrationLogger) update(sessionID string, fn func(*MigrationSession) error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	session, exists := l.active[sessionID]
+	if !exists {
+		return errSessionNotFound
+	}
+	if err := fn(session); err != nil {
+		return err
+	}
+	return l.save(session)
+}
+
+// sessionPath returns the file a session is persisted to
+func (l *MigrationLogger) sessionPath(session *MigrationSession) string {
+	return filepath.Join(l.dayDir(session.StartTime), session.ID+".json")
+}
+
+// save writes a session to disk atomically. The caller must hold l.mu.
+func (l *MigrationLogger) save(session *MigrationSession) error {
+	path := l.sessionPath(session)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session %s: %w", session.ID, err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o640); err != nil {
+		return fmt.Errorf("failed to write session %s: %w", session.ID, err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// dayDir returns the directory holding the sessions started on the day of t
+func (l *MigrationLogger) dayDir(t time.Time) string {
+	return filepath.Join(l.dir, t.UTC().Format("2006-01-02"))
+}
+
+// saveSummary records the summary of a session in its day index. The caller must hold l.mu.
+func (l *MigrationLogger) saveSummary(session *MigrationSession) error {
+	dir := l.dayDir(session.StartTime)
+	summaries, err := l.readDaySummaries(dir)
+	if err != nil {
+		return err
+	}
+	summaries[session.ID] = session.Summary()
+
+	data, err := json.Marshal(summaries)
+	if err != nil {
+		return fmt.Errorf("failed to encode session summaries: %w", err)
+	}
+	path := filepath.Join(dir, summaryFileName)
+	if err := os.WriteFile(path+".tmp", data, 0o640); err != nil {
+		return fmt.Errorf("failed to write session summaries: %w", err)
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// readDaySummaries reads the summary index of a day directory. Days written
+// before the index existed are summarized from their session files.
+func (l *MigrationLogger) readDaySummaries(dir string) (map[string]MigrationSessionSummary, error) {
+	summaries := make(map[string]MigrationSessionSummary)
+
+	data, err := os.ReadFile(filepath.Join(dir, summaryFileName))
+	if err == nil {
+		if err := json.Unmarshal(data, &summaries); err != nil {
+			return nil, fmt.Errorf("failed to decode session summaries in %s: %w", dir, err)
+		}
+		return summaries, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read session summaries in %s: %w", dir, err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, file := range files {
+		if filepath.Base(file) == summaryFileName {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var summary MigrationSessionSummary
+		if err := json.Unmarshal(data, &summary); err != nil || summary.ID == "" {
+			continue
+		}
+		summaries[summary.ID] = summary
+	}
+	return summaries, nil
+}
+
+// GetSessionsPaged returns the summaries of the sessions started between start and
+// end (inclusive days, UTC), newest first, skipping offset and returning at most
+// limit entries, together with the total number of matching sessions.
+func (l *MigrationLogger) GetSessionsPaged(start, end time.Time, offset, limit int) ([]MigrationSessionSummary, int, error) {
+	summaries, err := l.summariesInRange(start, end)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total := len(summaries)
+	if offset >= total {
+		return []MigrationSessionSummary{}, total, nil
+	}
+	last := total
+	if limit > 0 && offset+limit < total {
+		last = offset + limit
+	}
+	return summaries[offset:last], total, nil
+}
+
+// summariesInRange collects the session summaries of the days between start and end,
+// with running sessions taken from memory, sorted by start time descending.
+//
+// The day files are replaced atomically, so they are read without holding l.mu;
+// the lock is only taken to look up the running sessions.
+func (l *MigrationLogger) summariesInRange(start, end time.Time) ([]MigrationSessionSummary, error) {
+	startDay := start.UTC().Truncate(24 * time.Hour)
+	endDay := end.UTC().Truncate(24 * time.Hour)
+
+	var summaries []MigrationSessionSummary
+	for day := endDay; !day.Before(startDay); day = day.AddDate(0, 0, -1) {
+		daySummaries, err := l.readDaySummaries(l.dayDir(day))
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range daySummaries {
+			summaries = append(summaries, summary)
+		}
+	}
+
+	l.mu.RLock()
+	for i, summary := range summaries {
+		if session, running := l.active[summary.ID]; running {
+			summaries[i] = session.Summary()
+		}
+	}
+	l.mu.RUnlock()
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].StartTime.After(summaries[j].StartTime)
+	})
+	return summaries, nil
+}
+
+// load reads a persisted session by ID
+func (l *MigrationLogger) load(sessionID string) (*MigrationSession, error) {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return nil, errSessionNotFound
+	}
+
+	matches, err := filepath.Glob(filepath.Join(l.dir, "*", sessionID+".json"))
+	if err != nil || len(matches) == 0 {
+		return nil, errSessionNotFound
+	}
+
+	data, err := os.ReadFile(matches[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session %s: %w", sessionID, err)
+	}
+
+	var session MigrationSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to decode session %s: %w", sessionID, err)
+	}
+	session.redactCredentials()
+	return &session, nil
+}
+
+// Summary returns the lightweight listing view of the session
+func (s *MigrationSession) Summary() MigrationSessionSummary {
+	return MigrationSessionSummary{
+		ID:             s.ID,
+		Username:       s.Username,
+		Status:         s.Status,
+		StartTime:      s.StartTime,
+		EndTime:        s.EndTime,
+		DurationMs:     s.DurationMs,
+		TotalTasks:     s.TotalTasks,
+		CompletedTasks: s.CompletedTasks,
+		FailedTasks:    s.FailedTasks,
+		CancelledTasks: s.CancelledTasks,
+		SkippedTasks:   s.SkippedTasks,
+		TotalDataSize:  s.TotalDataSize,
+	}
+}
+
+// task returns the record of the task with the given index
+func (s *MigrationSession) task(index int) *MigrationTask {
+	for i := range s.Tasks {
+		if s.Tasks[i].Index == index {
+			return &s.Tasks[i]
+		}
+	}
+	return nil
+}
+
+// clone returns a deep copy that can be used without holding the logger lock
+func (s *MigrationSession) clone() *MigrationSession {
+	snapshot := *s
+	snapshot.Tasks = append([]MigrationTask(nil), s.Tasks...)
+	snapshot.Metadata = make(map[string]string, len(s.Metadata))
+	for k, v := range s.Metadata {
+		snapshot.Metadata[k] = v
+	}
+	return &snapshot
+}
+
+// finish sets the final status and timing of a task
+func (t *MigrationTask) finish(status string) {
+	now := time.Now().UTC()
+	t.EndTime = &now
+	t.DurationMs = now.Sub(t.StartTime).Milliseconds()
+	t.Status = status
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// retentionInterval is how often sessions beyond MIGRATION_LOG_RETENTION_DAYS are purged
+const retentionInterval = 24 * time.Hour
+
+// PurgeResult reports what PurgeOlderThan removed
+type PurgeResult struct {
+	Cutoff         string   `json:"cutoff"` // First day kept, YYYY-MM-DD
+	PurgedDays     []string `json:"purged_days"`
+	PurgedSessions int      `json:"purged_sessions"`
+	PurgedFiles    int      `json:"purged_files"`
+	ReclaimedBytes int64    `json:"reclaimed_bytes"`
+	// KeptActive counts sessions of purged days that were kept because they are still running
+	KeptActive int `json:"kept_active,omitempty"`
+}
+
+// PurgeOlderThan deletes the sessions started more than days days ago (UTC),
+// together with the summary index of their day. Running and queued sessions
+// are kept, and so is the summary of their day. Retained repository backups
+// are not touched.
+func (l *MigrationLogger) PurgeOlderThan(days int) (PurgeResult, error) {
+	if days < 1 {
+		return PurgeResult{}, fmt.Errorf("days must be at least 1, got %d", days)
+	}
+	cutoff := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -days)
+	result := PurgeResult{Cutoff: cutoff.Format("2006-01-02"), PurgedDays: []string{}}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return result, fmt.Errorf("failed to read migration log directory: %w", err)
+	}
+	var errs []error
+	for _, entry := range entries {
+		// Only day directories hold sessions; backups/ and other entries are skipped
+		day, err := time.Parse("2006-01-02", entry.Name())
+		if !entry.IsDir() || err != nil || !day.Before(cutoff) {
+			continue
+		}
+		if err := l.purgeDay(filepath.Join(l.dir, entry.Name()), &result); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		result.PurgedDays = append(result.PurgedDays, entry.Name())
+	}
+	return result, errors.Join(errs...)
+}
+
+// purgeDay deletes the session files of a day directory except those of
+// active sessions, and the directory itself once nothing is left. The caller
+// must hold l.mu.
+func (l *MigrationLogger) purgeDay(dir string, result *PurgeResult) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	kept := 0
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || name == summaryFileName {
+			continue
+		}
+		sessionID := name[:len(name)-len(filepath.Ext(name))]
+		if _, running := l.active[sessionID]; running && filepath.Ext(name) == ".json" {
+			kept++
+			result.KeptActive++
+			continue
+		}
+		if err := l.removeFile(filepath.Join(dir, name), result); err != nil {
+			return err
+		}
+		if filepath.Ext(name) == ".json" {
+			result.PurgedSessions++
+		}
+	}
+
+	if kept > 0 {
+		// Keep the summaries of the running sessions for listings
+		summaries, err := l.readDaySummaries(dir)
+		if err != nil {
+			return err
+		}
+		for id := range summaries {
+			if _, running := l.active[id]; !running {
+				delete(summaries, id)
+			}
+		}
+		data, err := json.Marshal(summaries)
+		if err != nil {
+			return fmt.Errorf("failed to encode session summaries: %w", err)
+		}
+		return os.WriteFile(filepath.Join(dir, summaryFileName), data, 0o640)
+	}
+
+	summaryPath := filepath.Join(dir, summaryFileName)
+	if _, err := os.Stat(summaryPath); err == nil {
+		if err := l.removeFile(summaryPath, result); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(dir); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", dir, err)
+	}
+	return nil
+}
+
+// removeFile deletes a file and counts it in result
+func (l *MigrationLogger) removeFile(path string, result *PurgeResult) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	result.PurgedFiles++
+	result.ReclaimedBytes += info.Size()
+	return nil
+}
+
+// startRetention purges sessions older than days now and then once a day, for
+// MIGRATION_LOG_RETENTION_DAYS. It must be called before the logger is shared.
+func (l *MigrationLogger) startRetention(days int) {
+	l.retentionDays = days
+	purge := func() {
+		result, err := l.PurgeOlderThan(days)
+		if err != nil {
+			serviceLog.Warn("Failed to purge old migration sessions", "error", err)
+		}
+		if result.PurgedFiles > 0 {
+			serviceLog.Info("Purged old migration sessions", "cutoff", result.Cutoff, "sessions", result.PurgedSessions, "files", result.PurgedFiles, "bytes", result.ReclaimedBytes)
+		}
+	}
+	go func() {
+		purge()
+		ticker := time.NewTicker(retentionInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purge()
+		}
+	}()
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// MigrationStatistics aggregates the migration sessions of a date range
+type MigrationStatistics struct {
+	From                time.Time      `json:"from"`
+	To                  time.Time      `json:"to"`
+	TotalSessions       int            `json:"total_sessions"`
+	CompletedSessions   int            `json:"completed_sessions"`
+	FailedSessions      int            `json:"failed_sessions"`
+	RunningSessions     int            `json:"running_sessions"`
+	CancelledSessions   int            `json:"cancelled_sessions"`
+	InterruptedSessions int            `json:"interrupted_sessions"`
+	TotalTasks          int            `json:"total_tasks"`
+	CompletedTasks      int            `json:"completed_tasks"`
+	FailedTasks         int            `json:"failed_tasks"`
+	TimeoutTasks        int            `json:"timeout_tasks"`
+	CancelledTasks      int            `json:"cancelled_tasks"`
+	SkippedTasks        int            `json:"skipped_tasks"`
+	InterruptedTasks    int            `json:"interrupted_tasks"`
+	TotalDataSize       int64          `json:"total_data_size_bytes"`
+	TotalTriples        int64          `json:"total_triples"`
+	SuccessRate         float64        `json:"success_rate"`
+	ActionCounts        map[string]int `json:"action_counts"`
+	UserCounts          map[string]int `json:"user_counts"`
+}
+
+// StatisticsFilter limits statistics to matching records. Empty fields match everything.
+type StatisticsFilter struct {
+	Action   string // only tasks with this action
+	Username string // only sessions of this user
+}
+
+// matchesTask reports whether a task passes the action filter
+func (f StatisticsFilter) matchesTask(task MigrationTask) bool {
+	return f.Action == "" || task.Action == f.Action
+}
+
+// matchesSession reports whether a session belongs to the filtered user and,
+// with an action filter, contains at least one task of that action
+func (f StatisticsFilter) matchesSession(session *MigrationSession) bool {
+	if f.Username != "" && session.Username != f.Username {
+		return false
+	}
+	if f.Action == "" {
+		return true
+	}
+	for _, task := range session.Tasks {
+		if f.matchesTask(task) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetStatistics aggregates all sessions started between start and end (inclusive days, UTC)
+func (l *MigrationLogger) GetStatistics(start, end time.Time) (*MigrationStatistics, error) {
+	return l.GetFilteredStatistics(start, end, StatisticsFilter{})
+}
+
+// GetFilteredStatistics aggregates the sessions started between start and end that
+// match filter. With an action filter only the matching tasks are counted,
+// sessions without such a task are skipped and a session counts as failed only
+// if one of its matching tasks failed.
+func (l *MigrationLogger) GetFilteredStatistics(start, end time.Time, filter StatisticsFilter) (*MigrationStatistics, error) {
+	sessions, err := l.GetSessionsInDateRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &MigrationStatistics{
+		From:         start,
+		To:           end,
+		ActionCounts: make(map[string]int),
+		UserCounts:   make(map[string]int),
+	}
+	for _, session := range sessions {
+		if !filter.matchesSession(session) {
+			continue
+		}
+
+		// With an action filter a session failed if one of its matching tasks failed
+		failedTasks := 0
+		for _, task := range session.Tasks {
+			if !filter.matchesTask(task) {
+				continue
+			}
+			stats.TotalTasks++
+			stats.ActionCounts[task.Action]++
+			stats.TotalDataSize += task.DataSize
+			stats.TotalTriples += task.TripleCount
+			switch task.Status {
+			case sessionStatusCompleted:
+				stats.CompletedTasks++
+			case sessionStatusSkipped:
+				stats.CompletedTasks++
+				stats.SkippedTasks++
+			case sessionStatusFailed:
+				stats.FailedTasks++
+				failedTasks++
+				if task.ErrorType == taskErrorTimeout {
+					stats.TimeoutTasks++
+				}
+			case sessionStatusCancelled:
+				stats.CancelledTasks++
+			case sessionStatusInterrupted:
+				stats.InterruptedTasks++
+			}
+		}
+
+		sessionFailed := failedTasks > 0
+		if filter.Action == "" {
+			sessionFailed = session.Status == sessionStatusFailed || session.FailedTasks > 0
+		}
+
+		stats.TotalSessions++
+		if session.Username != "" {
+			stats.UserCounts[session.Username]++
+		}
+		switch {
+		case session.Status == sessionStatusRunning:
+			stats.RunningSessions++
+		case session.Status == sessionStatusCancelled:
+			stats.CancelledSessions++
+		case session.Status == sessionStatusInterrupted:
+			stats.InterruptedSessions++
+		case sessionFailed:
+			stats.FailedSessions++
+		default:
+			stats.CompletedSessions++
+		}
+	}
+
+	if finished := stats.CompletedTasks + stats.FailedTasks; finished > 0 {
+		stats.SuccessRate = float64(stats.CompletedTasks) / float64(finished) * 100
+	}
+	return stats, nil
+}
+
+// GetFilteredSessions returns the sessions started between start and end that match filter, newest first
+func (l *MigrationLogger) GetFilteredSessions(start, end time.Time, filter StatisticsFilter) ([]*MigrationSession, error) {
+	sessions, err := l.GetSessionsInDateRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+	matching := sessions[:0]
+	for _, session := range sessions {
+		if filter.matchesSession(session) {
+			matching = append(matching, session)
+		}
+	}
+	return matching, nil
+}
+
+// GetSessionsInDateRange returns the full sessions started between start and end
+// (inclusive days, UTC), with running sessions taken from memory, newest first.
+// Like summariesInRange it reads the session files without holding l.mu.
+func (l *MigrationLogger) GetSessionsInDateRange(start, end time.Time) ([]*MigrationSession, error) {
+	startDay := start.UTC().Truncate(24 * time.Hour)
+	endDay := end.UTC().Truncate(24 * time.Hour)
+
+	var sessions []*MigrationSession
+	for day := endDay; !day.Before(startDay); day = day.AddDate(0, 0, -1) {
+		files, err := filepath.Glob(filepath.Join(l.dayDir(day), "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if filepath.Base(file) == summaryFileName {
+				continue
+			}
+			data, err := os.ReadFile(file)
+			if os.IsNotExist(err) {
+				// Purged while listing
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read session file %s: %w", file, err)
+			}
+			var session MigrationSession
+			if err := json.Unmarshal(data, &session); err != nil {
+				debugLog("Skipping unreadable session file %s: %v", file, err)
+				continue
+			}
+			session.redactCredentials()
+			sessions = append(sessions, &session)
+		}
+	}
+
+	l.mu.RLock()
+	for i, session := range sessions {
+		if running, exists := l.active[session.ID]; exists {
+			sessions[i] = running.clone()
+		}
+	}
+	l.mu.RUnlock()
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].StartTime.After(sessions[j].StartTime)
+	})
+	return sessions, nil
+}
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"eve.evalgo.org/common"
+)
+
+// notifierTimeout bounds how long a notifier may take to report a session
+const notifierTimeout = 30 * time.Second
+
+// Notifier is told about every finished migration session. Implementations
+// decide themselves which sessions they report.
+type Notifier interface {
+	// Name identifies the notifier in log messages
+	Name() string
+	// OnSessionComplete reports a session that finished without failures,
+	// including cancelled sessions. The session is a snapshot the notifier may keep.
+	OnSessionComplete(session *MigrationSession) error
+	// OnSessionFailed reports a session that failed as a whole or had failed tasks
+	OnSessionFailed(session *MigrationSession, cause error) error
+}
+
+// AddNotifier registers a notifier for the sessions finished from now on
+func (l *MigrationLogger) AddNotifier(n Notifier) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.notifiers = append(l.notifiers, n)
+}
+
+// notify passes a finished session to all notifiers. It runs in the background,
+// so a slow notifier does not delay the response of the session; a notifier
+// that does not return within notifierTimeout is logged and abandoned.
+func (l *MigrationLogger) notify(session *MigrationSession) {
+	l.mu.RLock()
+	notifiers := append([]Notifier(nil), l.notifiers...)
+	l.mu.RUnlock()
+
+	cause := sessionFailure(session)
+	for _, n := range notifiers {
+		done := make(chan error, 1)
+		go func() {
+			if cause != nil {
+				done <- n.OnSessionFailed(session, cause)
+			} else {
+				done <- n.OnSessionComplete(session)
+			}
+		}()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				serviceLog.Error("Session notification failed", "notifier", n.Name(), "session_id", session.ID, "error", err)
+			}
+		case <-time.After(notifierTimeout):
+			serviceLog.Error("Session notification timed out", "notifier", n.Name(), "session_id", session.ID, "timeout", notifierTimeout)
+		}
+	}
+}
+
+// sessionFailure returns why a finished session failed, or nil if it did not
+func sessionFailure(session *MigrationSession) error {
+	switch {
+	case session.Status != sessionStatusFailed:
+		return nil
+	case session.ErrorMessage != "":
+		return errors.New(session.ErrorMessage)
+	default:
+		return fmt.Errorf("%d of %d tasks failed", session.FailedTasks, session.TotalTasks)
+	}
+}
+
+// Values of NOTIFY_ON
+const (
+	notifyOnFailure = "failure"
+	notifyOnAlways  = "always"
+)
+
+// notifyOnAll reports whether successful sessions are reported as well,
+// set by NOTIFY_ON
+func notifyOnAll() (bool, error) {
+	switch notifyOn := strings.ToLower(common.GetEnv("NOTIFY_ON", notifyOnFailure)); notifyOn {
+	case "", notifyOnFailure:
+		return false, nil
+	case notifyOnAlways:
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid NOTIFY_ON %q: must be %s or %s", notifyOn, notifyOnFailure, notifyOnAlways)
+	}
+}
+
+// configuredNotifiers returns the notifiers configured by the environment:
+// email when SMTP_HOST and SMTP_TO are set, Slack when SLACK_WEBHOOK_URL is set
+func configuredNotifiers(serviceURL string) ([]Notifier, error) {
+	always, err := notifyOnAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var notifiers []Notifier
+	if n := configuredSMTPNotifier(always); n != nil {
+		notifiers = append(notifiers, n)
+	}
+	if n := configuredSlackNotifier(always, serviceURL); n != nil {
+		notifiers = append(notifiers, n)
+	}
+	return notifiers, nil
+}
+
+// smtpNotifier emails a summary of finished sessions. By default only failed
+// sessions are reported; NOTIFY_ON=always reports every session.
+type smtpNotifier struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	to       []string
+	always   bool
+
+	// send delivers the message, smtp.SendMail outside of tests
+	send func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// configuredSMTPNotifier returns the notifier configured by SMTP_HOST, SMTP_PORT,
+// SMTP_FROM, SMTP_TO, SMTP_USERNAME and SMTP_PASSWORD, or nil if SMTP_HOST or
+// SMTP_TO is not set
+func configuredSMTPNotifier(always bool) *smtpNotifier {
+	host := common.GetEnv("SMTP_HOST", "")
+	var to []string
+	for _, address := range strings.Split(common.GetEnv("SMTP_TO", ""), ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			to = append(to, address)
+		}
+	}
+	if host == "" || len(to) == 0 {
+		return nil
+	}
+
+	return &smtpNotifier{
+		host:     host,
+		port:     common.GetEnvInt("SMTP_PORT", 587),
+		username: common.GetEnv("SMTP_USERNAME", ""),
+		password: common.GetEnv("SMTP_PASSWORD", ""),
+		from:     common.GetEnv("SMTP_FROM", "graphdb-service@"+host),
+		to:       to,
+		always:   always,
+		send:     smtp.SendMail,
+	}
+}
+
+// Name implements Notifier
+func (n *smtpNotifier) Name() string {
+	return "smtp"
+}
+
+// OnSessionComplete implements Notifier
+func (n *smtpNotifier) OnSessionComplete(session *MigrationSession) error {
+	if !n.always {
+		return nil
+	}
+	return n.sendSession(session)
+}
+
+// OnSessionFailed implements Notifier. The cause is part of the session summary.
+func (n *smtpNotifier) OnSessionFailed(session *MigrationSession, cause error) error {
+	return n.sendSession(session)
+}
+
+// sendSession emails the summary of a session
+func (n *smtpNotifier) sendSession(session *MigrationSession) error {
+	var auth smtp.Auth
+	if n.username != "" {
+		auth = smtp.PlainAuth("", n.username, n.password, n.host)
+	}
+	addr := net.JoinHostPort(n.host, strconv.Itoa(n.port))
+	if err := n.send(addr, auth, n.from, n.to, n.message(session)); err != nil {
+		return fmt.Errorf("failed to send mail via %s: %w", addr, err)
+	}
+	return nil
+}
+
+// message returns the email for a session, headers included
+func (n *smtpNotifier) message(session *MigrationSession) []byte {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Session:   %s\r\n", session.ID)
+	fmt.Fprintf(&body, "Status:    %s\r\n", session.Status)
+	if session.Username != "" {
+		fmt.Fprintf(&body, "User:      %s\r\n", session.Username)
+	}
+	fmt.Fprintf(&body, "Started:   %s\r\n", session.StartTime.Format(time.RFC3339))
+	if session.EndTime != nil {
+		fmt.Fprintf(&body, "Finished:  %s (%s)\r\n", session.EndTime.Format(time.RFC3339), time.Duration(session.DurationMs)*time.Millisecond)
+	}
+	fmt.Fprintf(&body, "Tasks:     %s\r\n", sessionTaskCounts(session))
+	if session.ErrorMessage != "" {
+		fmt.Fprintf(&body, "Error:     %s\r\n", session.ErrorMessage)
+	}
+
+	if failed := failedSessionTasks(session); len(failed) > 0 {
+		body.WriteString("\r\nFailed tasks:\r\n")
+		for _, task := range failed {
+			fmt.Fprintf(&body, "  #%d %s [%s]: %s\r\n", task.Index, task.Action, task.ErrorType, task.ErrorMessage)
+		}
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&msg, "Subject: [graphdb-service] Migration session %s %s\r\n", session.ID, session.Status)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(body.String())
+	return []byte(msg.String())
+}
+
+// sessionTaskCounts describes the task counts of a session
+func sessionTaskCounts(session *MigrationSession) string {
+	return fmt.Sprintf("%d total, %d completed, %d failed, %d cancelled",
+		session.TotalTasks, session.CompletedTasks, session.FailedTasks, session.CancelledTasks)
+}
+
+// failedSessionTasks returns the tasks of a session that recorded an error
+func failedSessionTasks(session *MigrationSession) []MigrationTask {
+	var failed []MigrationTask
+	for _, task := range session.Tasks {
+		if task.ErrorMessage != "" {
+			failed = append(failed, task)
+		}
+	}
+	return failed
+}
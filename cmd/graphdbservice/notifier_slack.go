@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"eve.evalgo.org/common"
+)
+
+// slackNotifier posts a summary of finished sessions to a Slack incoming
+// webhook. By default only failed sessions are reported; NOTIFY_ON=always
+// reports every session.
+type slackNotifier struct {
+	webhookURL string
+	serviceURL string // Base URL of the session links
+	always     bool
+	client     *http.Client
+}
+
+// configuredSlackNotifier returns the notifier configured by SLACK_WEBHOOK_URL,
+// or nil if it is not set. Messages link to the session details of serviceURL.
+func configuredSlackNotifier(always bool, serviceURL string) *slackNotifier {
+	webhookURL := common.GetEnv("SLACK_WEBHOOK_URL", "")
+	if webhookURL == "" {
+		return nil
+	}
+	return &slackNotifier{
+		webhookURL: webhookURL,
+		serviceURL: strings.TrimRight(serviceURL, "/"),
+		always:     always,
+		client:     &http.Client{Timeout: notifierTimeout},
+	}
+}
+
+// Name implements Notifier
+func (n *slackNotifier) Name() string {
+	return "slack"
+}
+
+// OnSessionComplete implements Notifier
+func (n *slackNotifier) OnSessionComplete(session *MigrationSession) error {
+	if !n.always {
+		return nil
+	}
+	icon := ":white_check_mark:"
+	if session.Status == sessionStatusCancelled {
+		icon = ":no_entry_sign:"
+	}
+	return n.post(n.message(session, icon, nil))
+}
+
+// OnSessionFailed implements Notifier
+func (n *slackNotifier) OnSessionFailed(session *MigrationSession, cause error) error {
+	return n.post(n.message(session, ":x:", cause))
+}
+
+// message formats a session in Slack mrkdwn
+func (n *slackNotifier) message(session *MigrationSession, icon string, cause error) string {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "%s Migration session `%s` *%s*", icon, session.ID, session.Status)
+	if session.Username != "" {
+		fmt.Fprintf(&msg, " (%s)", session.Username)
+	}
+	fmt.Fprintf(&msg, "\nTasks: %s", sessionTaskCounts(session))
+	if session.DurationMs > 0 {
+		fmt.Fprintf(&msg, ", %d ms", session.DurationMs)
+	}
+	if cause != nil {
+		fmt.Fprintf(&msg, "\nError: %s", cause)
+	}
+	for _, task := range failedSessionTasks(session) {
+		fmt.Fprintf(&msg, "\n• #%d %s [%s]: %s", task.Index, task.Action, task.ErrorType, task.ErrorMessage)
+	}
+	if n.serviceURL != "" {
+		fmt.Fprintf(&msg, "\n<%s/v1/api/sessions/%s|Session details>", n.serviceURL, session.ID)
+	}
+	return msg.String()
+}
+
+// post sends a message to the webhook
+func (n *slackNotifier) post(text string) error {
+	payload, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	resp, err := n.client.Post(n.webhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		// The webhook URL is a secret, leave it out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to post to Slack: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook answered with status %d: %s", resp.StatusCode, readErrorBody(resp))
+	}
+	return nil
+}
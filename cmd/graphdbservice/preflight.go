@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"eve.evalgo.org/semantic"
+)
+
+// preflightTimeout bounds the reachability probe of each GraphDB server
+const preflightTimeout = 5 * time.Second
+
+// unreachableServerError reports a GraphDB server that failed the preflight probe
+type unreachableServerError struct {
+	URL string
+	Err error
+}
+
+func (e *unreachableServerError) Error() string {
+	return fmt.Sprintf("GraphDB server %s is unreachable: %v", e.URL, e.Err)
+}
+
+func (e *unreachableServerError) Unwrap() error {
+	return e.Err
+}
+
+// checkGraphDBReachable probes the repositories endpoint of a GraphDB server.
+// Any HTTP response counts as reachable, authentication is checked by the task itself.
+func checkGraphDBReachable(client *http.Client, serverURL string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, normalizeURL(serverURL)+"/rest/repositories", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+// preflightServers checks that every GraphDB server is reachable before any
+// task runs and returns an *unreachableServerError for the first that is not.
+func preflightServers(serverURLs []string) error {
+	for _, serverURL := range serverURLs {
+		client, err := graphDBClientFor(serverURL)
+		if err != nil {
+			return &unreachableServerError{URL: serverURL, Err: err}
+		}
+		if err := checkGraphDBReachable(client, serverURL, preflightTimeout); err != nil {
+			return &unreachableServerError{URL: serverURL, Err: err}
+		}
+		debugLog("Preflight: GraphDB server %s is reachable", serverURL)
+	}
+	return nil
+}
+
+// preflightTaskServers checks every GraphDB server of the tasks before any
+// task runs: that it is reachable, returning an *unreachableServerError, and
+// that it accepts the credentials of the task, returning an ErrAuth error that
+// tells missing credentials from rejected ones.
+func preflightTaskServers(tasks []Task) error {
+	for _, repo := range taskServerEndpoints(tasks) {
+		serverURL := normalizeURL(repo.URL)
+		client, err := graphDBClientFor(serverURL)
+		if err != nil {
+			return &unreachableServerError{URL: serverURL, Err: err}
+		}
+		if err := checkGraphDBAccess(client, repo, preflightTimeout); err != nil {
+			return err
+		}
+		debugLog("Preflight: GraphDB server %s is reachable", serverURL)
+	}
+	return nil
+}
+
+// collectActionServerURLs returns the distinct GraphDB server URLs referenced by a
+// semantic action, including nested workflow items. Repositories carry their server
+// in "serverUrl", data catalogs of graphs in "url".
+func collectActionServerURLs(action *semantic.SemanticAction) []string {
+	seen := make(map[string]bool)
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for key, child := range v {
+				if s, ok := child.(string); ok && (key == "serverUrl" || key == "url") {
+					if strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") {
+						seen[normalizeURL(s)] = true
+					}
+					continue
+				}
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(action.Properties)
+
+	urls := make([]string, 0, len(seen))
+	for u := range seen {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// preflightAction probes all GraphDB servers referenced by the action unless the
+// caller set the "skipPreflight" property.
+func preflightAction(action *semantic.SemanticAction) error {
+	if skip, _ := action.Properties["skipPreflight"].(bool); skip {
+		return nil
+	}
+	return preflightServers(collectActionServerURLs(action))
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RDFFormat describes an RDF serialization: the name used in tasks (format,
+// export_format), the file extension it is detected by and the MIME type sent
+// to and requested from GraphDB.
+type RDFFormat struct {
+	Name        string `json:"name"`
+	Extension   string `json:"extension"`
+	ContentType string `json:"content_type"`
+	Quads       bool   `json:"quads,omitempty"` // The format carries named graphs of its own
+}
+
+// RegisterRDFFormat adds an RDF format, or replaces the extension and MIME type
+// of a known one, e.g. to send application/x-turtle for turtle to a GraphDB
+// version that expects it. It must be called before the service starts.
+func RegisterRDFFormat(format RDFFormat) error {
+	name := strings.ToLower(format.Name)
+	ext := strings.ToLower(format.Extension)
+	if name == "" || name == "unknown" {
+		return fmt.Errorf("invalid RDF format name '%s'", format.Name)
+	}
+	if !strings.HasPrefix(ext, ".") || len(ext) < 2 {
+		return fmt.Errorf("invalid extension '%s' for RDF format '%s': must start with a dot", format.Extension, name)
+	}
+	if err := checkMediaType(format.ContentType); err != nil {
+		return fmt.Errorf("invalid content type for RDF format '%s': %w", name, err)
+	}
+	if other := getFileType("file" + ext); other != "unknown" && other != name {
+		return fmt.Errorf("extension '%s' of RDF format '%s' is already used by '%s'", ext, name, other)
+	}
+
+	rdfFormatExtensions[name] = ext
+	rdfContentTypes[name] = format.ContentType
+	rdfQuadFormats[name] = format.Quads
+	return nil
+}
+
+// rdfFormats returns the known RDF formats sorted by name
+func rdfFormats() []RDFFormat {
+	formats := make([]RDFFormat, 0, len(rdfContentTypes))
+	for _, name := range sortedKeys(rdfFormatSet()) {
+		formats = append(formats, RDFFormat{
+			Name:        name,
+			Extension:   rdfFormatExtensions[name],
+			ContentType: rdfContentTypes[name],
+			Quads:       rdfQuadFormats[name],
+		})
+	}
+	return formats
+}
+
+// registeredFileType returns the format whose extension the file name has, for
+// formats not hard-wired in getFileType, or "unknown"
+func registeredFileType(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == "" {
+		return "unknown"
+	}
+	for name, formatExt := range rdfFormatExtensions {
+		if formatExt == ext {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// supportedRDFExtensions lists the file extensions recognized by getFileType
+func supportedRDFExtensions() string {
+	exts := []string{".xml", ".json"}
+	for _, ext := range rdfFormatExtensions {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return strings.Join(exts, ", ")
+}
+
+// isRDFStarFormat reports whether a file type is an RDF-star serialization
+func isRDFStarFormat(fileType string) bool {
+	return fileType == "turtle-star" || fileType == "trig-star"
+}
+
+// warnUnlessRDFStar adds a warning to result if the server of repo does not
+// support RDF-star or its version cannot be determined. The task still runs:
+// GraphDB rejects the data itself if it cannot read it.
+func warnUnlessRDFStar(client *http.Client, repo *Repository, result map[string]interface{}) {
+	info, err := graphDBServerInfo(client, repo.URL, repo.Username, repo.Password)
+	switch {
+	case err != nil:
+		addResultWarning(result, fmt.Sprintf("could not check RDF-star support of %s: %v", repo.URL, err))
+	case !info.SupportsRDFStar():
+		addResultWarning(result, fmt.Sprintf("GraphDB %s at %s does not support RDF-star (requires 9.2 or later)", info.Version, repo.URL))
+	}
+}
+
+// rdfMediaType returns the MIME type used on the wire for an RDF format: the
+// override of the task if set, otherwise the type of the format
+func rdfMediaType(format, override string) string {
+	if override != "" {
+		return override
+	}
+	return rdfContentTypes[format]
+}
+
+// checkMediaType checks that a MIME type override is a valid media type such as
+// "application/x-turtle"
+func checkMediaType(contentType string) error {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("invalid media type '%s': %v", contentType, err)
+	}
+	if !strings.Contains(mediaType, "/") {
+		return fmt.Errorf("invalid media type '%s': expected type/subtype", contentType)
+	}
+	return nil
+}
+
+// validateMediaTypeOverride checks the MIME type override of a task field
+func validateMediaTypeOverride(field, contentType string) error {
+	if contentType == "" {
+		return nil
+	}
+	if err := checkMediaType(contentType); err != nil {
+		return &taskFieldError{Field: field, Message: err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// repoBackupsDir is the directory below MIGRATION_LOG_DIR holding retained repository backups
+	repoBackupsDir = "backups"
+	// repoBackupNoSession groups backups taken outside a migration session (e.g. semantic actions)
+	repoBackupNoSession = "no-session"
+	// repoBackupManifestFile describes the contents of a backup directory
+	repoBackupManifestFile = "manifest.json"
+	// brfContentType is the MIME type of GraphDB binary RDF backups
+	brfContentType = "application/x-binary-rdf"
+	// repoBackupConfigFile and repoBackupDataFile are the files of a backup.
+	// They do not depend on the repository name, which may contain path
+	// separators or "..".
+	repoBackupConfigFile = "config.ttl"
+	repoBackupDataFile   = "data.brf"
+)
+
+// repoBackupManifest describes a retained repository backup. Backups are stored
+// in <MIGRATION_LOG_DIR>/backups/<session id>/<backup id>/ and are never
+// deleted automatically.
+type repoBackupManifest struct {
+	ID         string    `json:"id"`
+	SessionID  string    `json:"session_id,omitempty"`
+	Server     string    `json:"server"`
+	Repository string    `json:"repository"`
+	CreatedAt  time.Time `json:"created_at"`
+	ConfigFile string    `json:"config_file"`
+	DataFile   string    `json:"data_file"`
+	DataSize   int64     `json:"data_size"`
+
+	// RenamedTo and Graphs track the repo-rename that took the backup, so an
+	// interrupted rename can be resumed from the retained graph exports
+	RenamedTo string            `json:"renamed_to,omitempty"`
+	Graphs    []repoRenameGraph `json:"graphs,omitempty"`
+
+	dir string // backup directory, set by saveRepoBackup and loadRepoBackup
+}
+
+// sessionIDContextKey stores the migration session ID in a task context
+type sessionIDContextKey struct{}
+
+// withSessionID returns a copy of ctx carrying the migration session ID of a task
+func withSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionIDContextKey{}, sessionID)
+}
+
+// sessionIDFromContext returns the migration session ID of a task context, or ""
+func sessionIDFromContext(ctx context.Context) string {
+	sessionID, _ := ctx.Value(sessionIDContextKey{}).(string)
+	return sessionID
+}
+
+// backupRoot returns the directory holding all retained repository backups
+func (l *MigrationLogger) backupRoot() string {
+	return filepath.Join(l.dir, repoBackupsDir)
+}
+
+// saveRepoBackup retains the configuration and the BRF data of a repository
+// below the migration log directory. confFile is copied, so the caller keeps
+// ownership of it.
+func saveRepoBackup(run *taskRun, repoName, confFile string) (*repoBackupManifest, error) {
+	if migrationLogger == nil {
+		return nil, fmt.Errorf("keep_backup requires migration session logging (MIGRATION_LOG_DIR)")
+	}
+
+	sessionID := sessionIDFromContext(run.ctx)
+	if sessionID == "" {
+		sessionID = repoBackupNoSession
+	}
+	manifest := &repoBackupManifest{
+		ID:         uuid.New().String(),
+		SessionID:  sessionIDFromContext(run.ctx),
+		Server:     redactURL(normalizeURL(run.task.Tgt.URL)),
+		Repository: repoName,
+		CreatedAt:  time.Now().UTC(),
+		ConfigFile: repoBackupConfigFile,
+		DataFile:   repoBackupDataFile,
+	}
+
+	dir := filepath.Join(migrationLogger.backupRoot(), sessionID, manifest.ID)
+	manifest.dir = dir
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	if _, err := copyFile(confFile, filepath.Join(dir, manifest.ConfigFile)); err != nil {
+		return nil, fmt.Errorf("failed to back up configuration of repository '%s': %w", repoName, err)
+	}
+
+	// The data is downloaded straight into the backup directory, it is not needed elsewhere
+	dataSize, err := graphDBDownloadRepositoryData(run.tgtClient, run.task.Tgt.URL, run.task.Tgt.Username, run.task.Tgt.Password, repoName, filepath.Join(dir, manifest.DataFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up data of repository '%s': %w", repoName, err)
+	}
+	manifest.DataSize = dataSize
+
+	if err := manifest.save(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// save writes the manifest into its backup directory
+func (m *repoBackupManifest) save() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(m.dir, repoBackupManifestFile), data, 0o640); err != nil {
+		return fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+	return nil
+}
+
+// loadRepoBackup finds a retained backup by ID and returns its manifest and directory
+func loadRepoBackup(backupID string) (*repoBackupManifest, string, error) {
+	if migrationLogger == nil {
+		return nil, "", fmt.Errorf("repository backups require migration session logging (MIGRATION_LOG_DIR)")
+	}
+	if _, err := uuid.Parse(backupID); err != nil {
+		return nil, "", fmt.Errorf("invalid backup_id '%s'", backupID)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(migrationLogger.backupRoot(), "*", backupID, repoBackupManifestFile))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(matches) == 0 {
+		return nil, "", fmt.Errorf("backup '%s' not found", backupID)
+	}
+
+	data, err := os.ReadFile(matches[0])
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read backup manifest: %w", err)
+	}
+	var manifest repoBackupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, "", fmt.Errorf("invalid backup manifest: %w", err)
+	}
+	manifest.dir = filepath.Dir(matches[0])
+	return &manifest, manifest.dir, nil
+}
+
+// copyFile copies src to dst and returns the number of bytes copied
+func copyFile(src, dst string) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
+
+// executeRepoRestoreBackupTask executes the repo-restore-backup action.
+//
+// It recreates a repository from a backup retained by repo-rename with
+// keep_backup: the saved configuration is restored (renamed to tgt.repo when
+// given) and the BRF data is imported into it.
+func executeRepoRestoreBackupTask(run *taskRun) error {
+	task, progress, result, tgtClient, zitiClient := run.task, run.progress, run.result, run.tgtClient, run.zitiClient
+
+	if identityFile != "" {
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
+		if err != nil {
+			return err
+		}
+		tgtClient, err = zitiClient(tgtURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	manifest, dir, err := loadRepoBackup(task.BackupID)
+	if err != nil {
+		return err
+	}
+	repoName := task.Tgt.Repo
+	if repoName == "" {
+		repoName = manifest.Repository
+	}
+
+	existingRepos, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	if err != nil {
+		return err
+	}
+	for _, bind := range existingRepos.Results.Bindings {
+		if bind.Id["value"] == repoName {
+			return fmt.Errorf("repository '%s' already exists", repoName)
+		}
+	}
+
+	// Restore from a copy of the config, the retained backup stays unchanged
+	confFile := run.tempFile(fmt.Sprintf("repo_restore_%s.ttl", uuid.New().String()))
+	if _, err := copyFile(filepath.Join(dir, manifest.ConfigFile), confFile); err != nil {
+		return fmt.Errorf("failed to read backup configuration: %w", err)
+	}
+	defer func() { _ = os.Remove(confFile) }()
+	if repoName != manifest.Repository {
+		if err := updateRepositoryNameInConfig(confFile, manifest.Repository, repoName); err != nil {
+			return fmt.Errorf("failed to update repository name in config: %w", err)
+		}
+	}
+
+	progress("Creating repository", 1, 1)
+	if err := run.graphDB(tgtClient).RestoreConf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, confFile); err != nil {
+		return fmt.Errorf("failed to create repository '%s': %w", repoName, err)
+	}
+
+	upload, err := graphDBRestoreRepositoryData(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repoName, filepath.Join(dir, manifest.DataFile), progress)
+	if err != nil {
+		// Do not leave an empty repository behind
+		_ = run.graphDB(tgtClient).DeleteRepository(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repoName)
+		return fmt.Errorf("failed to import backup data into repository '%s': %w", repoName, err)
+	}
+
+	result["message"] = "Repository restored from backup"
+	result["backup_id"] = manifest.ID
+	result["repository"] = repoName
+	result["source_repository"] = manifest.Repository
+	result["backup_created_at"] = manifest.CreatedAt
+	upload.setResult(result)
+	return nil
+}
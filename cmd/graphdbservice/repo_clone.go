@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/google/uuid"
+)
+
+// Copy methods reported by repo-clone
+const (
+	cloneMethodFederation = "federation" // SPARQL UPDATE through GraphDB's internal federation, data stays on the server
+	cloneMethodBRFStream  = "brf_stream" // BRF export streamed through the service into the new repository
+)
+
+// cloneFederationUpdates copy the explicit statements of a repository into the
+// repository the update runs on, using GraphDB's internal SPARQL federation
+// (SERVICE <repository:id>). The first copies all named graphs, the second the
+// default graph, which GraphDB exposes as sesame:nil. Inferred statements are
+// not copied; the clone infers them again with the same ruleset.
+var cloneFederationUpdates = []string{
+	`INSERT { GRAPH ?g { ?s ?p ?o } } WHERE { SERVICE <repository:%s> { GRAPH ?g { ?s ?p ?o } } }`,
+	`INSERT { ?s ?p ?o } WHERE { SERVICE <repository:%s> { GRAPH <http://www.openrdf.org/schema/sesame#nil> { ?s ?p ?o } } }`,
+}
+
+// executeRepoCloneTask executes the repo-clone action.
+//
+// The clone gets the configuration of the source repository with the new name.
+// When src and tgt are the same server the data is copied server side through
+// internal federation and checked by comparing triple counts; otherwise, or if
+// that fails, the BRF data is streamed from src into the clone.
+func executeRepoCloneTask(run *taskRun) error {
+	task, progress, log, result, srcClient, tgtClient, zitiClient := run.task, run.progress, run.log, run.result, run.srcClient, run.tgtClient, run.zitiClient
+
+	if identityFile != "" {
+		srcURL, err := URL2ServiceRobust(task.Src.URL)
+		if err != nil {
+			return err
+		}
+		srcClient, err = zitiClient(srcURL)
+		if err != nil {
+			return err
+		}
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
+		if err != nil {
+			return err
+		}
+		tgtClient, err = zitiClient(tgtURL)
+		if err != nil {
+			return err
+		}
+	}
+	srcRepo, tgtRepo := task.Src.Repo, task.Tgt.Repo
+	sameServer := normalizeURL(task.Src.URL) == normalizeURL(task.Tgt.URL)
+
+	if err := requireTaskRepository(run.graphDB(srcClient), task.Src, "src"); err != nil {
+		return err
+	}
+	tgtRepos, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	if err != nil {
+		return err
+	}
+	for _, bind := range tgtRepos.Results.Bindings {
+		if bind.Id["value"] == tgtRepo {
+			return fmt.Errorf("target repository '%s' already exists", tgtRepo)
+		}
+	}
+
+	// Create the clone from the source configuration under the new name
+	confFile := run.tempFile(fmt.Sprintf("repo_clone_%s.ttl", uuid.New().String()))
+	if err := graphDBDownloadRepositoryConfig(srcClient, task.Src.URL, task.Src.Username, task.Src.Password, srcRepo, confFile); err != nil {
+		return fmt.Errorf("failed to download configuration of repository '%s': %w", srcRepo, err)
+	}
+	defer func() { _ = os.Remove(confFile) }()
+	if err := updateRepositoryNameInConfig(confFile, srcRepo, tgtRepo); err != nil {
+		return fmt.Errorf("failed to update repository name in config: %w", err)
+	}
+	createClone := func() error {
+		if err := run.graphDB(tgtClient).RestoreConf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, confFile); err != nil {
+			return fmt.Errorf("failed to create repository '%s': %w", tgtRepo, err)
+		}
+		return nil
+	}
+	progress("Creating repository", 1, 1)
+	if err := createClone(); err != nil {
+		return err
+	}
+
+	method := cloneMethodBRFStream
+	if sameServer {
+		if err := run.ctx.Err(); err != nil {
+			return err
+		}
+		progress("Copying data on the server", 1, 1)
+		if err := cloneByFederation(run, tgtClient); err != nil {
+			log.Warn("Server side copy failed, falling back to BRF streaming", "src_repo", srcRepo, "tgt_repo", tgtRepo, "error", err)
+			addResultWarning(result, fmt.Sprintf("Server side copy not used: %v", err))
+
+			// Start the fallback from an empty clone
+			if err := run.graphDB(tgtClient).DeleteRepository(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, tgtRepo); err != nil {
+				return fmt.Errorf("failed to reset repository '%s' after the server side copy failed: %w", tgtRepo, err)
+			}
+			if err := createClone(); err != nil {
+				return err
+			}
+		} else {
+			method = cloneMethodFederation
+		}
+	}
+
+	if method == cloneMethodBRFStream {
+		dataSize, err := graphDBStreamRepositoryData(
+			srcClient, task.Src.URL, task.Src.Username, task.Src.Password, srcRepo,
+			tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, tgtRepo, progress,
+		)
+		if err != nil {
+			// Do not leave an incomplete clone behind
+			_ = run.graphDB(tgtClient).DeleteRepository(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, tgtRepo)
+			return fmt.Errorf("failed to copy data into repository '%s': %w", tgtRepo, err)
+		}
+		result["data_size"] = dataSize
+	}
+
+	result["message"] = "Repository cloned successfully"
+	result["src_repo"] = srcRepo
+	result["tgt_repo"] = tgtRepo
+	result["same_server"] = sameServer
+	result["clone_method"] = method
+	result["fast_path"] = method == cloneMethodFederation
+	return nil
+}
+
+// cloneByFederation copies the data of task.Src.Repo into the new, empty
+// task.Tgt.Repo on the same server and verifies the result by triple count
+func cloneByFederation(run *taskRun, tgtClient *http.Client) error {
+	task := run.task
+	for _, update := range cloneFederationUpdates {
+		if err := sparqlUpdate(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, fmt.Sprintf(update, task.Src.Repo)); err != nil {
+			return err
+		}
+	}
+
+	srcTriples, err := countRepositoryTriples(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Src.Repo)
+	if err != nil {
+		return fmt.Errorf("failed to count triples in repository '%s': %w", task.Src.Repo, err)
+	}
+	tgtTriples, err := countRepositoryTriples(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
+	if err != nil {
+		return fmt.Errorf("failed to count triples in repository '%s': %w", task.Tgt.Repo, err)
+	}
+	if srcTriples != tgtTriples {
+		return fmt.Errorf("triple count mismatch after copy: source has %d, clone has %d", srcTriples, tgtTriples)
+	}
+	run.result["src_triples"] = srcTriples
+	run.result["tgt_triples"] = tgtTriples
+	return nil
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// knownRulesets are the reasoning rulesets shipped with GraphDB
+var knownRulesets = map[string]bool{
+	"empty":               true,
+	"rdfs":                true,
+	"rdfs-optimized":      true,
+	"rdfsplus":            true,
+	"rdfsplus-optimized":  true,
+	"owl-horst":           true,
+	"owl-horst-optimized": true,
+	"owl-max":             true,
+	"owl-max-optimized":   true,
+	"owl2-ql":             true,
+	"owl2-ql-optimized":   true,
+	"owl2-rl":             true,
+	"owl2-rl-optimized":   true,
+}
+
+// repositoryTypes maps the supported repository types to their sail type
+var repositoryTypes = map[string]string{
+	"graphdb": "graphdb:Sail",     // GraphDB 10 and later
+	"free":    "graphdb:FreeSail", // GraphDB 9 Free
+	"se":      "owlim:Sail",       // GraphDB 9 SE/EE
+}
+
+// defaultRepositoryType is used when a repository config is generated without a type
+const defaultRepositoryType = "graphdb"
+
+// repositoryConfigTemplate is a GraphDB SailRepository configuration in Turtle.
+// Placeholders: repository ID, label, sail type, ruleset.
+const repositoryConfigTemplate = `@prefix rdfs: <http://www.w3.org/2000/01/rdf-schema#> .
+@prefix rep: <http://www.openrdf.org/config/repository#> .
+@prefix sr: <http://www.openrdf.org/config/repository/sail#> .
+@prefix sail: <http://www.openrdf.org/config/sail#> .
+@prefix graphdb: <http://www.ontotext.com/config/graphdb#> .
+@prefix owlim: <http://www.ontotext.com/trree/owlim#> .
+
+[] a rep:Repository ;
+   rep:repositoryID "%s" ;
+   rdfs:label "%s" ;
+   rep:repositoryImpl [
+      rep:repositoryType "graphdb:SailRepository" ;
+      sr:sailImpl [
+         sail:sailType "%s" ;
+         graphdb:read-only "false" ;
+         graphdb:ruleset "%s" ;
+      ]
+   ] .
+`
+
+// validateRepositoryTemplate checks the ruleset and repository type used to
+// generate a repository configuration. An empty repository type is allowed.
+func validateRepositoryTemplate(ruleset, repoType string) error {
+	if !knownRulesets[ruleset] {
+		return fmt.Errorf("unknown ruleset '%s' (supported: %s)", ruleset, strings.Join(sortedKeys(knownRulesets), ", "))
+	}
+	if repoType != "" {
+		if _, ok := repositoryTypes[repoType]; !ok {
+			types := make(map[string]bool, len(repositoryTypes))
+			for t := range repositoryTypes {
+				types[t] = true
+			}
+			return fmt.Errorf("unknown repository type '%s' (supported: %s)", repoType, strings.Join(sortedKeys(types), ", "))
+		}
+	}
+	return nil
+}
+
+// generateRepositoryConfig renders a repository configuration for repoID with the
+// given ruleset and repository type (default "graphdb").
+func generateRepositoryConfig(repoID, ruleset, repoType string) (string, error) {
+	if err := validateRepositoryTemplate(ruleset, repoType); err != nil {
+		return "", err
+	}
+	if repoType == "" {
+		repoType = defaultRepositoryType
+	}
+	if strings.ContainsAny(repoID, "\"\\\n") {
+		return "", fmt.Errorf("invalid repository name '%s'", repoID)
+	}
+	return fmt.Sprintf(repositoryConfigTemplate, repoID, repoID, repositoryTypes[repoType], ruleset), nil
+}
+
+// Patterns recognizing the parts of a GraphDB TTL configuration, with either
+// the rep: prefix or the full IRIs of the repository config vocabulary
+var (
+	// repositoryIDPattern matches the repositoryID statement and captures the ID
+	repositoryIDPattern = regexp.MustCompile(`(?:rep:repositoryID|<http://www\.openrdf\.org/config/repository#repositoryID>)\s+"([^"]+)"`)
+	// repositoryNodePattern matches the declaration of the rep:Repository node
+	repositoryNodePattern = regexp.MustCompile(`(?:\sa|rdf:type|<http://www\.w3\.org/1999/02/22-rdf-syntax-ns#type>)\s+(?:rep:Repository\b|<http://www\.openrdf\.org/config/repository#Repository>)`)
+	// repositoryPrefixPattern matches the declaration of the rep: prefix
+	repositoryPrefixPattern = regexp.MustCompile(`(?im)^\s*(?:@prefix|prefix)\s+rep:\s*<http://www\.openrdf\.org/config/repository#>`)
+)
+
+// maxRepositoryConfigBytes bounds how much of an uploaded config is checked
+const maxRepositoryConfigBytes = 1 << 20
+
+// repositoryConfigError lists everything a repository configuration is missing
+type repositoryConfigError struct {
+	problems []string
+}
+
+func (e *repositoryConfigError) Error() string {
+	return strings.Join(e.problems, ", ")
+}
+
+// parseRepositoryConfig checks that a GraphDB TTL configuration declares a
+// rep:Repository node with a rep:repositoryID and returns the ID. It is no full
+// Turtle parser, but catches the mistakes GraphDB would only report as a
+// generic error: files that are not Turtle, a missing rep: prefix, node or ID.
+func parseRepositoryConfig(content []byte) (string, error) {
+	if len(bytes.TrimSpace(content)) == 0 {
+		return "", &repositoryConfigError{problems: []string{"config file is empty"}}
+	}
+	if !utf8.Valid(content) || bytes.IndexByte(content, 0) >= 0 {
+		return "", &repositoryConfigError{problems: []string{"config file is not a Turtle text file"}}
+	}
+
+	var problems []string
+	if bytes.Contains(content, []byte("rep:")) && !repositoryPrefixPattern.Match(content) {
+		problems = append(problems, "missing @prefix declaration for rep: <http://www.openrdf.org/config/repository#>")
+	}
+	if !repositoryNodePattern.Match(content) {
+		problems = append(problems, "missing rep:Repository node")
+	}
+	match := repositoryIDPattern.FindSubmatch(content)
+	if match == nil {
+		problems = append(problems, "missing rep:repositoryID")
+	}
+	if len(problems) > 0 {
+		return "", &repositoryConfigError{problems: problems}
+	}
+	return string(match[1]), nil
+}
+
+// checkUploadedRepositoryConfig checks an uploaded repository configuration
+// before it is sent to GraphDB
+func checkUploadedRepositoryConfig(fileHeader *multipart.FileHeader) error {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open config file %s: %w", fileHeader.Filename, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	content, err := io.ReadAll(io.LimitReader(file, maxRepositoryConfigBytes+1))
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", fileHeader.Filename, err)
+	}
+	if len(content) > maxRepositoryConfigBytes {
+		return fmt.Errorf("config file %s is larger than %d bytes", fileHeader.Filename, maxRepositoryConfigBytes)
+	}
+	if _, err := parseRepositoryConfig(content); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", fileHeader.Filename, err)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of a set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// checkSelectedGraphs checks that every graph listed in src.graphs of a
+// repo-migration exists in the source repository and returns the source graphs
+// that are not selected
+func checkSelectedGraphs(run *taskRun, srcClient *http.Client) ([]string, error) {
+	task := run.task
+	graphsList, err := run.graphDB(srcClient).ListGraphs(task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list graphs in source repository '%s': %w", task.Src.Repo, err)
+	}
+
+	selected := make(map[string]bool, len(task.Src.Graphs))
+	for _, graphURI := range task.Src.Graphs {
+		selected[graphURI] = false
+	}
+	var skipped []string
+	for _, bind := range graphsList.Results.Bindings {
+		graphURI := bind.ContextID.Value
+		if graphURI == "" {
+			continue
+		}
+		if _, ok := selected[graphURI]; ok {
+			selected[graphURI] = true
+		} else {
+			skipped = append(skipped, graphURI)
+		}
+	}
+	for _, graphURI := range task.Src.Graphs {
+		if !selected[graphURI] && !isDefaultGraph(graphURI) {
+			return nil, newTaskError(ErrGraphNotFound, "could not find required src graph %s in repository %s", graphURI, task.Src.Repo)
+		}
+	}
+	sort.Strings(skipped)
+	return skipped, nil
+}
+
+// migrateSelectedGraphs transfers the graphs listed in src.graphs into the
+// target repository, one graph at a time, instead of the BRF data of the whole
+// repository. Each graph is exported to a temporary RDF/XML file and imported
+// into the graph of the same name. A graph that fails is reported and the
+// others are still transferred; the task fails only if none could be.
+func migrateSelectedGraphs(run *taskRun, srcClient, tgtClient *http.Client) (int64, error) {
+	task, progress, result := run.task, run.progress, run.result
+
+	var transferred, failed []string
+	var graphErrors []string
+	var dataSize int64
+	for i, graphURI := range task.Src.Graphs {
+		if err := run.ctx.Err(); err != nil {
+			return 0, err
+		}
+		progress("Transferring graph", i+1, len(task.Src.Graphs))
+		size, err := transferGraph(run, srcClient, tgtClient, graphURI)
+		if err != nil {
+			run.log.Warn("Failed to transfer graph", "graph", graphURI, "error", err)
+			failed = append(failed, graphURI)
+			graphErrors = append(graphErrors, err.Error())
+			continue
+		}
+		transferred = append(transferred, graphURI)
+		dataSize += size
+	}
+
+	result["transferred_graphs"] = transferred
+	if len(failed) > 0 {
+		result["failed_graphs"] = failed
+	}
+	if len(transferred) == 0 {
+		return 0, fmt.Errorf("failed to transfer any graphs: %s", strings.Join(graphErrors, "; "))
+	}
+	if len(failed) > 0 {
+		result["status"] = "completed_with_warning"
+		addResultWarning(result, fmt.Sprintf("%d of %d graphs were not transferred: %s", len(failed), len(task.Src.Graphs), strings.Join(graphErrors, "; ")))
+	}
+	return dataSize, nil
+}
+
+// transferGraph copies one graph of the source repository into the target
+// repository and returns the size of the export
+func transferGraph(run *taskRun, srcClient, tgtClient *http.Client, graphURI string) (int64, error) {
+	task := run.task
+	fileName := run.tempFile(fmt.Sprintf("repo_migration_%s.rdf", uuid.New().String()))
+	defer func() { _ = os.Remove(fileName) }()
+
+	done := run.timeStep(stepExportGraphs)
+	err := run.graphDB(srcClient).ExportGraphRdf(task.Src.URL, task.Src.Username, task.Src.Password, task.Src.Repo, graphURI, fileName)
+	done()
+	if err != nil {
+		return 0, fmt.Errorf("failed to export graph '%s': %v", graphURI, err)
+	}
+	info, err := os.Stat(fileName)
+	if err != nil || info.Size() == 0 {
+		return 0, fmt.Errorf("graph '%s' export file is empty or missing", graphURI)
+	}
+	done = run.timeStep(stepImportGraphs)
+	err = importExportedGraph(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, graphURI, fileName, rdfContentTypes["rdf-xml"])
+	done()
+	if err != nil {
+		return 0, fmt.Errorf("failed to import graph '%s': %v", graphURI, err)
+	}
+	return info.Size(), nil
+}
+
+// selectedGraphTriples sums the triple counts of the transferred graphs of a
+// selective repo-migration in a repository
+func selectedGraphTriples(client *http.Client, repo *Repository, graphs []string) (int, error) {
+	total := 0
+	for _, graphURI := range graphs {
+		count, err := countGraphTriples(client, repo.URL, repo.Username, repo.Password, repo.Repo, graphURI)
+		if err != nil {
+			return -1, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// validateSelectedGraphs checks the src.graphs of a repo-migration: no empty or
+// repeated graph URIs
+func validateSelectedGraphs(graphs []string) error {
+	seen := make(map[string]bool, len(graphs))
+	for _, graphURI := range graphs {
+		if strings.TrimSpace(graphURI) == "" {
+			return &taskFieldError{Field: "src.graphs", Message: "src.graphs must not contain empty graph URIs"}
+		}
+		if seen[graphURI] {
+			return &taskFieldError{Field: "src.graphs", Message: fmt.Sprintf("graph '%s' is listed more than once in src.graphs", graphURI)}
+		}
+		seen[graphURI] = true
+	}
+	return nil
+}
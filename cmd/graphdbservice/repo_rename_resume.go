@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// States of a graph in a repo-rename tracked by a backup manifest
+const (
+	renameGraphExported = "exported" // export retained in the backup, not imported yet
+	renameGraphImported = "imported" // imported into the new repository
+	renameGraphFailed   = "failed"   // export or import failed
+)
+
+// repoRenameGraphsDir is the directory of a backup holding the retained graph exports
+const repoRenameGraphsDir = "graphs"
+
+// repoRenameGraph tracks one graph of a repo-rename run with keep_backup
+type repoRenameGraph struct {
+	Graph  string `json:"graph"`
+	File   string `json:"file,omitempty"` // Export file relative to the backup directory, "" if the export failed
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// exportGraphForRename exports one graph of a repository to a temporary RDF/XML
+// file, compressed when the task asks for it, and returns the file name
+func exportGraphForRename(run *taskRun, tgtClient *http.Client, compression *exportCompression, repo, graphURI string) (string, error) {
+	task := run.task
+	// Create a unique filename for each graph using UUID to avoid conflicts
+	graphFileName := run.tempFile(fmt.Sprintf("repo_rename_%s.rdf", uuid.New().String()))
+
+	err := run.graphDB(tgtClient).ExportGraphRdf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repo, graphURI, graphFileName)
+	if err != nil {
+		_ = os.Remove(graphFileName)
+		return "", fmt.Errorf("failed to export graph '%s': %v", graphURI, err)
+	}
+
+	// Verify the export file was created and has content
+	if fileInfo, err := os.Stat(graphFileName); err != nil || fileInfo.Size() == 0 {
+		_ = os.Remove(graphFileName) // Clean up empty file
+		return "", fmt.Errorf("graph '%s' export file is empty or missing", graphURI)
+	}
+
+	// Exports are held until all graphs are imported, so large ones are kept compressed
+	importFileName, err := compression.compressExport(task, graphFileName)
+	if err != nil {
+		_ = os.Remove(graphFileName)
+		return "", fmt.Errorf("failed to compress graph '%s': %v", graphURI, err)
+	}
+	return importFileName, nil
+}
+
+// graph returns the tracking entry of a graph, adding it if it is not tracked yet
+func (m *repoBackupManifest) graph(graphURI string) *repoRenameGraph {
+	for i := range m.Graphs {
+		if m.Graphs[i].Graph == graphURI {
+			return &m.Graphs[i]
+		}
+	}
+	m.Graphs = append(m.Graphs, repoRenameGraph{Graph: graphURI})
+	return &m.Graphs[len(m.Graphs)-1]
+}
+
+// setGraphStatus records the state of a graph; err explains a failure
+func (m *repoBackupManifest) setGraphStatus(graphURI, status string, err error) {
+	g := m.graph(graphURI)
+	g.Status = status
+	g.Error = ""
+	if err != nil {
+		g.Error = err.Error()
+	}
+}
+
+// keepGraphExport moves an export file of a graph into the backup directory
+// and returns its new path
+func (m *repoBackupManifest) keepGraphExport(graphURI, fileName string) (string, error) {
+	if err := os.MkdirAll(filepath.Join(m.dir, repoRenameGraphsDir), 0o750); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	// Graph URIs are no valid file names, so files are numbered in manifest order
+	g := m.graph(graphURI)
+	index := 0
+	for i := range m.Graphs {
+		if &m.Graphs[i] == g {
+			index = i
+		}
+	}
+	name := strconv.Itoa(index) + ".rdf"
+	if isGzipFile(fileName) {
+		name += ".gz"
+	}
+	relative := filepath.Join(repoRenameGraphsDir, name)
+	kept := filepath.Join(m.dir, relative)
+
+	// Temporary files may live on another file system, where rename fails
+	if err := os.Rename(fileName, kept); err != nil {
+		if _, err := copyFile(fileName, kept); err != nil {
+			return "", fmt.Errorf("failed to retain export of graph '%s': %w", graphURI, err)
+		}
+		_ = os.Remove(fileName)
+	}
+	g.File = relative
+	g.Status = renameGraphExported
+	return kept, nil
+}
+
+// resumeRepoRename continues a repo-rename that kept a backup (keep_backup) and
+// did not transfer every graph. Graphs not yet imported into the new repository
+// are imported from the retained exports; graphs whose export failed are
+// exported again if the old repository still exists. The old repository is
+// deleted once all graphs are transferred, or with force.
+func resumeRepoRename(run *taskRun, tgtClient *http.Client) error {
+	task, progress, log, result := run.task, run.progress, run.log, run.result
+	oldRepoName, newRepoName := task.Tgt.RepoOld, task.Tgt.RepoNew
+
+	manifest, dir, err := loadRepoBackup(task.BackupID)
+	if err != nil {
+		return err
+	}
+	if manifest.Repository != oldRepoName || manifest.RenamedTo != newRepoName {
+		return fmt.Errorf("backup '%s' does not belong to renaming '%s' to '%s'", task.BackupID, oldRepoName, newRepoName)
+	}
+
+	repos, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	if err != nil {
+		return err
+	}
+	oldExists, newExists := false, false
+	for _, bind := range repos.Results.Bindings {
+		switch bind.Id["value"] {
+		case oldRepoName:
+			oldExists = true
+		case newRepoName:
+			newExists = true
+		}
+	}
+
+	var pending []*repoRenameGraph
+	for i := range manifest.Graphs {
+		if manifest.Graphs[i].Status != renameGraphImported {
+			pending = append(pending, &manifest.Graphs[i])
+		}
+	}
+	resumedFrom := len(manifest.Graphs) - len(pending)
+
+	if task.DryRun {
+		var operations []map[string]interface{}
+		if !newExists {
+			operations = append(operations, plannedOperation("create-repository", newRepoName, "", -1))
+		}
+		for _, g := range pending {
+			if g.File == "" {
+				operations = append(operations, plannedOperation("export-graph", oldRepoName, g.Graph, -1))
+			}
+			operations = append(operations, plannedOperation("import-graph", newRepoName, g.Graph, -1))
+		}
+		if oldExists {
+			operations = append(operations, plannedOperation("delete-repository", oldRepoName, "", -1))
+		}
+		setDryRunResult(result, "Dry run: repository rename would be resumed", operations)
+		result["backup_id"] = manifest.ID
+		result["resumed_from"] = resumedFrom
+		result["old_name"] = oldRepoName
+		result["new_name"] = newRepoName
+		return nil
+	}
+
+	// The interrupted run may have failed before the new repository was created
+	if !newExists {
+		confFile := run.tempFile(fmt.Sprintf("repo_rename_%s.ttl", uuid.New().String()))
+		if _, err := copyFile(filepath.Join(dir, manifest.ConfigFile), confFile); err != nil {
+			return fmt.Errorf("failed to read backup configuration: %w", err)
+		}
+		defer func() { _ = os.Remove(confFile) }()
+		if err := updateRepositoryNameInConfig(confFile, oldRepoName, newRepoName); err != nil {
+			return fmt.Errorf("failed to update repository name in config: %w", err)
+		}
+		if err := run.ctx.Err(); err != nil {
+			return err
+		}
+		progress("Creating repository", 1, 1)
+		if err := run.graphDB(tgtClient).RestoreConf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, confFile); err != nil {
+			return fmt.Errorf("failed to create new repository '%s': %w", newRepoName, err)
+		}
+	}
+
+	var compression exportCompression
+	var completed, failedGraphs, graphErrors []string
+	for i, g := range pending {
+		if err := run.ctx.Err(); err != nil {
+			return err
+		}
+		progress("Resuming graph", i+1, len(pending))
+
+		fileName := ""
+		if g.File != "" {
+			fileName = filepath.Join(dir, g.File)
+		} else if oldExists {
+			exportFile, err := exportGraphForRename(run, tgtClient, &compression, oldRepoName, g.Graph)
+			if err == nil {
+				fileName, err = manifest.keepGraphExport(g.Graph, exportFile)
+			}
+			if err != nil {
+				_ = os.Remove(exportFile)
+				manifest.setGraphStatus(g.Graph, renameGraphFailed, err)
+				graphErrors = append(graphErrors, err.Error())
+				failedGraphs = append(failedGraphs, g.Graph)
+				continue
+			}
+		} else {
+			err := fmt.Errorf("graph '%s' has no retained export and repository '%s' no longer exists", g.Graph, oldRepoName)
+			manifest.setGraphStatus(g.Graph, renameGraphFailed, err)
+			graphErrors = append(graphErrors, err.Error())
+			failedGraphs = append(failedGraphs, g.Graph)
+			continue
+		}
+
+		// The new repository may already hold part of the graph from the interrupted run
+		if err := run.graphDB(tgtClient).DeleteGraph(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, newRepoName, g.Graph); err != nil {
+			log.Warn("Failed to clear graph before resuming its import", "graph", g.Graph, "error", err)
+		}
+		if err := importExportedGraph(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, newRepoName, g.Graph, fileName, rdfContentTypes["rdf-xml"]); err != nil {
+			err = fmt.Errorf("failed to import graph '%s': %v", g.Graph, err)
+			manifest.setGraphStatus(g.Graph, renameGraphFailed, err)
+			graphErrors = append(graphErrors, err.Error())
+			failedGraphs = append(failedGraphs, g.Graph)
+			continue
+		}
+		manifest.setGraphStatus(g.Graph, renameGraphImported, nil)
+		completed = append(completed, g.Graph)
+	}
+	if err := manifest.save(); err != nil {
+		log.Warn("Failed to update backup manifest", "backup_id", manifest.ID, "error", err)
+	}
+
+	sort.Strings(failedGraphs)
+	partial := len(failedGraphs) > 0
+	oldRepoDeleted := false
+	switch {
+	case !oldExists:
+		result["message"] = "Repository rename resumed"
+	case partial && !task.Force:
+		log.Warn("Keeping old repository because some graphs were not transferred", "old_repo", oldRepoName, "failed_graphs", len(failedGraphs))
+		result["message"] = "Repository rename resumed, old repository kept because some graphs were not transferred"
+	default:
+		if err := run.ctx.Err(); err != nil {
+			return err
+		}
+		progress("Deleting old repository", 1, 1)
+		if err := run.graphDB(tgtClient).DeleteRepository(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, oldRepoName); err != nil {
+			log.Warn("Failed to delete old repository", "old_repo", oldRepoName, "error", err)
+			addResultWarning(result, fmt.Sprintf("New repository completed, but failed to delete old repository: %v", err))
+		} else {
+			oldRepoDeleted = true
+		}
+		result["message"] = "Repository rename resumed and completed"
+	}
+
+	if partial {
+		result["status"] = "partial"
+		result["failed_graphs"] = failedGraphs
+		addResultWarning(result, fmt.Sprintf("Some graphs were not transferred: %s", strings.Join(graphErrors, "; ")))
+	}
+	if completed == nil {
+		completed = []string{}
+	}
+	result["backup_id"] = manifest.ID
+	result["resumed_from"] = resumedFrom
+	result["completed_graphs"] = completed
+	result["old_repository_deleted"] = oldRepoDeleted
+	result["old_name"] = oldRepoName
+	result["new_name"] = newRepoName
+	result["total_graphs"] = len(manifest.Graphs)
+	compression.report(result)
+	return nil
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Per repository states reported by repos-create
+const (
+	reposCreateCreated = "created"
+	reposCreateSkipped = "skipped" // already existed, with if_not_exists
+	reposCreateFailed  = "failed"
+)
+
+// executeReposCreateTask executes the repos-create action.
+//
+// Every repository of tgt.repos is created from the same configuration: the
+// uploaded config template with its repository ID replaced by the name, or a
+// config generated for tgt.ruleset. All repositories are attempted and each
+// is reported; the task fails only if none could be created.
+func executeReposCreateTask(run *taskRun) error {
+	task, progress, log, result, tgtClient, zitiClient := run.task, run.progress, run.log, run.result, run.tgtClient, run.zitiClient
+
+	if identityFile != "" {
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
+		if err != nil {
+			return err
+		}
+		tgtClient, err = zitiClient(tgtURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	template, templateID, configSource, err := reposCreateTemplate(run)
+	if err != nil {
+		return err
+	}
+
+	existingRepos, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(existingRepos.Results.Bindings))
+	for _, bind := range existingRepos.Results.Bindings {
+		existing[bind.Id["value"]] = true
+	}
+
+	repoResults := make([]map[string]interface{}, 0, len(task.Tgt.Repos))
+	var created, skipped, failed []string
+	fail := func(repoName string, err error) {
+		log.Warn("Failed to create repository", "repo", repoName, "error", err)
+		failed = append(failed, repoName)
+		repoResults = append(repoResults, map[string]interface{}{"repo": repoName, "status": reposCreateFailed, "error": err.Error()})
+	}
+	for i, repoName := range task.Tgt.Repos {
+		if err := run.ctx.Err(); err != nil {
+			return err
+		}
+		progress("Creating repositories", i+1, len(task.Tgt.Repos))
+		if existing[repoName] {
+			if task.IfNotExists {
+				skipped = append(skipped, repoName)
+				repoResults = append(repoResults, map[string]interface{}{"repo": repoName, "status": reposCreateSkipped})
+			} else {
+				fail(repoName, fmt.Errorf("repository '%s' already exists", repoName))
+			}
+			continue
+		}
+		if err := createRepositoryFromTemplate(run, tgtClient, template, templateID, repoName); err != nil {
+			fail(repoName, err)
+			continue
+		}
+		created = append(created, repoName)
+		repoResults = append(repoResults, map[string]interface{}{"repo": repoName, "status": reposCreateCreated})
+	}
+
+	// Check that the created repositories are listed by the server
+	if len(created) > 0 {
+		verifyRepos, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+		if err != nil {
+			return err
+		}
+		listed := make(map[string]bool, len(verifyRepos.Results.Bindings))
+		for _, bind := range verifyRepos.Results.Bindings {
+			listed[bind.Id["value"]] = true
+		}
+		verified := created[:0]
+		for _, repoName := range created {
+			if listed[repoName] {
+				verified = append(verified, repoName)
+				continue
+			}
+			failed = append(failed, repoName)
+			for _, repoResult := range repoResults {
+				if repoResult["repo"] == repoName {
+					repoResult["status"] = reposCreateFailed
+					repoResult["error"] = fmt.Sprintf("repository '%s' was not created successfully", repoName)
+				}
+			}
+		}
+		created = verified
+	}
+
+	result["config_file"] = configSource
+	result["created_repos"] = created
+	result["skipped_repos"] = skipped
+	result["repo_results"] = repoResults
+	if len(failed) > 0 {
+		result["failed_repos"] = failed
+		if len(created) == 0 && len(skipped) == 0 {
+			return fmt.Errorf("failed to create any of %d repositories: %s", len(task.Tgt.Repos), strings.Join(failed, ", "))
+		}
+		result["status"] = "partial"
+		result["message"] = fmt.Sprintf("Created %d of %d repositories", len(created), len(task.Tgt.Repos))
+		return nil
+	}
+	result["message"] = "Repositories created successfully"
+	return nil
+}
+
+// reposCreateTemplate returns the uploaded config template of a repos-create
+// task with its repository ID, or nil without an upload, when the config is
+// generated for tgt.ruleset. configSource names where the config came from.
+func reposCreateTemplate(run *taskRun) (template []byte, templateID, configSource string, err error) {
+	task := run.task
+	taskFiles := run.files[fmt.Sprintf("task_%d_config", run.taskIndex)]
+	if len(taskFiles) == 0 {
+		if task.Tgt.Ruleset == "" {
+			return nil, "", "", fmt.Errorf("repos-create requires a configuration file with key 'task_%d_config' or a ruleset", run.taskIndex)
+		}
+		run.result["ruleset"] = task.Tgt.Ruleset
+		return nil, "", "generated", nil
+	}
+
+	fileHeader := taskFiles[0]
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to open config file %s: %w", fileHeader.Filename, err)
+	}
+	defer func() { _ = file.Close() }()
+	template, err = io.ReadAll(io.LimitReader(file, maxRepositoryConfigBytes+1))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to read config file %s: %w", fileHeader.Filename, err)
+	}
+	if len(template) > maxRepositoryConfigBytes {
+		return nil, "", "", fmt.Errorf("config file %s is larger than %d bytes", fileHeader.Filename, maxRepositoryConfigBytes)
+	}
+	templateID, err = parseRepositoryConfig(template)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("invalid config file %s: %w", fileHeader.Filename, err)
+	}
+	return template, templateID, fileHeader.Filename, nil
+}
+
+// createRepositoryFromTemplate creates one repository of a repos-create task
+// from a copy of the template renamed to repoName, or from a generated config
+// if there is no template
+func createRepositoryFromTemplate(run *taskRun, tgtClient *http.Client, template []byte, templateID, repoName string) error {
+	task := run.task
+	configFile := run.tempFile(fmt.Sprintf("repos_create_%s.ttl", uuid.New().String()))
+	defer func() { _ = os.Remove(configFile) }()
+
+	config := template
+	if template == nil {
+		generated, err := generateRepositoryConfig(repoName, task.Tgt.Ruleset, task.Tgt.RepoType)
+		if err != nil {
+			return err
+		}
+		config = []byte(generated)
+	}
+	if err := os.WriteFile(configFile, config, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if template != nil && templateID != repoName {
+		if err := updateRepositoryNameInConfig(configFile, templateID, repoName); err != nil {
+			return fmt.Errorf("failed to set repository name in config: %w", err)
+		}
+	}
+	if err := run.graphDB(tgtClient).RestoreConf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, configFile); err != nil {
+		return fmt.Errorf("failed to create repository '%s': %w", repoName, err)
+	}
+	return nil
+}
+
+// validateRepositoryNames checks the tgt.repos of a repos-create: no empty or
+// repeated repository names
+func validateRepositoryNames(repos []string) error {
+	seen := make(map[string]bool, len(repos))
+	for _, repoName := range repos {
+		if strings.TrimSpace(repoName) == "" {
+			return &taskFieldError{Field: "tgt.repos", Message: "tgt.repos must not contain empty repository names"}
+		}
+		if seen[repoName] {
+			return &taskFieldError{Field: "tgt.repos", Message: fmt.Sprintf("repository '%s' is listed more than once in tgt.repos", repoName)}
+		}
+		seen[repoName] = true
+	}
+	return nil
+}
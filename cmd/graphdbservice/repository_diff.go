@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RepositoryDiffSide holds the connection details and repository of one side of a diff
+type RepositoryDiffSide struct {
+	GraphDBConnectionRequest
+	Repo string `json:"repo"`
+}
+
+// RepositoryDiffRequest is the body of POST /v1/api/repositories/diff
+type RepositoryDiffRequest struct {
+	Source       RepositoryDiffSide `json:"source"`
+	Target       RepositoryDiffSide `json:"target"`
+	TripleCounts bool               `json:"triple_counts,omitempty"` // Compare the triple counts of the graphs in both repositories
+	Summary      bool               `json:"summary,omitempty"`       // Only return counts, no graph lists
+}
+
+// GraphCountDiff is a graph whose triple count differs between the repositories
+type GraphCountDiff struct {
+	Graph         string `json:"graph"`
+	SourceTriples *int   `json:"source_triples"` // Null if the count could not be determined
+	TargetTriples *int   `json:"target_triples"`
+	Difference    *int   `json:"difference,omitempty"` // Target minus source triples
+}
+
+// repositoryDiffGraphs holds the graphs of one side of a diff
+type repositoryDiffGraphs struct {
+	side   *RepositoryDiffSide
+	client *http.Client
+	graphs map[string]bool
+}
+
+// loadDiffSide checks the repository of one side of a diff and lists its
+// graphs, including "default" if the default graph has statements. On failure
+// it returns the HTTP status to respond with.
+func loadDiffSide(name string, side *RepositoryDiffSide) (*repositoryDiffGraphs, int, error) {
+	if side.URL == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("%s.url is required", name)
+	}
+	if side.Repo == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("%s.repo is required", name)
+	}
+	side.URL = normalizeURL(side.URL)
+
+	client, err := graphDBClientFor(side.URL)
+	if err != nil {
+		return nil, http.StatusBadGateway, fmt.Errorf("%s: failed to connect to %s: %w", name, side.URL, err)
+	}
+	if status, err := requireRepository(client, &side.GraphDBConnectionRequest, side.Repo); err != nil {
+		return nil, status, fmt.Errorf("%s: %w", name, err)
+	}
+	listing, err := graphDBWith(client).ListGraphs(side.URL, side.Username, side.Password, side.Repo)
+	if err != nil {
+		return nil, http.StatusBadGateway, fmt.Errorf("%s: failed to list graphs in repository '%s': %w", name, side.Repo, err)
+	}
+
+	graphs := make(map[string]bool)
+	for _, graph := range repositoryGraphs(client, side.URL, side.Username, side.Password, side.Repo, listing) {
+		graphs[graph] = true
+	}
+	return &repositoryDiffGraphs{side: side, client: client, graphs: graphs}, http.StatusOK, nil
+}
+
+// count returns the number of triples in a graph, or nil if it could not be determined
+func (d *repositoryDiffGraphs) count(graph string) *int {
+	count, err := countGraphTriples(d.client, d.side.URL, d.side.Username, d.side.Password, d.side.Repo, graph)
+	if err != nil {
+		debugLog("Failed to count triples in graph %s of repository %s: %v", graph, d.side.Repo, err)
+		return nil
+	}
+	return &count
+}
+
+// totalTriples returns the number of triples in the repository, or nil if it could not be determined
+func (d *repositoryDiffGraphs) totalTriples() *int {
+	count, err := countRepositoryTriples(d.client, d.side.URL, d.side.Username, d.side.Password, d.side.Repo)
+	if err != nil {
+		debugLog("Failed to count triples in repository %s: %v", d.side.Repo, err)
+		return nil
+	}
+	return &count
+}
+
+// missingGraphs returns the graphs of a that b lacks, sorted
+func missingGraphs(a, b map[string]bool) []string {
+	graphs := []string{}
+	for graph := range a {
+		if !b[graph] {
+			graphs = append(graphs, graph)
+		}
+	}
+	sort.Strings(graphs)
+	return graphs
+}
+
+// diffRepositoriesREST handles REST POST /v1/api/repositories/diff
+//
+// The JSON body holds the source and target connection details (url, username,
+// password, repo). The response lists the graphs only in the source, only in
+// the target and in both. With triple_counts the graphs in both are counted
+// and the ones with different counts are listed in triple_differences. In
+// summary mode only the numbers of graphs and the total triple counts of the
+// repositories are returned, at the cost of one COUNT query per repository.
+// Nothing is written to either repository.
+func diffRepositoriesREST(c echo.Context) error {
+	var req RepositoryDiffRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid request: %v", err)})
+	}
+	if req.Summary && req.TripleCounts {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "summary and triple_counts cannot be combined"})
+	}
+
+	src, status, err := loadDiffSide("source", &req.Source)
+	if err != nil {
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+	tgt, status, err := loadDiffSide("target", &req.Target)
+	if err != nil {
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+
+	onlyInSource := missingGraphs(src.graphs, tgt.graphs)
+	onlyInTarget := missingGraphs(tgt.graphs, src.graphs)
+	inBoth := []string{}
+	for graph := range src.graphs {
+		if tgt.graphs[graph] {
+			inBoth = append(inBoth, graph)
+		}
+	}
+	sort.Strings(inBoth)
+
+	sideInfo := func(d *repositoryDiffGraphs) map[string]interface{} {
+		return map[string]interface{}{
+			"server":     d.side.URL,
+			"repository": d.side.Repo,
+			"graphs":     len(d.graphs),
+		}
+	}
+	response := map[string]interface{}{
+		"source": sideInfo(src),
+		"target": sideInfo(tgt),
+	}
+	identical := len(onlyInSource) == 0 && len(onlyInTarget) == 0
+
+	if req.Summary {
+		srcTriples, tgtTriples := src.totalTriples(), tgt.totalTriples()
+		response["source"].(map[string]interface{})["triples"] = srcTriples
+		response["target"].(map[string]interface{})["triples"] = tgtTriples
+		response["only_in_source_count"] = len(onlyInSource)
+		response["only_in_target_count"] = len(onlyInTarget)
+		response["in_both_count"] = len(inBoth)
+		if srcTriples != nil && tgtTriples != nil && *srcTriples != *tgtTriples {
+			identical = false
+		}
+		response["identical"] = identical
+		return c.JSON(http.StatusOK, response)
+	}
+
+	response["only_in_source"] = onlyInSource
+	response["only_in_target"] = onlyInTarget
+	response["in_both"] = inBoth
+	if req.TripleCounts {
+		differences := []GraphCountDiff{}
+		for _, graph := range inBoth {
+			srcCount, tgtCount := src.count(graph), tgt.count(graph)
+			if srcCount != nil && tgtCount != nil && *srcCount == *tgtCount {
+				continue
+			}
+			diff := GraphCountDiff{Graph: graph, SourceTriples: srcCount, TargetTriples: tgtCount}
+			if srcCount != nil && tgtCount != nil {
+				difference := *tgtCount - *srcCount
+				diff.Difference = &difference
+			}
+			differences = append(differences, diff)
+		}
+		response["triple_differences"] = differences
+		identical = identical && len(differences) == 0
+	}
+	response["identical"] = identical
+	return c.JSON(http.StatusOK, response)
+}
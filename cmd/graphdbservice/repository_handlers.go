@@ -0,0 +1,384 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// GraphDBConnectionRequest holds the connection details for read-only discovery endpoints.
+// The fields can be passed as query parameters or as a small JSON body.
+type GraphDBConnectionRequest struct {
+	URL      string `json:"url" query:"url"`
+	Username string `json:"username" query:"username"`
+	Password string `json:"password" query:"password"`
+}
+
+// GraphInfo describes a named graph in a repository
+type GraphInfo struct {
+	URI     string `json:"uri"`
+	Triples *int   `json:"triples,omitempty"` // Omitted if the count could not be determined
+}
+
+// RepositoryInfo describes a repository on a GraphDB server
+type RepositoryInfo struct {
+	ID       string `json:"id"`
+	Title    string `json:"title,omitempty"`
+	Readable bool   `json:"readable"`
+	Writable bool   `json:"writable"`
+}
+
+// registerRepositoryEndpoints adds read-only discovery endpoints for repositories and graphs
+func registerRepositoryEndpoints(apiGroup *echo.Group, apiKeyMiddleware echo.MiddlewareFunc) {
+	var middleware []echo.MiddlewareFunc
+	if apiKeyMiddleware != nil {
+		middleware = append(middleware, apiKeyMiddleware)
+	}
+
+	// GET /v1/api/server-info - Version and edition of a GraphDB server
+	apiGroup.GET("/server-info", serverInfoREST, middleware...)
+
+	// GET /v1/api/repositories - List repositories on a GraphDB server
+	apiGroup.GET("/repositories", listRepositoriesREST, middleware...)
+
+	// GET /v1/api/repositories/:repo/graphs - List named graphs in a repository
+	apiGroup.GET("/repositories/:repo/graphs", listGraphsREST, middleware...)
+
+	// GET /v1/api/repositories/:repo/graphs/export - Download a named graph in a chosen RDF format
+	apiGroup.GET("/repositories/:repo/graphs/export", exportGraphREST, middleware...)
+
+	// GET /v1/api/repositories/:repo/export - Download a repository backup (BRF) or its config (TTL)
+	apiGroup.GET("/repositories/:repo/export", exportRepositoryREST, middleware...)
+
+	// POST /v1/api/repositories/diff - Compare the graphs of two repositories
+	apiGroup.POST("/repositories/diff", diffRepositoriesREST, middleware...)
+}
+
+// bindConnectionRequest reads and validates the connection details of a discovery request
+func bindConnectionRequest(c echo.Context) (*GraphDBConnectionRequest, error) {
+	var req GraphDBConnectionRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if req.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	req.URL = normalizeURL(req.URL)
+	return &req, nil
+}
+
+// graphDBClientFor returns the HTTP client used to reach a GraphDB server,
+// using Ziti when an identity file is configured.
+func graphDBClientFor(serverURL string) (*http.Client, error) {
+	client := http.DefaultClient
+	if identityFile != "" {
+		serviceURL, err := URL2ServiceRobust(serverURL)
+		if err != nil {
+			return nil, err
+		}
+		client, err = graphDBClients.get(identityFile, serviceURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if debugMode {
+		client = enableHTTPDebugLogging(client)
+	}
+	return client, nil
+}
+
+// requireRepository checks that repo exists on the server of req.
+// On failure it returns the HTTP status to respond with.
+func requireRepository(client *http.Client, req *GraphDBConnectionRequest, repo string) (int, error) {
+	repos, err := graphDBWith(client).Repositories(req.URL, req.Username, req.Password)
+	if err != nil {
+		return http.StatusBadGateway, fmt.Errorf("failed to fetch repositories from %s: %w", req.URL, err)
+	}
+	for _, bind := range repos.Results.Bindings {
+		if bind.Id["value"] == repo {
+			return http.StatusOK, nil
+		}
+	}
+	return http.StatusNotFound, fmt.Errorf("repository '%s' not found on server %s", repo, req.URL)
+}
+
+// serverInfoREST handles REST GET /v1/api/server-info
+//
+// Query parameters: url, username, password.
+func serverInfoREST(c echo.Context) error {
+	req, err := bindConnectionRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	client, err := graphDBClientFor(req.URL)
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": fmt.Sprintf("Failed to connect to %s: %v", req.URL, err)})
+	}
+
+	info, err := graphDBServerInfo(client, req.URL, req.Username, req.Password)
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"server":        req.URL,
+		"version":       info.Version,
+		"major_version": info.MajorVersion(),
+		"rdf_star":      info.SupportsRDFStar(),
+		"edition":       info.Edition,
+		"workbench":     info.Workbench,
+		"rdf4j":         info.RDF4J,
+		"connectors":    info.Connectors,
+	})
+}
+
+// listRepositoriesREST handles REST GET /v1/api/repositories
+//
+// Query parameters: url, username, password.
+func listRepositoriesREST(c echo.Context) error {
+	req, err := bindConnectionRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	client, err := graphDBClientFor(req.URL)
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": fmt.Sprintf("Failed to connect to %s: %v", req.URL, err)})
+	}
+
+	repos, err := graphDBWith(client).Repositories(req.URL, req.Username, req.Password)
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": fmt.Sprintf("Failed to fetch repositories from %s: %v", req.URL, err)})
+	}
+
+	repositories := make([]RepositoryInfo, 0, len(repos.Results.Bindings))
+	for _, bind := range repos.Results.Bindings {
+		id, exists := bind.Id["value"]
+		if !exists {
+			continue
+		}
+		repositories = append(repositories, RepositoryInfo{
+			ID:       id,
+			Title:    bind.Title["value"],
+			Readable: bind.Readable["value"] == "true",
+			Writable: bind.Writable["value"] == "true",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"server":       req.URL,
+		"count":        len(repositories),
+		"repositories": repositories,
+	})
+}
+
+// listGraphsREST handles REST GET /v1/api/repositories/:repo/graphs
+//
+// Query parameters: url, username, password and an optional prefix to only
+// return graphs whose URI starts with it.
+func listGraphsREST(c echo.Context) error {
+	repo := c.Param("repo")
+	if repo == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "repo is required"})
+	}
+
+	req, err := bindConnectionRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	prefix := c.QueryParam("prefix")
+
+	client, err := graphDBClientFor(req.URL)
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": fmt.Sprintf("Failed to connect to %s: %v", req.URL, err)})
+	}
+
+	if status, err := requireRepository(client, req, repo); err != nil {
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+
+	graphsList, err := graphDBWith(client).ListGraphs(req.URL, req.Username, req.Password, repo)
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": fmt.Sprintf("Failed to list graphs in repository '%s': %v", repo, err)})
+	}
+
+	graphs := make([]GraphInfo, 0, len(graphsList.Results.Bindings))
+	for _, bind := range graphsList.Results.Bindings {
+		graphURI := bind.ContextID.Value
+		if graphURI == "" || !strings.HasPrefix(graphURI, prefix) {
+			continue
+		}
+
+		info := GraphInfo{URI: graphURI}
+		if count, err := countGraphTriples(client, req.URL, req.Username, req.Password, repo, graphURI); err == nil {
+			info.Triples = &count
+		} else {
+			debugLog("Failed to count triples in graph %s: %v", graphURI, err)
+		}
+		graphs = append(graphs, info)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"repository": repo,
+		"count":      len(graphs),
+		"graphs":     graphs,
+	})
+}
+
+// exportRepositoryREST handles REST GET /v1/api/repositories/:repo/export
+//
+// Query parameters: url, username, password and an optional format: "brf"
+// (default, binary RDF backup of all statements) or "ttl" (repository config).
+// The file is streamed as an attachment named <repo>.<format>.
+func exportRepositoryREST(c echo.Context) error {
+	repo := c.Param("repo")
+	if repo == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "repo is required"})
+	}
+
+	req, err := bindConnectionRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	format := strings.ToLower(c.QueryParam("format"))
+	if format == "" {
+		format = "brf"
+	}
+	if format != "brf" && format != "ttl" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("unsupported format '%s' (supported: brf, ttl)", format)})
+	}
+
+	client, err := graphDBClientFor(req.URL)
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": fmt.Sprintf("Failed to connect to %s: %v", req.URL, err)})
+	}
+
+	if status, err := requireRepository(client, req, repo); err != nil {
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+
+	exportFile := filepath.Join(migrationTempDir, fmt.Sprintf("repo_export_%s.%s", uuid.New().String(), format))
+	var contentType string
+	if format == "ttl" {
+		err = graphDBDownloadRepositoryConfig(client, req.URL, req.Username, req.Password, repo, exportFile)
+		contentType = "text/turtle"
+	} else {
+		_, err = graphDBDownloadRepositoryData(client, req.URL, req.Username, req.Password, repo, exportFile)
+		contentType = "application/x-binary-rdf"
+	}
+	// The temp file is removed once the response is written or the client disconnected
+	defer func() { _ = os.Remove(exportFile) }()
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": fmt.Sprintf("Failed to export repository '%s': %v", repo, err)})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, contentType)
+	return c.Attachment(exportFile, fmt.Sprintf("%s.%s", repo, format))
+}
+
+// graphExportFileName derives a download file name from the last segment of a graph URI
+func graphExportFileName(graphURI, ext string) string {
+	name := strings.TrimRight(graphURI, "/#")
+	if i := strings.LastIndexAny(name, "/#:"); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, name)
+	if name == "" {
+		name = "graph"
+	}
+	return name + ext
+}
+
+// exportGraphREST handles REST GET /v1/api/repositories/:repo/graphs/export
+//
+// Query parameters: url, username, password, graph (URI of the named graph) and
+// an optional format (default: turtle), one of the types known to getFileType
+// such as turtle, n-triples, rdf-xml or json-ld. The optional accept overrides
+// the MIME type requested from GraphDB, e.g. application/x-turtle. The serialized
+// graph is streamed as an attachment with the requested content type.
+func exportGraphREST(c echo.Context) error {
+	repo := c.Param("repo")
+	if repo == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "repo is required"})
+	}
+
+	req, err := bindConnectionRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	graph := c.QueryParam("graph")
+	if graph == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "graph is required"})
+	}
+
+	format := strings.ToLower(c.QueryParam("format"))
+	if format == "" {
+		format = "turtle"
+	}
+	contentType, ok := rdfContentTypes[format]
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("unsupported format '%s' (supported: %s)", format, strings.Join(sortedKeys(rdfFormatSet()), ", "))})
+	}
+	if accept := c.QueryParam("accept"); accept != "" {
+		if err := checkMediaType(accept); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		contentType = accept
+	}
+
+	client, err := graphDBClientFor(req.URL)
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": fmt.Sprintf("Failed to connect to %s: %v", req.URL, err)})
+	}
+
+	if status, err := requireRepository(client, req, repo); err != nil {
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+
+	graphsList, err := graphDBWith(client).ListGraphs(req.URL, req.Username, req.Password, repo)
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": fmt.Sprintf("Failed to list graphs in repository '%s': %v", repo, err)})
+	}
+	foundGraph := false
+	for _, bind := range graphsList.Results.Bindings {
+		if bind.ContextID.Value == graph {
+			foundGraph = true
+			break
+		}
+	}
+	if !foundGraph {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": fmt.Sprintf("graph '%s' not found in repository '%s'", graph, repo)})
+	}
+
+	body, err := graphDBExportGraph(client, req.URL, req.Username, req.Password, repo, graph, contentType)
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": err.Error()})
+	}
+	defer func() { _ = body.Close() }()
+
+	fileName := graphExportFileName(graph, rdfFormatExtensions[format])
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", fileName))
+	return c.Stream(http.StatusOK, contentType, body)
+}
+
+// rdfFormatSet returns the names of the supported RDF formats as a set
+func rdfFormatSet() map[string]bool {
+	formats := make(map[string]bool, len(rdfContentTypes))
+	for format := range rdfContentTypes {
+		formats[format] = true
+	}
+	return formats
+}
@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// requestSchemaJSON is the JSON schema of MigrationRequest, published at GET /v1/api/schema
+//
+//go:embed request_schema.json
+var requestSchemaJSON []byte
+
+// requestSchema is the parsed request schema. The enum of Task.action is filled
+// from actionSpecs, so the schema always lists the registered actions.
+var requestSchema map[string]interface{}
+
+func init() {
+	if err := json.Unmarshal(requestSchemaJSON, &requestSchema); err != nil {
+		panic(fmt.Sprintf("invalid embedded request schema: %v", err))
+	}
+
+	actions := make([]interface{}, 0, len(actionSpecs))
+	for _, spec := range actionSpecs {
+		actions = append(actions, spec.Name)
+		for _, alias := range spec.Aliases {
+			actions = append(actions, alias)
+		}
+	}
+	action := schemaLookup(requestSchema, "$defs", "Task", "properties", "action")
+	if action == nil {
+		panic("embedded request schema has no Task.action property")
+	}
+	action["enum"] = actions
+}
+
+// schemaLookup follows a path of object keys through a schema and returns the
+// schema found there, or nil
+func schemaLookup(schema map[string]interface{}, path ...string) map[string]interface{} {
+	for _, key := range path {
+		next, ok := schema[key].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		schema = next
+	}
+	return schema
+}
+
+// validateRequestSchema validates a raw MigrationRequest body against the
+// request schema and returns all violations, or none if the body is valid.
+//
+// Only the schema keywords used by request_schema.json are supported: type,
+// required, properties, additionalProperties (false), items, enum, minItems,
+// minLength, minimum and local $ref. Optional fields may be null.
+func validateRequestSchema(body []byte) []validationMessage {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []validationMessage{jsonSyntaxViolation(body, err)}
+	}
+
+	v := &schemaValidator{root: requestSchema, violations: []validationMessage{}}
+	v.validate(requestSchema, value, "")
+	return v.violations
+}
+
+// schemaValidator collects the violations found while walking a value and its schema
+type schemaValidator struct {
+	root       map[string]interface{}
+	violations []validationMessage
+}
+
+// fail records a violation at path
+func (v *schemaValidator) fail(path, format string, args ...interface{}) {
+	field := path
+	if field == "" {
+		field = "(root)"
+	}
+	v.violations = append(v.violations, validationMessage{Field: path, Message: field + ": " + fmt.Sprintf(format, args...)})
+}
+
+// resolve replaces a local $ref ("#/$defs/Task") by the schema it points to
+func (v *schemaValidator) resolve(schema map[string]interface{}) map[string]interface{} {
+	ref, ok := schema["$ref"].(string)
+	if !ok {
+		return schema
+	}
+	target := schemaLookup(v.root, strings.Split(strings.TrimPrefix(ref, "#/"), "/")...)
+	if target == nil {
+		panic(fmt.Sprintf("unresolvable $ref %s in request schema", ref))
+	}
+	return target
+}
+
+// validate checks value against schema; path names the value in violations
+func (v *schemaValidator) validate(schema map[string]interface{}, value interface{}, path string) {
+	schema = v.resolve(schema)
+
+	if expected, ok := schema["type"].(string); ok && !schemaTypeMatches(expected, value) {
+		v.fail(path, "must be of type %s, got %s", expected, schemaTypeName(value))
+		return
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && !schemaEnumContains(enum, value) {
+		allowed := make([]string, len(enum))
+		for i, e := range enum {
+			allowed[i] = fmt.Sprint(e)
+		}
+		v.fail(path, "must be one of: %s", strings.Join(allowed, ", "))
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(schema, value, path)
+	case []interface{}:
+		if minItems, ok := schemaNumber(schema["minItems"]); ok && float64(len(value)) < minItems {
+			if minItems == 1 {
+				v.fail(path, "must not be empty")
+			} else {
+				v.fail(path, "must contain at least %v items", minItems)
+			}
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range value {
+				v.validate(items, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case string:
+		if minLength, ok := schemaNumber(schema["minLength"]); ok && float64(len([]rune(value))) < minLength {
+			if minLength == 1 {
+				v.fail(path, "must not be empty")
+			} else {
+				v.fail(path, "must be at least %v characters long", minLength)
+			}
+		}
+	case json.Number:
+		if minimum, ok := schemaNumber(schema["minimum"]); ok {
+			if n, err := value.Float64(); err == nil && n < minimum {
+				v.fail(path, "must be at least %v", minimum)
+			}
+		}
+	}
+}
+
+// validateObject checks the required and allowed properties of an object
+func (v *schemaValidator) validateObject(schema, value map[string]interface{}, path string) {
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	required := make(map[string]bool)
+	if names, ok := schema["required"].([]interface{}); ok {
+		for _, name := range names {
+			required[name.(string)] = true
+			if _, present := value[name.(string)]; !present {
+				v.fail(joinSchemaPath(path, name.(string)), "is required")
+			}
+		}
+	}
+
+	// Walk the properties in a stable order so violations are reported consistently
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		propertySchema, known := properties[name].(map[string]interface{})
+		if !known {
+			if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+				v.fail(joinSchemaPath(path, name), "unknown field")
+			}
+			continue
+		}
+		// null leaves an optional field unset, as it does when the body is bound
+		if value[name] == nil && !required[name] {
+			continue
+		}
+		v.validate(propertySchema, value[name], joinSchemaPath(path, name))
+	}
+}
+
+// joinSchemaPath appends a property name to a violation path ("tasks[0]" + "tgt")
+func joinSchemaPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// schemaTypeMatches reports whether a decoded JSON value has the schema type
+func schemaTypeMatches(expected string, value interface{}) bool {
+	switch expected {
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := strconv.ParseInt(n.String(), 10, 64)
+		return err == nil
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	}
+	return schemaTypeName(value) == expected
+}
+
+// schemaTypeName returns the JSON schema type name of a decoded JSON value
+func schemaTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// schemaEnumContains reports whether value is one of the enum values
+func schemaEnumContains(enum []interface{}, value interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(value) && schemaTypeName(e) == schemaTypeName(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaNumber returns a numeric schema keyword as float64
+func schemaNumber(keyword interface{}) (float64, bool) {
+	n, ok := keyword.(float64)
+	return n, ok
+}
+
+// requestSchemaViolationError is the response body for a request body that does
+// not match the schema
+func requestSchemaViolationError(c echo.Context, violations []validationMessage) error {
+	return c.JSON(http.StatusBadRequest, map[string]interface{}{
+		"error":  fmt.Sprintf("Request does not match the schema (%d violations)", len(violations)),
+		"errors": violations,
+	})
+}
+
+// requestSchemaREST handles REST GET /v1/api/schema
+//
+// Returns the JSON schema that POST /v1/api/action and POST /v1/api/validate
+// check request bodies against.
+func requestSchemaREST(c echo.Context) error {
+	return c.JSON(http.StatusOK, requestSchema)
+}
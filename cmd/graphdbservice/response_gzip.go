@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"strings"
+
+	"eve.evalgo.org/common"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// Default of GRAPHDB_GZIP_MIN_BYTES: smaller responses are not worth compressing
+const defaultGzipMinBytes = 1024
+
+// responseGzipMiddleware returns the middleware compressing responses for
+// clients sending Accept-Encoding: gzip if GRAPHDB_GZIP enables it, nil otherwise.
+// GRAPHDB_GZIP_LEVEL sets the compression level (1-9, default -1 for gzip's
+// default) and GRAPHDB_GZIP_MIN_BYTES the size from which a response is
+// compressed. The gzip writer of echo flushes the compressed data on Flush, so
+// streamed downloads still reach the client while they are written.
+func responseGzipMiddleware() echo.MiddlewareFunc {
+	if !common.GetEnvBool("GRAPHDB_GZIP", false) {
+		return nil
+	}
+	level := common.GetEnvInt("GRAPHDB_GZIP_LEVEL", gzip.DefaultCompression)
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+	return middleware.GzipWithConfig(middleware.GzipConfig{
+		Skipper:   skipResponseGzip,
+		Level:     level,
+		MinLength: max(common.GetEnvInt("GRAPHDB_GZIP_MIN_BYTES", defaultGzipMinBytes), 0),
+	})
+}
+
+// skipResponseGzip leaves event streams, health probes and downloads that are
+// already compressed or binary uncompressed. A gzipped event stream is buffered
+// by some proxies and browsers until the stream ends, and the health checks are
+// small and polled often. Server backup archives (application/gzip) and BRF
+// repository exports (application/x-binary-rdf) would only cost CPU to
+// compress again.
+func skipResponseGzip(c echo.Context) bool {
+	req := c.Request()
+	accept := req.Header.Get(echo.HeaderAccept)
+	for _, mediaType := range []string{"text/event-stream", "application/gzip", "application/x-gzip", brfContentType} {
+		if strings.Contains(accept, mediaType) {
+			return true
+		}
+	}
+
+	path := req.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/health"), strings.HasPrefix(path, "/v1/api/server-backups/"):
+		return true
+	case strings.HasPrefix(path, "/v1/api/repositories/") && strings.HasSuffix(path, "/export"):
+		// Repository exports are BRF unless the TTL config is requested
+		return !strings.EqualFold(req.URL.Query().Get("format"), "ttl")
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"eve.evalgo.org/common"
+)
+
+const (
+	// defaultRetryAttempts is the default maximum number of attempts per GraphDB request
+	defaultRetryAttempts = 3
+	// defaultRetryDelayMs is the default base delay before the first retry
+	defaultRetryDelayMs = 500
+)
+
+// retryPolicy controls how transient GraphDB HTTP failures are retried.
+type retryPolicy struct {
+	MaxAttempts int           // Maximum attempts per request (1 disables retries)
+	BaseDelay   time.Duration // Delay before the first retry, doubled for every further retry
+}
+
+// retryPolicyForTask builds the retry policy for a task. Task fields take
+// precedence over the GRAPHDB_RETRY_ATTEMPTS and GRAPHDB_RETRY_DELAY_MS env vars.
+func retryPolicyForTask(task Task) retryPolicy {
+	attempts := common.GetEnvInt("GRAPHDB_RETRY_ATTEMPTS", defaultRetryAttempts)
+	delayMs := common.GetEnvInt("GRAPHDB_RETRY_DELAY_MS", defaultRetryDelayMs)
+
+	if task.RetryAttempts > 0 {
+		attempts = task.RetryAttempts
+	}
+	if task.RetryDelayMs > 0 {
+		delayMs = task.RetryDelayMs
+	}
+	if attempts < 1 {
+		attempts = 1
+	}
+	if delayMs < 0 {
+		delayMs = 0
+	}
+
+	return retryPolicy{
+		MaxAttempts: attempts,
+		BaseDelay:   time.Duration(delayMs) * time.Millisecond,
+	}
+}
+
+// retryHTTPTransport wraps an http.RoundTripper and retries requests whose
+// outcome shouldRetry classifies as transient, using exponential backoff.
+//
+// Retries happen per HTTP request, so a failing step of a multi-step action is
+// retried without re-running the steps that already succeeded. Only idempotent
+// requests (GET, HEAD, PUT, DELETE) are repeated after GraphDB may have seen
+// them; a POST, such as a statements import that would add its data twice, is
+// only retried if the connection was refused before anything was sent.
+type retryHTTPTransport struct {
+	Transport http.RoundTripper
+	Policy    retryPolicy
+	retries   *int64 // Shared counter of retries performed, may be read while requests run
+}
+
+// RoundTrip implements http.RoundTripper interface with retries
+func (r *retryHTTPTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Requests with a body can only be retried if the body can be recreated
+	maxAttempts := r.Policy.MaxAttempts
+	if req.Body != nil && req.GetBody == nil {
+		maxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := r.Transport.RoundTrip(req)
+		if attempt >= maxAttempts || !retryable(req, err) || !shouldRetry(roundTripError(req, resp, err)) {
+			return resp, err
+		}
+
+		if err != nil {
+			debugLog("Request %s %s failed (attempt %d/%d): %v", req.Method, req.URL.String(), attempt, maxAttempts, err)
+		} else {
+			debugLog("Request %s %s returned status %d (attempt %d/%d)", req.Method, req.URL.String(), resp.StatusCode, attempt, maxAttempts)
+			// Drain and close the failed response so the connection can be reused
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			_ = resp.Body.Close()
+		}
+
+		delay := r.Policy.BaseDelay << (attempt - 1)
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, bodyErr
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		atomic.AddInt64(r.retries, 1)
+	}
+}
+
+// retryable reports whether a failed request may be sent again: idempotent
+// methods always, others only if the connection was refused, so GraphDB
+// received nothing of the first attempt
+func retryable(req *http.Request, err error) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return err != nil && errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// roundTripError returns the outcome of a request as an error: the transport
+// error, a GraphDB status error for a 4xx or 5xx response, or nil
+func roundTripError(req *http.Request, resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return graphDBStatusError(resp.StatusCode, "%s %s returned status %d", req.Method, req.URL.Redacted(), resp.StatusCode)
+	}
+	return nil
+}
+
+// shouldRetry reports whether a failed GraphDB request is worth retrying.
+//
+// Retryable are 5xx responses and network failures: refused or reset
+// connections, connections closed mid-response and network timeouts. Fatal are
+// 4xx responses (bad config, existing or missing repositories, rejected
+// credentials), missing repositories or graphs, validation errors, cancelled
+// or timed out tasks and any error not listed here.
+func shouldRetry(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, errTaskTimeout) || errors.Is(err, errTaskCancelled) {
+		return false
+	}
+
+	var taskErr *TaskError
+	if errors.As(err, &taskErr) {
+		if taskErr.StatusCode != 0 {
+			return taskErr.StatusCode >= 500
+		}
+		return false
+	}
+	var fieldErr *taskFieldError
+	if errors.As(err, &fieldErr) {
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// taskRetrier wraps the HTTP clients of a task with a shared retry transport
+// and counts the retries performed across all of them.
+type taskRetrier struct {
+	policy  retryPolicy
+	retries int64
+}
+
+// newTaskRetrier creates a retrier for the given policy.
+func newTaskRetrier(policy retryPolicy) *taskRetrier {
+	return &taskRetrier{policy: policy}
+}
+
+// wrap returns a copy of client whose requests are retried on transient failures.
+// The original client is not modified.
+func (t *taskRetrier) wrap(client *http.Client) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	wrapped := *client
+	wrapped.Transport = &retryHTTPTransport{
+		Transport: transport,
+		Policy:    t.policy,
+		retries:   &t.retries,
+	}
+	return &wrapped
+}
+
+// Retries returns the number of retries performed so far.
+func (t *taskRetrier) Retries() int {
+	return int(atomic.LoadInt64(&t.retries))
+}
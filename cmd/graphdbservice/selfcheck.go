@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// startupCheck collects the problems found by the startup self-check.
+// Errors prevent the service from starting, warnings are only logged.
+type startupCheck struct {
+	Errors   []string
+	Warnings []string
+}
+
+// startupCheckConfig holds the configuration validated by runStartupSelfCheck.
+type startupCheckConfig struct {
+	Port         int
+	ServiceURL   string
+	RegistryURL  string
+	APIKeys      int // Number of configured API keys
+	IdentityFile string
+	TempDir      string
+}
+
+func (s *startupCheck) addError(format string, args ...interface{}) {
+	s.Errors = append(s.Errors, fmt.Sprintf(format, args...))
+}
+
+func (s *startupCheck) addWarning(format string, args ...interface{}) {
+	s.Warnings = append(s.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Err returns a single error listing every fatal problem, or nil if there are none.
+func (s *startupCheck) Err() error {
+	if len(s.Errors) == 0 {
+		return nil
+	}
+	return fmt.Errorf("startup self-check failed with %d problem(s):\n  - %s", len(s.Errors), strings.Join(s.Errors, "\n  - "))
+}
+
+// runStartupSelfCheck validates the critical service configuration before the
+// server starts accepting traffic, so misconfigurations surface at startup
+// instead of on the first request that needs them.
+func runStartupSelfCheck(cfg startupCheckConfig) *startupCheck {
+	check := &startupCheck{}
+
+	// Port
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		check.addError("port %d is out of range (1-65535)", cfg.Port)
+	}
+
+	// Service URL is published to the registry and must be absolute
+	if u, err := url.Parse(cfg.ServiceURL); err != nil || u.Scheme == "" || u.Host == "" {
+		check.addError("service URL '%s' is not a valid absolute URL", cfg.ServiceURL)
+	}
+
+	// Registry is optional, the service keeps running without registration
+	if cfg.RegistryURL == "" {
+		check.addWarning("registry URL is not set, service will not be registered")
+	} else if u, err := url.Parse(cfg.RegistryURL); err != nil || u.Scheme == "" || u.Host == "" {
+		check.addWarning("registry URL '%s' is not a valid absolute URL, registration will fail", cfg.RegistryURL)
+	}
+
+	// API key
+	if cfg.APIKeys == 0 {
+		check.addWarning("no API key configured, all endpoints are unprotected")
+	}
+
+	// Temp directory is used for every export, backup and upload
+	checkTempDir(check, cfg.TempDir)
+
+	// Ziti identity
+	if cfg.IdentityFile != "" {
+		checkIdentityFile(check, cfg.IdentityFile)
+	}
+
+	return check
+}
+
+// checkTempDir verifies that the temp directory exists and is writable.
+func checkTempDir(check *startupCheck, dir string) {
+	if err := validateTempDir(dir); err != nil {
+		check.addError("%v", err)
+	}
+}
+
+// checkIdentityFile verifies that the Ziti identity file exists and contains JSON.
+func checkIdentityFile(check *startupCheck, path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		check.addError("Ziti identity file '%s' is not accessible: %v", path, err)
+		return
+	}
+	if info.IsDir() {
+		check.addError("Ziti identity path '%s' is a directory, expected a JSON file", path)
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		check.addError("Ziti identity file '%s' is not readable: %v", path, err)
+		return
+	}
+	if !json.Valid(data) {
+		check.addError("Ziti identity file '%s' does not contain valid JSON", path)
+	}
+}
@@ -50,6 +50,11 @@ func handleSemanticAction(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Failed to parse action: %v", err))
 	}
 
+	// Fail fast if a referenced GraphDB server is unreachable
+	if err := preflightAction(action); err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, err.Error())
+	}
+
 	// Dispatch to registered handler using the ActionRegistry
 	// No switch statement needed - handlers are registered at startup
 	return semantic.Handle(c, action)
@@ -58,6 +63,13 @@ func handleSemanticAction(c echo.Context) error {
 // handleSemanticActionMultipart handles multipart/form-data requests with file uploads
 // This is used for operations like CreateAction with config files or UploadAction with data files
 func handleSemanticActionMultipart(c echo.Context) error {
+	// Parse the form with the configured memory and body limits first, larger
+	// files are spilled to temp files. The EVE parser reuses the parsed form.
+	if err := parseMultipartForm(c, "Failed to parse multipart request"); err != nil {
+		return err
+	}
+	defer func() { _ = c.Request().MultipartForm.RemoveAll() }() // Clean up spilled temp files
+
 	// Parse the multipart request using EVE semantic library
 	semanticReq, err := semantic.ParseMultipartSemanticRequest(c.Request())
 	if err != nil {
@@ -70,6 +82,11 @@ func handleSemanticActionMultipart(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid action type in multipart request")
 	}
 
+	// Fail fast if a referenced GraphDB server is unreachable
+	if err := preflightAction(action); err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, err.Error())
+	}
+
 	// Convert EVE multipart files to the format expected by processTask
 	files := make(map[string][]*multipart.FileHeader)
 	for key, fileHeaders := range semanticReq.Files {
@@ -112,7 +129,10 @@ func executeSemanticCreateActionWithFiles(c echo.Context, action *semantic.Seman
 			Username: tgtUser,
 			Password: tgtPass,
 			Repo:     tgtRepoName,
+			Ruleset:  stringProperty(action, "ruleset"),
+			RepoType: stringProperty(action, "repositoryType"),
 		},
+		IfNotExists: isIfNotExists(action),
 	}
 
 	// The files are passed with key "config" for repository creation
@@ -175,14 +195,24 @@ func executeSemanticUploadActionWithFiles(c echo.Context, action *semantic.Seman
 	if graphErr == nil {
 		graphURI := semantic.ExtractGraphIdentifier(graph)
 
+		// Optional RDF format override for files without a recognized extension
+		format, _ := action.Properties["format"].(string)
+		// Keep the graph names of quad formats (.nq, .trig) instead of using the object graph
+		preserveGraphs, _ := action.Properties["preserveGraphs"].(bool)
+		// "append" adds the data to the object graph instead of replacing it
+		mode := stringProperty(action, "mode")
+
 		task := Task{
 			Action: "graph-import",
 			Tgt: &Repository{
-				URL:      tgtURL,
-				Username: tgtUser,
-				Password: tgtPass,
-				Repo:     tgtRepoName,
-				Graph:    graphURI,
+				URL:            tgtURL,
+				Username:       tgtUser,
+				Password:       tgtPass,
+				Repo:           tgtRepoName,
+				Graph:          graphURI,
+				Format:         format,
+				PreserveGraphs: preserveGraphs,
+				Mode:           mode,
 			},
 		}
 
@@ -253,8 +283,18 @@ func executeSemanticActionByType(c echo.Context, action *semantic.SemanticAction
 	}
 }
 
-// executeSemanticTransferAction handles TransferAction (repo-migration, graph-migration)
+// executeSemanticTransferAction handles TransferAction (repo-migration, graph-migration, graph-merge, graph-query-import)
 func executeSemanticTransferAction(c echo.Context, action *semantic.SemanticAction) error {
+	// An array of graphs as object merges all of them into the result graph
+	if isGraphMergeAction(action) {
+		return executeGraphMerge(c, action)
+	}
+
+	// A query imports the CONSTRUCT/DESCRIBE result into the object graph
+	if isGraphQueryImportAction(action) {
+		return executeGraphQueryImport(c, action)
+	}
+
 	// Determine if it's repo migration or graph migration by checking for object property
 	if _, hasObject := action.Properties["object"]; hasObject {
 		// Graph migration: transfer specific graph
@@ -311,7 +351,10 @@ func executeRepositoryMigration(c echo.Context, action *semantic.SemanticAction)
 
 	// Create legacy Task for execution
 	task := Task{
-		Action: "repo-migration",
+		Action:        "repo-migration",
+		Verify:        isVerify(action),
+		ReportGraphs:  isReportGraphs(action),
+		ForceRecreate: isForceRecreate(action),
 		Src: &Repository{
 			URL:      srcURL,
 			Username: srcUser,
@@ -327,7 +370,7 @@ func executeRepositoryMigration(c echo.Context, action *semantic.SemanticAction)
 	}
 
 	// Execute the task
-	result, err := processTask(task, nil, 0)
+	result, err := processTaskWithProgress(task, nil, 0, operationProgress(opID))
 	if err != nil {
 		stateManager.CompleteOperation(opID, err)
 		return semantic.ReturnActionError(c, action, "Migration failed", err)
@@ -410,10 +453,11 @@ func executeGraphMigration(c echo.Context, action *semantic.SemanticAction) erro
 			Repo:     tgtRepoName,
 			Graph:    graphURI,
 		},
+		ExportFormat: stringProperty(action, "exportFormat"),
 	}
 
 	// Execute the task
-	result, err := processTask(task, nil, 0)
+	result, err := processTaskWithProgress(task, nil, 0, operationProgress(opID))
 	if err != nil {
 		stateManager.CompleteOperation(opID, err)
 		return semantic.ReturnActionError(c, action, "Graph migration failed", err)
@@ -425,6 +469,253 @@ func executeGraphMigration(c echo.Context, action *semantic.SemanticAction) erro
 	return c.JSON(http.StatusOK, action)
 }
 
+// operationProgress returns a ProgressFunc that publishes task progress
+// (e.g. "Exporting graph 3/10") as metadata of the tracked operation.
+func operationProgress(opID string) ProgressFunc {
+	return func(stage string, current, total int) {
+		stateManager.UpdateMetadata(opID, "progress", fmt.Sprintf("%s %d/%d", stage, current, total))
+	}
+}
+
+// isVerify reports whether the action requests a verification of the migrated data via the "verify" property
+func isVerify(action *semantic.SemanticAction) bool {
+	verify, _ := action.Properties["verify"].(bool)
+	return verify
+}
+
+// isReportGraphs reports whether a repository migration should list the target graphs with
+// their triple counts via the "reportGraphs" property
+func isReportGraphs(action *semantic.SemanticAction) bool {
+	reportGraphs, _ := action.Properties["reportGraphs"].(bool)
+	return reportGraphs
+}
+
+// isForceRecreate reports whether a repository migration should replace an existing
+// target repository via the "forceRecreate" property
+func isForceRecreate(action *semantic.SemanticAction) bool {
+	forceRecreate, _ := action.Properties["forceRecreate"].(bool)
+	return forceRecreate
+}
+
+// isDryRun reports whether the action requests a dry run via the "dryRun" property
+func isDryRun(action *semantic.SemanticAction) bool {
+	dryRun, _ := action.Properties["dryRun"].(bool)
+	return dryRun
+}
+
+// isForce reports whether the action overrides a safety check via the "force" property
+func isForce(action *semantic.SemanticAction) bool {
+	force, _ := action.Properties["force"].(bool)
+	return force
+}
+
+// isIfNotExists reports whether a CreateAction should succeed when the repository already exists
+func isIfNotExists(action *semantic.SemanticAction) bool {
+	ifNotExists, _ := action.Properties["ifNotExists"].(bool)
+	return ifNotExists
+}
+
+// stringProperty returns a string property of the action, or "" if it is absent
+func stringProperty(action *semantic.SemanticAction, name string) string {
+	value, _ := action.Properties[name].(string)
+	return value
+}
+
+// isGraphMergeAction reports whether a TransferAction lists multiple source graphs in its object
+func isGraphMergeAction(action *semantic.SemanticAction) bool {
+	_, isList := action.Properties["object"].([]interface{})
+	return isList
+}
+
+// buildGraphMergeTask converts a TransferAction with an array of graphs as object into a graph-merge Task.
+// The source graphs are read from fromLocation and merged into the result graph in toLocation.
+func buildGraphMergeTask(action *semantic.SemanticAction) (Task, error) {
+	srcRepo, err := semantic.GetGraphDBRepositoryFromAction(action, "fromLocation")
+	if err != nil {
+		return Task{}, fmt.Errorf("invalid fromLocation: %w", err)
+	}
+
+	tgtRepo, err := semantic.GetGraphDBRepositoryFromAction(action, "toLocation")
+	if err != nil {
+		return Task{}, fmt.Errorf("invalid toLocation: %w", err)
+	}
+
+	objects, _ := action.Properties["object"].([]interface{})
+	if len(objects) == 0 {
+		return Task{}, fmt.Errorf("object must contain at least one graph")
+	}
+
+	srcGraphs := make([]string, 0, len(objects))
+	for i, obj := range objects {
+		// Wrap each element so the graph helper can parse it
+		graph, err := semantic.GetGraphDBGraphFromAction(&semantic.SemanticAction{
+			Properties: map[string]interface{}{"object": obj},
+		}, "object")
+		if err != nil {
+			return Task{}, fmt.Errorf("invalid object[%d] (graph): %w", i, err)
+		}
+		srcGraphs = append(srcGraphs, semantic.ExtractGraphIdentifier(graph))
+	}
+
+	tgtGraph, err := semantic.GetGraphDBGraphFromAction(action, "result")
+	if err != nil {
+		return Task{}, fmt.Errorf("invalid result (target graph): %w", err)
+	}
+
+	srcURL, srcUser, srcPass, srcRepoName, err := semantic.ExtractRepositoryCredentials(srcRepo)
+	if err != nil {
+		return Task{}, fmt.Errorf("invalid source credentials: %w", err)
+	}
+
+	tgtURL, tgtUser, tgtPass, tgtRepoName, err := semantic.ExtractRepositoryCredentials(tgtRepo)
+	if err != nil {
+		return Task{}, fmt.Errorf("invalid target credentials: %w", err)
+	}
+
+	deleteSources, _ := action.Properties["deleteSources"].(bool)
+
+	return Task{
+		Action:        "graph-merge",
+		DeleteSources: deleteSources,
+		DryRun:        isDryRun(action),
+		Src: &Repository{
+			URL:      normalizeURL(srcURL),
+			Username: srcUser,
+			Password: srcPass,
+			Repo:     srcRepoName,
+			Graphs:   srcGraphs,
+		},
+		Tgt: &Repository{
+			URL:      normalizeURL(tgtURL),
+			Username: tgtUser,
+			Password: tgtPass,
+			Repo:     tgtRepoName,
+			Graph:    semantic.ExtractGraphIdentifier(tgtGraph),
+		},
+	}, nil
+}
+
+// executeGraphMerge merges multiple source graphs into a single target graph
+func executeGraphMerge(c echo.Context, action *semantic.SemanticAction) error {
+	// Track operation
+	opID := uuid.New().String()
+	stateManager.StartOperation(opID, "graph-merge", map[string]interface{}{
+		"action": "graph-merge",
+	})
+
+	task, err := buildGraphMergeTask(action)
+	if err != nil {
+		stateManager.CompleteOperation(opID, err)
+		return semantic.ReturnActionError(c, action, "Invalid graph merge request", err)
+	}
+
+	// Update metadata with graph info
+	stateManager.UpdateMetadata(opID, "source_graphs", task.Src.Graphs)
+	stateManager.UpdateMetadata(opID, "target_graph", task.Tgt.Graph)
+	stateManager.UpdateMetadata(opID, "source_repo", task.Src.Repo)
+	stateManager.UpdateMetadata(opID, "target_repo", task.Tgt.Repo)
+
+	// Execute the task
+	result, err := processTaskWithProgress(task, nil, 0, operationProgress(opID))
+	stateManager.CompleteOperation(opID, err)
+	if err != nil {
+		return semantic.ReturnActionError(c, action, "Graph merge failed", err)
+	}
+
+	// Set result and success status
+	action.Properties["result"] = result
+	semantic.SetSuccessOnAction(action)
+	return c.JSON(http.StatusOK, action)
+}
+
+// isGraphQueryImportAction reports whether a TransferAction carries a SPARQL query
+func isGraphQueryImportAction(action *semantic.SemanticAction) bool {
+	query, _ := action.Properties["query"].(string)
+	return query != ""
+}
+
+// buildGraphQueryImportTask converts a TransferAction with a "query" property into a
+// graph-query-import Task. The query runs on fromLocation and its result replaces the
+// object graph in toLocation.
+func buildGraphQueryImportTask(action *semantic.SemanticAction) (Task, error) {
+	srcRepo, err := semantic.GetGraphDBRepositoryFromAction(action, "fromLocation")
+	if err != nil {
+		return Task{}, fmt.Errorf("invalid fromLocation: %w", err)
+	}
+
+	tgtRepo, err := semantic.GetGraphDBRepositoryFromAction(action, "toLocation")
+	if err != nil {
+		return Task{}, fmt.Errorf("invalid toLocation: %w", err)
+	}
+
+	graph, err := semantic.GetGraphDBGraphFromAction(action, "object")
+	if err != nil {
+		return Task{}, fmt.Errorf("invalid object (graph): %w", err)
+	}
+
+	srcURL, srcUser, srcPass, srcRepoName, err := semantic.ExtractRepositoryCredentials(srcRepo)
+	if err != nil {
+		return Task{}, fmt.Errorf("invalid source credentials: %w", err)
+	}
+
+	tgtURL, tgtUser, tgtPass, tgtRepoName, err := semantic.ExtractRepositoryCredentials(tgtRepo)
+	if err != nil {
+		return Task{}, fmt.Errorf("invalid target credentials: %w", err)
+	}
+
+	query, _ := action.Properties["query"].(string)
+
+	return Task{
+		Action: "graph-query-import",
+		Src: &Repository{
+			URL:      normalizeURL(srcURL),
+			Username: srcUser,
+			Password: srcPass,
+			Repo:     srcRepoName,
+			Query:    query,
+		},
+		Tgt: &Repository{
+			URL:      normalizeURL(tgtURL),
+			Username: tgtUser,
+			Password: tgtPass,
+			Repo:     tgtRepoName,
+			Graph:    semantic.ExtractGraphIdentifier(graph),
+		},
+	}, nil
+}
+
+// executeGraphQueryImport imports the result of a SPARQL CONSTRUCT/DESCRIBE query into a graph
+func executeGraphQueryImport(c echo.Context, action *semantic.SemanticAction) error {
+	// Track operation
+	opID := uuid.New().String()
+	stateManager.StartOperation(opID, "graph-query-import", map[string]interface{}{
+		"action": "graph-query-import",
+	})
+
+	task, err := buildGraphQueryImportTask(action)
+	if err != nil {
+		stateManager.CompleteOperation(opID, err)
+		return semantic.ReturnActionError(c, action, "Invalid graph query import request", err)
+	}
+
+	// Update metadata with graph info
+	stateManager.UpdateMetadata(opID, "graph_uri", task.Tgt.Graph)
+	stateManager.UpdateMetadata(opID, "source_repo", task.Src.Repo)
+	stateManager.UpdateMetadata(opID, "target_repo", task.Tgt.Repo)
+
+	// Execute the task
+	result, err := processTaskWithProgress(task, nil, 0, operationProgress(opID))
+	stateManager.CompleteOperation(opID, err)
+	if err != nil {
+		return semantic.ReturnActionError(c, action, "Graph query import failed", err)
+	}
+
+	// Set result and success status
+	action.Properties["result"] = result
+	semantic.SetSuccessOnAction(action)
+	return c.JSON(http.StatusOK, action)
+}
+
 // executeSemanticCreateAction handles CreateAction (repo-create)
 func executeSemanticCreateAction(c echo.Context, action *semantic.SemanticAction) error {
 	// Track operation
@@ -461,11 +752,14 @@ func executeSemanticCreateAction(c echo.Context, action *semantic.SemanticAction
 			Username: tgtUser,
 			Password: tgtPass,
 			Repo:     tgtRepoName,
+			Ruleset:  stringProperty(action, "ruleset"),
+			RepoType: stringProperty(action, "repositoryType"),
 		},
+		IfNotExists: isIfNotExists(action),
 	}
 
 	// Execute the task (will handle config file from multipart if present)
-	result, err := processTask(task, nil, 0)
+	result, err := processTaskWithProgress(task, nil, 0, operationProgress(opID))
 	if err != nil {
 		stateManager.CompleteOperation(opID, err)
 		return semantic.ReturnActionError(c, action, "Creation failed", err)
@@ -491,6 +785,7 @@ func executeSemanticDeleteAction(c echo.Context, action *semantic.SemanticAction
 
 		task := Task{
 			Action: "repo-delete",
+			DryRun: isDryRun(action),
 			Tgt: &Repository{
 				URL:      tgtURL,
 				Username: tgtUser,
@@ -532,6 +827,7 @@ func executeSemanticDeleteAction(c echo.Context, action *semantic.SemanticAction
 
 		task := Task{
 			Action: "graph-delete",
+			DryRun: isDryRun(action),
 			Tgt: &Repository{
 				URL:      repoURL,
 				Username: username,
@@ -568,8 +864,18 @@ func executeSemanticUpdateAction(c echo.Context, action *semantic.SemanticAction
 		}
 		tgtURL = normalizeURL(tgtURL)
 
+		// Track operation so per-graph progress is visible via the state endpoints
+		opID := uuid.New().String()
+		stateManager.StartOperation(opID, "repo-rename", map[string]interface{}{
+			"action":   "repo-rename",
+			"old_name": oldRepoName,
+			"new_name": targetName,
+		})
+
 		task := Task{
 			Action: "repo-rename",
+			DryRun: isDryRun(action),
+			Force:  isForce(action),
 			Tgt: &Repository{
 				URL:      tgtURL,
 				Username: tgtUser,
@@ -579,7 +885,8 @@ func executeSemanticUpdateAction(c echo.Context, action *semantic.SemanticAction
 			},
 		}
 
-		result, err := processTask(task, nil, 0)
+		result, err := processTaskWithProgress(task, nil, 0, operationProgress(opID))
+		stateManager.CompleteOperation(opID, err)
 		if err != nil {
 			return semantic.ReturnActionError(c, action, "Rename failed", err)
 		}
@@ -610,6 +917,7 @@ func executeSemanticUpdateAction(c echo.Context, action *semantic.SemanticAction
 
 		task := Task{
 			Action: "graph-rename",
+			DryRun: isDryRun(action),
 			Tgt: &Repository{
 				URL:      repoURL,
 				Username: username,
@@ -772,7 +1080,7 @@ func executeSemanticItemList(c echo.Context, action *semantic.SemanticAction) er
 	if workflow.Parallel {
 		// Execute actions in parallel with concurrency limit
 		debugLog("Executing %d actions in PARALLEL (concurrency: %d)\n", len(workflow.ItemListElement), workflow.Concurrency)
-		results, errors = executeActionsParallel(c, workflow.ItemListElement, workflow.Concurrency)
+		results, errors = executeActionsParallel(c, workflow.ItemListElement, workflow.Concurrency, executeWorkflowItem)
 	} else {
 		// Execute actions sequentially
 		debugLog("Executing %d actions SEQUENTIALLY\n", len(workflow.ItemListElement))
@@ -833,8 +1141,13 @@ func executeActionsSequential(c echo.Context, items []ListItemNode) ([]map[strin
 	return results, errors
 }
 
-// executeActionsParallel executes actions in parallel with concurrency control
-func executeActionsParallel(c echo.Context, items []ListItemNode, concurrency int) ([]map[string]interface{}, []string) {
+// workflowItemFunc executes a single workflow item
+type workflowItemFunc func(c echo.Context, listItem ListItemNode, index int) (map[string]interface{}, error)
+
+// executeActionsParallel executes actions in parallel with concurrency control.
+// Items targeting the same repository run one after another in list order;
+// items on different repositories run concurrently up to the concurrency limit.
+func executeActionsParallel(c echo.Context, items []ListItemNode, concurrency int, execute workflowItemFunc) ([]map[string]interface{}, []string) {
 	results := make([]map[string]interface{}, len(items))
 	var errors []string
 
@@ -849,14 +1162,32 @@ func executeActionsParallel(c echo.Context, items []ListItemNode, concurrency in
 	}
 	resultChan := make(chan resultPair, len(items))
 
+	// The lock of a repository is handed from item to item in list order: each
+	// item waits until the previous item on the same repository is done
+	repoLocks := make(map[string]chan struct{})
+
 	// Launch goroutines for each item
 	for i, listItem := range items {
+		var previous chan struct{}
+		done := make(chan struct{})
+		if key := workflowItemTargetKey(listItem.Item); key != "" {
+			previous = repoLocks[key]
+			repoLocks[key] = done
+		}
+
 		go func(idx int, item ListItemNode) {
+			defer close(done)
+
+			// Wait for the repository before taking a concurrency slot
+			if previous != nil {
+				<-previous
+			}
+
 			// Acquire semaphore
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			result, err := executeWorkflowItem(c, item, idx)
+			result, err := execute(c, item, idx)
 			resultChan <- resultPair{
 				index:  idx,
 				result: result,
@@ -884,6 +1215,59 @@ func executeActionsParallel(c echo.Context, items []ListItemNode, concurrency in
 	return results, errors
 }
 
+// workflowTargetProperties are the action properties naming the repository an
+// action writes to, in order of precedence
+var workflowTargetProperties = []string{"toLocation", "target", "result", "object"}
+
+// workflowItemTargetKey identifies the repository a workflow item writes to as
+// normalized server URL and repository name, like taskTargetKey. It returns ""
+// if the item names no repository; such items are not serialized.
+func workflowItemTargetKey(item map[string]interface{}) string {
+	action := item
+	if itemType, _ := item["@type"].(string); itemType == "ScheduledAction" {
+		props, _ := item["additionalProperty"].(map[string]interface{})
+		action, _ = props["body"].(map[string]interface{})
+	}
+
+	for _, property := range workflowTargetProperties {
+		node, ok := action[property].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// Graphs name their repository as the catalog they are included in
+		if catalog, ok := node["includedInDataCatalog"].(map[string]interface{}); ok {
+			node = catalog
+		}
+		if key := repositoryNodeKey(node); key != "" {
+			return key
+		}
+	}
+	return ""
+}
+
+// repositoryNodeKey returns the key of a JSON-LD repository node, or "" if the
+// node lacks a repository name or server URL
+func repositoryNodeKey(node map[string]interface{}) string {
+	repo, _ := node["identifier"].(string)
+	if repo == "" {
+		return ""
+	}
+
+	props, _ := node["additionalProperty"].(map[string]interface{})
+	serverURL, _ := props["serverUrl"].(string)
+	if serverURL == "" {
+		serverURL, _ = node["url"].(string)
+	}
+	if serverURL == "" {
+		codeRepository, _ := node["codeRepository"].(string)
+		serverURL = strings.TrimSuffix(codeRepository, "/repositories/"+repo)
+	}
+	if serverURL == "" {
+		return ""
+	}
+	return normalizeURL(serverURL) + "|" + repo
+}
+
 // executeWorkflowItem executes a single workflow item (typically a ScheduledAction)
 func executeWorkflowItem(c echo.Context, listItem ListItemNode, index int) (map[string]interface{}, error) {
 	debugLog("executeWorkflowItem called for index %d, position %d\n", index, listItem.Position)
@@ -1024,6 +1408,7 @@ func executeDeleteActionDirect(action *semantic.SemanticAction) (map[string]inte
 
 		task := Task{
 			Action: "repo-delete",
+			DryRun: isDryRun(action),
 			Tgt: &Repository{
 				URL:      tgtURL,
 				Username: tgtUser,
@@ -1073,6 +1458,7 @@ func executeDeleteActionDirect(action *semantic.SemanticAction) (map[string]inte
 
 		task := Task{
 			Action: "graph-delete",
+			DryRun: isDryRun(action),
 			Tgt: &Repository{
 				URL:      repoURL,
 				Username: username,
@@ -1102,6 +1488,46 @@ func executeDeleteActionDirect(action *semantic.SemanticAction) (map[string]inte
 
 // executeTransferActionDirect executes a TransferAction and returns the result directly
 func executeTransferActionDirect(action *semantic.SemanticAction) (map[string]interface{}, error) {
+	if isGraphMergeAction(action) {
+		task, err := buildGraphMergeTask(action)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := processTask(task, nil, 0)
+		if err != nil {
+			return nil, fmt.Errorf("graph merge failed: %w", err)
+		}
+
+		action.Properties["result"] = result
+		semantic.SetSuccessOnAction(action)
+
+		actionMap := make(map[string]interface{})
+		actionJSON, _ := json.Marshal(action)
+		_ = json.Unmarshal(actionJSON, &actionMap)
+		return actionMap, nil
+	}
+
+	if isGraphQueryImportAction(action) {
+		task, err := buildGraphQueryImportTask(action)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := processTask(task, nil, 0)
+		if err != nil {
+			return nil, fmt.Errorf("graph query import failed: %w", err)
+		}
+
+		action.Properties["result"] = result
+		semantic.SetSuccessOnAction(action)
+
+		actionMap := make(map[string]interface{})
+		actionJSON, _ := json.Marshal(action)
+		_ = json.Unmarshal(actionJSON, &actionMap)
+		return actionMap, nil
+	}
+
 	if _, hasObject := action.Properties["object"]; hasObject {
 		// Graph migration
 		srcRepo, err := semantic.GetGraphDBRepositoryFromAction(action, "fromLocation")
@@ -1149,6 +1575,7 @@ func executeTransferActionDirect(action *semantic.SemanticAction) (map[string]in
 				Repo:     tgtRepoName,
 				Graph:    graphURI,
 			},
+			ExportFormat: stringProperty(action, "exportFormat"),
 		}
 
 		result, err := processTask(task, nil, 0)
@@ -1189,7 +1616,10 @@ func executeTransferActionDirect(action *semantic.SemanticAction) (map[string]in
 	tgtURL = normalizeURL(tgtURL)
 
 	task := Task{
-		Action: "repo-migration",
+		Action:        "repo-migration",
+		Verify:        isVerify(action),
+		ReportGraphs:  isReportGraphs(action),
+		ForceRecreate: isForceRecreate(action),
 		Src: &Repository{
 			URL:      srcURL,
 			Username: srcUser,
@@ -1238,7 +1668,10 @@ func executeCreateActionDirect(action *semantic.SemanticAction) (map[string]inte
 			Username: tgtUser,
 			Password: tgtPass,
 			Repo:     tgtRepoName,
+			Ruleset:  stringProperty(action, "ruleset"),
+			RepoType: stringProperty(action, "repositoryType"),
 		},
+		IfNotExists: isIfNotExists(action),
 	}
 
 	result, err := processTask(task, nil, 0)
@@ -1267,8 +1700,18 @@ func executeUpdateActionDirect(action *semantic.SemanticAction) (map[string]inte
 		}
 		tgtURL = normalizeURL(tgtURL)
 
+		// Track operation so per-graph progress is visible via the state endpoints
+		opID := uuid.New().String()
+		stateManager.StartOperation(opID, "repo-rename", map[string]interface{}{
+			"action":   "repo-rename",
+			"old_name": oldRepoName,
+			"new_name": targetName,
+		})
+
 		task := Task{
 			Action: "repo-rename",
+			DryRun: isDryRun(action),
+			Force:  isForce(action),
 			Tgt: &Repository{
 				URL:      tgtURL,
 				Username: tgtUser,
@@ -1278,7 +1721,8 @@ func executeUpdateActionDirect(action *semantic.SemanticAction) (map[string]inte
 			},
 		}
 
-		result, err := processTask(task, nil, 0)
+		result, err := processTaskWithProgress(task, nil, 0, operationProgress(opID))
+		stateManager.CompleteOperation(opID, err)
 		if err != nil {
 			return nil, fmt.Errorf("rename failed: %w", err)
 		}
@@ -1312,6 +1756,7 @@ func executeUpdateActionDirect(action *semantic.SemanticAction) (map[string]inte
 
 		task := Task{
 			Action: "graph-rename",
+			DryRun: isDryRun(action),
 			Tgt: &Repository{
 				URL:      repoURL,
 				Username: username,
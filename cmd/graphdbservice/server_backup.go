@@ -0,0 +1,357 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"eve.evalgo.org/common"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// serverBackupsDir is the directory below MIGRATION_LOG_DIR holding the
+	// archives of server-backup unless SERVER_BACKUP_DIR is set
+	serverBackupsDir = "server-backups"
+	// serverBackupManifestFile describes the contents of a server backup archive
+	serverBackupManifestFile = "manifest.json"
+	// defaultServerBackupConcurrency is the default of SERVER_BACKUP_CONCURRENCY
+	defaultServerBackupConcurrency = 2
+)
+
+// serverBackupRepo is the outcome of the backup of one repository of a server
+type serverBackupRepo struct {
+	Repository string `json:"repository"`
+	Status     string `json:"status"` // completed or failed
+	ConfigFile string `json:"config_file,omitempty"`
+	DataFile   string `json:"data_file,omitempty"`
+	DataSize   int64  `json:"data_size,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// serverBackupManifest is stored as manifest.json in a server backup archive
+type serverBackupManifest struct {
+	ID           string             `json:"id"`
+	SessionID    string             `json:"session_id,omitempty"`
+	Server       string             `json:"server"`
+	CreatedAt    time.Time          `json:"created_at"`
+	Repositories []serverBackupRepo `json:"repositories"`
+}
+
+// serverBackupDir returns the directory holding the server backup archives:
+// SERVER_BACKUP_DIR, or server-backups below MIGRATION_LOG_DIR
+func serverBackupDir() (string, error) {
+	if dir := common.GetEnv("SERVER_BACKUP_DIR", ""); dir != "" {
+		return dir, nil
+	}
+	if migrationLogger == nil {
+		return "", fmt.Errorf("server-backup requires SERVER_BACKUP_DIR or migration session logging (MIGRATION_LOG_DIR)")
+	}
+	return filepath.Join(migrationLogger.dir, serverBackupsDir), nil
+}
+
+// serverBackupConcurrency returns the number of repositories backed up at once
+func serverBackupConcurrency() int {
+	if concurrency := common.GetEnvInt("SERVER_BACKUP_CONCURRENCY", defaultServerBackupConcurrency); concurrency > 0 {
+		return concurrency
+	}
+	return defaultServerBackupConcurrency
+}
+
+// serverBackupArchive appends files to a tar.gz archive. The repositories are
+// downloaded concurrently, so adding their files is serialized.
+type serverBackupArchive struct {
+	mu   sync.Mutex
+	file *os.File
+	gz   *gzip.Writer
+	tar  *tar.Writer
+}
+
+// createServerBackupArchive creates the archive file fileName
+func createServerBackupArchive(fileName string) (*serverBackupArchive, error) {
+	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup archive: %w", err)
+	}
+	gz := gzip.NewWriter(file)
+	return &serverBackupArchive{file: file, gz: gz, tar: tar.NewWriter(gz)}, nil
+}
+
+// addFile stores the file fileName under name in the archive
+func (a *serverBackupArchive) addFile(name, fileName string) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	header := &tar.Header{Name: name, Mode: 0o640, Size: info.Size(), ModTime: info.ModTime()}
+	if err := a.tar.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(a.tar, file)
+	return err
+}
+
+// addData stores data under name in the archive
+func (a *serverBackupArchive) addData(name string, data []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	header := &tar.Header{Name: name, Mode: 0o640, Size: int64(len(data)), ModTime: time.Now()}
+	if err := a.tar.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := a.tar.Write(data)
+	return err
+}
+
+// close finishes the archive
+func (a *serverBackupArchive) close() error {
+	err := a.tar.Close()
+	if closeErr := a.gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := a.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// backupRepositoryInto downloads the configuration and the BRF data of a
+// repository into temp files and adds them to the archive as <repo>/<repo>.ttl
+// and <repo>/<repo>.brf. The temp files are removed once they are archived.
+func backupRepositoryInto(run *taskRun, client *http.Client, archive *serverBackupArchive, repo string) serverBackupRepo {
+	task := run.task
+	backup := serverBackupRepo{Repository: repo, Status: "failed"}
+	prefix := fmt.Sprintf("server_backup_%s", uuid.New().String())
+	confFile, dataFile := run.tempFile(prefix+".ttl"), run.tempFile(prefix+".brf")
+	defer func() {
+		_ = os.Remove(confFile)
+		_ = os.Remove(dataFile)
+	}()
+
+	if err := graphDBDownloadRepositoryConfig(client, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repo, confFile); err != nil {
+		backup.Error = fmt.Sprintf("failed to download configuration: %v", err)
+		return backup
+	}
+	dataSize, err := graphDBDownloadRepositoryData(client, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repo, dataFile)
+	if err != nil {
+		backup.Error = fmt.Sprintf("failed to download data: %v", err)
+		return backup
+	}
+
+	configName, dataName := repo+"/"+repo+".ttl", repo+"/"+repo+".brf"
+	if err := archive.addFile(configName, confFile); err != nil {
+		backup.Error = fmt.Sprintf("failed to archive configuration: %v", err)
+		return backup
+	}
+	if err := archive.addFile(dataName, dataFile); err != nil {
+		backup.Error = fmt.Sprintf("failed to archive data: %v", err)
+		return backup
+	}
+	backup.Status = "completed"
+	backup.ConfigFile, backup.DataFile, backup.DataSize = configName, dataName, dataSize
+	return backup
+}
+
+// executeServerBackupTask executes the server-backup action.
+//
+// It backs up the configuration and the BRF data of every repository of the
+// tgt server, or of those named by tgt.repos or matched by tgt.pattern, into
+// one tar.gz archive in the server backup directory. SERVER_BACKUP_CONCURRENCY
+// repositories are downloaded at once. The result lists the outcome of every
+// repository; a failed repository fails the task unless continue_on_error is
+// set, in which case the archive holds the repositories that succeeded.
+func executeServerBackupTask(run *taskRun) error {
+	task, progress, log, result, tgtClient, zitiClient := run.task, run.progress, run.log, run.result, run.tgtClient, run.zitiClient
+
+	if identityFile != "" {
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
+		if err != nil {
+			return err
+		}
+		tgtClient, err = zitiClient(tgtURL)
+		if err != nil {
+			return err
+		}
+	}
+	dir, err := serverBackupDir()
+	if err != nil {
+		return err
+	}
+
+	repos, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	if err != nil {
+		return fmt.Errorf("failed to fetch repositories from %s: %w", task.Tgt.URL, err)
+	}
+	names := getRepositoryNames(repos.Results.Bindings)
+	switch {
+	case task.Tgt.Pattern != "":
+		re, err := compileNamePattern(task.Tgt.Pattern)
+		if err != nil {
+			return err
+		}
+		names = matchNames(re, names)
+	case len(task.Tgt.Repos) > 0:
+		available := make(map[string]bool, len(names))
+		for _, name := range names {
+			available[name] = true
+		}
+		for _, name := range task.Tgt.Repos {
+			if !available[name] {
+				return newTaskError(ErrRepoNotFound, "repository '%s' not found. Available repositories: %v", name, names)
+			}
+		}
+		names = task.Tgt.Repos
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no repositories to back up on %s", task.Tgt.URL)
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	manifest := serverBackupManifest{
+		ID:           uuid.New().String(),
+		SessionID:    sessionIDFromContext(run.ctx),
+		Server:       redactURL(normalizeURL(task.Tgt.URL)),
+		CreatedAt:    time.Now().UTC(),
+		Repositories: make([]serverBackupRepo, len(names)),
+	}
+	archiveFile := filepath.Join(dir, manifest.ID+".tar.gz")
+	archive, err := createServerBackupArchive(archiveFile)
+	if err != nil {
+		return err
+	}
+	keepArchive := false
+	defer func() {
+		if !keepArchive {
+			_ = os.Remove(archiveFile)
+		}
+	}()
+
+	// The repositories are backed up by a fixed number of workers, so the
+	// server is not asked for more downloads at once
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	next, done := 0, 0
+	for range min(serverBackupConcurrency(), len(names)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				i := next
+				next++
+				mu.Unlock()
+				if i >= len(names) || run.ctx.Err() != nil {
+					return
+				}
+
+				backup := backupRepositoryInto(run, tgtClient, archive, names[i])
+				if backup.Error != "" {
+					log.Warn("Failed to back up repository", "repository", names[i], "error", backup.Error)
+				}
+				mu.Lock()
+				manifest.Repositories[i] = backup
+				done++
+				progress("Backing up repositories", done, len(names))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if err := run.ctx.Err(); err != nil {
+		_ = archive.close()
+		return err
+	}
+
+	var completed, failed []string
+	for _, backup := range manifest.Repositories {
+		if backup.Status == "completed" {
+			completed = append(completed, backup.Repository)
+		} else {
+			failed = append(failed, backup.Repository)
+		}
+	}
+	result["repositories"] = manifest.Repositories
+	result["failed_repositories"] = len(failed)
+	if len(failed) > 0 && (!task.ContinueOnError || len(completed) == 0) {
+		_ = archive.close()
+		return fmt.Errorf("backup of %d of %d repositories failed: %s", len(failed), len(names), strings.Join(failed, ", "))
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		_ = archive.close()
+		return err
+	}
+	if err := archive.addData(serverBackupManifestFile, data); err != nil {
+		_ = archive.close()
+		return fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+	if err := archive.close(); err != nil {
+		return fmt.Errorf("failed to write backup archive: %w", err)
+	}
+	info, err := os.Stat(archiveFile)
+	if err != nil {
+		return err
+	}
+	keepArchive = true
+
+	result["message"] = fmt.Sprintf("Backed up %d repositories of %s", len(completed), manifest.Server)
+	if len(failed) > 0 {
+		result["status"] = "partial"
+	}
+	result["backup_id"] = manifest.ID
+	result["archive"] = archiveFile
+	result["archive_size"] = info.Size()
+	result["download"] = "/v1/api/server-backups/" + manifest.ID
+	return nil
+}
+
+// registerServerBackupEndpoints adds the download of server-backup archives
+func registerServerBackupEndpoints(apiGroup *echo.Group, apiKeyMiddleware echo.MiddlewareFunc) {
+	var middleware []echo.MiddlewareFunc
+	if apiKeyMiddleware != nil {
+		middleware = append(middleware, apiKeyMiddleware)
+	}
+
+	// GET /v1/api/server-backups/:id - Download the archive of a server-backup task
+	apiGroup.GET("/server-backups/:id", downloadServerBackupREST, middleware...)
+}
+
+// downloadServerBackupREST handles REST GET /v1/api/server-backups/:id
+//
+// The archive is streamed as an attachment named server-backup-<id>.tar.gz.
+func downloadServerBackupREST(c echo.Context) error {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid backup id '%s'", id)})
+	}
+	dir, err := serverBackupDir()
+	if err != nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+	}
+	archiveFile := filepath.Join(dir, id+".tar.gz")
+	if _, err := os.Stat(archiveFile); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": fmt.Sprintf("server backup '%s' not found", id)})
+	}
+	c.Response().Header().Set(echo.HeaderContentType, "application/gzip")
+	return c.Attachment(archiveFile, fmt.Sprintf("server-backup-%s.tar.gz", id))
+}
@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// serverRestoreRepo is the outcome of the restore of one repository of a server backup
+type serverRestoreRepo struct {
+	Repository string `json:"repository"`
+	Status     string `json:"status"` // completed, skipped or failed
+	Replaced   bool   `json:"replaced,omitempty"`
+	DataSize   int64  `json:"data_size,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// checkUploadedServerBackup checks that an uploaded server backup is a gzip file
+func checkUploadedServerBackup(fileHeader *multipart.FileHeader) error {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open backup archive %s: %w", fileHeader.Filename, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("backup archive %s is not a tar.gz file produced by server-backup", fileHeader.Filename)
+	}
+	return zr.Close()
+}
+
+// serverBackupEntry splits the name of a repository file in a server backup
+// archive, <repo>/<repo>.ttl or <repo>/<repo>.brf, into the repository and the
+// extension. Other entries, such as the manifest, report false.
+func serverBackupEntry(name string) (string, string, bool) {
+	dir, file := path.Split(path.Clean(name))
+	repo := strings.TrimSuffix(dir, "/")
+	if repo == "" || strings.Contains(repo, "/") || repo == "." || repo == ".." {
+		return "", "", false
+	}
+	for _, ext := range []string{".ttl", ".brf"} {
+		if file == repo+ext {
+			return repo, ext, true
+		}
+	}
+	return "", "", false
+}
+
+// restoreSelected reports whether a repository of the archive is restored:
+// it must be listed in tgt.repos, if given, and not in tgt.exclude_repos
+func restoreSelected(tgt *Repository, repo string) bool {
+	if len(tgt.Repos) > 0 && !slices.Contains(tgt.Repos, repo) {
+		return false
+	}
+	return !slices.Contains(tgt.ExcludeRepos, repo)
+}
+
+// restoreRepositoryFrom recreates a repository from the configuration and the
+// BRF data of a server backup. An existing repository is skipped, or deleted
+// first with force_recreate. A repository whose data fails to import is
+// deleted again, so no empty repository is left behind.
+func restoreRepositoryFrom(run *taskRun, client *http.Client, existing map[string]bool, repo, confFile, dataFile string) serverRestoreRepo {
+	task := run.task
+	restore := serverRestoreRepo{Repository: repo, Status: "failed"}
+
+	content, err := os.ReadFile(confFile)
+	if err != nil {
+		restore.Error = fmt.Sprintf("failed to read configuration: %v", err)
+		return restore
+	}
+	if id, err := parseRepositoryConfig(content); err != nil {
+		restore.Error = fmt.Sprintf("invalid configuration: %v", err)
+		return restore
+	} else if id != repo {
+		restore.Error = fmt.Sprintf("configuration is for repository '%s'", id)
+		return restore
+	}
+
+	if existing[repo] {
+		if !task.ForceRecreate {
+			restore.Status = "skipped"
+			restore.Error = "repository already exists; set force_recreate to replace it"
+			return restore
+		}
+		if err := run.graphDB(client).DeleteRepository(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repo); err != nil {
+			restore.Error = fmt.Sprintf("failed to delete the existing repository: %v", err)
+			return restore
+		}
+		restore.Replaced = true
+	}
+
+	if err := run.graphDB(client).RestoreConf(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, confFile); err != nil {
+		restore.Error = fmt.Sprintf("failed to create repository: %v", err)
+		return restore
+	}
+	upload, err := graphDBRestoreRepositoryData(client, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repo, dataFile, nil)
+	if err != nil {
+		_ = run.graphDB(client).DeleteRepository(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, repo)
+		restore.Error = fmt.Sprintf("failed to import data: %v", err)
+		return restore
+	}
+	restore.Status = "completed"
+	restore.DataSize = upload.Bytes
+	return restore
+}
+
+// executeServerRestoreTask executes the server-restore action.
+//
+// It reads the tar.gz archive of server-backup uploaded as task_{index}_files
+// and recreates every repository in it on the tgt server, or those selected
+// by tgt.repos and tgt.exclude_repos. The archive is read as a stream: each
+// repository is restored as soon as its configuration and data are extracted,
+// so only one repository is on disk at a time. Existing repositories are
+// skipped unless force_recreate is set. A failed repository fails the task
+// unless continue_on_error is set; the other repositories are restored anyway.
+func executeServerRestoreTask(run *taskRun) error {
+	task, files, taskIndex, progress, log, result, tgtClient, zitiClient := run.task, run.files, run.taskIndex, run.progress, run.log, run.result, run.tgtClient, run.zitiClient
+
+	if identityFile != "" {
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
+		if err != nil {
+			return err
+		}
+		tgtClient, err = zitiClient(tgtURL)
+		if err != nil {
+			return err
+		}
+	}
+	fileKey := fmt.Sprintf("task_%d_files", taskIndex)
+	if len(files[fileKey]) != 1 {
+		return fmt.Errorf("server-restore requires one backup archive uploaded with key '%s'", fileKey)
+	}
+	fileHeader := files[fileKey][0]
+
+	repos, err := run.graphDB(tgtClient).Repositories(task.Tgt.URL, task.Tgt.Username, task.Tgt.Password)
+	if err != nil {
+		return fmt.Errorf("failed to fetch repositories from %s: %w", task.Tgt.URL, err)
+	}
+	existing := make(map[string]bool)
+	for _, name := range getRepositoryNames(repos.Results.Bindings) {
+		existing[name] = true
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open backup archive %s: %w", fileHeader.Filename, err)
+	}
+	defer func() { _ = file.Close() }()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("backup archive %s is not a tar.gz file: %w", fileHeader.Filename, err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	// Configurations extracted so far by repository, waiting for their data
+	configs := make(map[string]string)
+	defer func() {
+		for _, confFile := range configs {
+			_ = os.Remove(confFile)
+		}
+	}()
+
+	restored := make([]serverRestoreRepo, 0)
+	seen := make(map[string]bool)
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read backup archive %s: %w", fileHeader.Filename, err)
+		}
+		if err := run.ctx.Err(); err != nil {
+			return err
+		}
+		repo, ext, ok := serverBackupEntry(header.Name)
+		if !ok || header.Typeflag != tar.TypeReg || !restoreSelected(task.Tgt, repo) {
+			continue
+		}
+
+		tempFile := run.tempFile(fmt.Sprintf("server_restore_%s%s", uuid.New().String(), ext))
+		if err := extractTarEntry(tr, tempFile); err != nil {
+			_ = os.Remove(tempFile)
+			return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+		}
+		if ext == ".ttl" {
+			if previous, ok := configs[repo]; ok {
+				_ = os.Remove(previous)
+			}
+			configs[repo] = tempFile
+			continue
+		}
+
+		seen[repo] = true
+		confFile, ok := configs[repo]
+		var restore serverRestoreRepo
+		if !ok {
+			restore = serverRestoreRepo{Repository: repo, Status: "failed", Error: "configuration missing in the archive"}
+		} else {
+			progress("Restoring repositories", len(restored)+1, 0)
+			restore = restoreRepositoryFrom(run, tgtClient, existing, repo, confFile, tempFile)
+			_ = os.Remove(confFile)
+			delete(configs, repo)
+		}
+		_ = os.Remove(tempFile)
+		if restore.Status == "failed" {
+			log.Warn("Failed to restore repository", "repository", repo, "error", restore.Error)
+		}
+		restored = append(restored, restore)
+	}
+	for _, repo := range task.Tgt.Repos {
+		if !seen[repo] {
+			restored = append(restored, serverRestoreRepo{Repository: repo, Status: "failed", Error: "repository not found in the archive"})
+		}
+	}
+
+	var completed, skipped, failed []string
+	for _, restore := range restored {
+		switch restore.Status {
+		case "completed":
+			completed = append(completed, restore.Repository)
+		case "skipped":
+			skipped = append(skipped, restore.Repository)
+		default:
+			failed = append(failed, restore.Repository)
+		}
+	}
+	result["repositories"] = restored
+	result["restored_repositories"] = len(completed)
+	result["skipped_repositories"] = len(skipped)
+	result["failed_repositories"] = len(failed)
+	if len(restored) == 0 {
+		return fmt.Errorf("backup archive %s holds no repository to restore", fileHeader.Filename)
+	}
+	if len(failed) > 0 && !task.ContinueOnError {
+		return fmt.Errorf("restore of %d of %d repositories failed: %s", len(failed), len(restored), strings.Join(failed, ", "))
+	}
+
+	result["message"] = fmt.Sprintf("Restored %d repositories on %s", len(completed), redactURL(normalizeURL(task.Tgt.URL)))
+	if len(failed) > 0 {
+		result["status"] = "partial"
+	}
+	return nil
+}
+
+// extractTarEntry writes the current entry of an archive to fileName
+func extractTarEntry(tr *tar.Reader, fileName string) error {
+	out, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, tr)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
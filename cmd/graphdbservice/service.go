@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"regexp"
+	"strings"
 	"syscall"
 	"time"
 
@@ -21,6 +23,13 @@ import (
 // Global state manager
 var stateManager *statemanager.Manager
 
+// multipartMemoryBytes is the amount of a multipart upload kept in memory before
+// the remaining file parts are spilled to temp files (MULTIPART_MEMORY_MB).
+var multipartMemoryBytes int64 = 32 << 20
+
+// bodyLimitPattern matches the body limit format accepted by echo (e.g. "100M", "2G")
+var bodyLimitPattern = regexp.MustCompile(`^[1-9][0-9]*[KMGTP]?$`)
+
 var serviceCmd = &cobra.Command{
 	Use:   "service",
 	Short: "Start GraphDB semantic service",
@@ -42,7 +51,45 @@ Environment Variables:
   - GRAPHDB_SERVICE_URL: Public URL of this service (default: http://hostname:port)
   - REGISTRYSERVICE_API_URL: Registry service URL (default: http://localhost:8096)
   - HOSTNAME: Hostname for service identification (default: system hostname)
-  - API_KEY: Optional API key for endpoint protection`,
+  - GRAPHDB_API_KEY: Optional API key for endpoint protection (label "default")
+  - GRAPHDB_API_KEYS: Additional labelled API keys, as "label:key,..." or a JSON object of label to key
+  - GRAPHDB_ADMIN_KEYS: Labels of the API keys allowed to use /admin/migrations (default: default)
+  - GRAPHDB_IDENTITY_FILE: Ziti identity file for zero-trust networking
+  - GRAPHDB_SKIP_STARTUP_CHECK: Skip the startup configuration self-check (default: false)
+  - GRAPHDB_ALLOW_RELATIVE_GRAPHS: Accept relative graph names instead of requiring absolute IRIs (default: false)
+  - MULTIPART_MEMORY_MB: Memory used for multipart uploads before spilling to disk (default: 32)
+  - BODY_LIMIT: Maximum request body size, e.g. "100M" or "2G" (default: 100M)
+  - LOG_LEVEL: Task log level: debug, info, warn, error (default: info)
+  - LOG_FORMAT: Task log format: json or text (default: json)
+  - MIGRATION_LOG_DIR: Directory for migration session records (default: migration-logs)
+  - MIGRATION_LOG_RETENTION_DAYS: Delete migration sessions older than this many days, daily, 0 = keep (default: 0)
+  - MAX_CONCURRENT_SESSIONS: Migration sessions running at once, 0 = unlimited (default: 4)
+  - MAX_QUEUED_SESSIONS: Sessions waiting for a free slot before submissions are rejected with 503 (default: 16)
+  - SMTP_HOST, SMTP_PORT, SMTP_FROM, SMTP_TO, SMTP_USERNAME, SMTP_PASSWORD: Email a summary of finished sessions (default: disabled, port 587)
+  - SLACK_WEBHOOK_URL: Post a summary of finished sessions to a Slack incoming webhook (default: disabled)
+  - NOTIFY_ON: Sessions reported by the notifiers: failure or always (default: failure)
+  - UPLOAD_CHUNK_SIZE_KB: Block size in which BRF data is read while it is uploaded (default: 1024)
+  - GRAPHDB_IMPORT_POLL_MS: Interval of the import status requests of async_import graph imports (default: 2000)
+  - MAX_IMPORT_FILES: Most files a graph-import task accepts, 0 = unlimited (default: 100)
+  - MAX_FILE_SIZE_MB: Largest file a graph-import task accepts, 0 = unlimited (default: 100)
+  - SERVER_BACKUP_DIR: Directory for the archives of server-backup (default: MIGRATION_LOG_DIR/server-backups)
+  - SERVER_BACKUP_CONCURRENCY: Repositories a server-backup downloads at once (default: 2)
+  - MIGRATION_TEMP_DIR: Directory for uploads, exports and downloads of tasks (default: TEMP_DIR or the system temp directory)
+  - ALLOWED_TEMP_DIRS: Comma separated directories whose subdirectories requests may use as temp_dir besides MIGRATION_TEMP_DIR
+  - CALLBACK_RETRY_ATTEMPTS: Delivery attempts for async result callbacks (default: 5)
+  - CALLBACK_ALLOWED_HOSTS: Callback hosts that may resolve to internal addresses, comma separated (default: none)
+  - SPARQL_UPDATE_ENABLED: Allow the sparql-update action (default: false)
+  - SPARQL_UPDATE_SAFE_MODE: Reject DROP, CLEAR, MOVE, COPY, ADD, LOAD and deletes without a fixed graph in sparql-update (default: true)
+  - SPARQL_QUERY_MAX_ROWS: Most result rows returned by sparql-query (default: 1000)
+  - LISTING_CACHE_TTL_SECONDS: Time the tasks of a request reuse repository and graph listings, 0 = disabled (default: 5)
+  - SHUTDOWN_TIMEOUT_SECONDS: Time a shutdown waits for active migration sessions before marking them interrupted (default: 60)
+  - GRAPHDB_HEALTH_TARGETS: Comma separated GraphDB URLs checked by /health/ready (default: none)
+  - GRAPHDB_HEALTH_CACHE_SECONDS: Time a readiness check result is reused (default: 10)
+  - GRAPHDB_HEALTH_TIMEOUT_SECONDS: Timeout of the readiness check of one GraphDB server (default: 5)
+  - EXPORT_COMPRESS_THRESHOLD_MB: Size from which intermediate export files are gzipped (default: 64)
+  - GRAPHDB_GZIP: Gzip responses for clients sending Accept-Encoding: gzip (default: false)
+  - GRAPHDB_GZIP_LEVEL: Gzip level of responses, 1-9 or -1 for the default (default: -1)
+  - GRAPHDB_GZIP_MIN_BYTES: Size from which responses are gzipped (default: 1024)`,
 	Run: runSemanticService,
 }
 
@@ -54,6 +101,8 @@ func init() {
 	serviceCmd.Flags().String("registry-url", "", "Registry service URL")
 	serviceCmd.Flags().String("api-key", "", "API key for endpoint protection")
 	serviceCmd.Flags().Bool("debug", false, "Enable debug logging")
+	serviceCmd.Flags().String("identity", "", "Ziti identity file for zero-trust networking")
+	serviceCmd.Flags().Bool("skip-startup-check", false, "Skip the startup configuration self-check")
 }
 
 func runSemanticService(cmd *cobra.Command, args []string) {
@@ -61,12 +110,18 @@ func runSemanticService(cmd *cobra.Command, args []string) {
 	serverConfig := evehttp.DefaultServerConfig()
 	serverConfig.Port = common.GetEnvInt("GRAPHDB_SERVICE_PORT", 8080)
 	serverConfig.Debug = common.GetEnvBool("GRAPHDB_DEBUG", false)
-	serverConfig.BodyLimit = "100M"
+	serverConfig.BodyLimit = strings.ToUpper(common.GetEnv("BODY_LIMIT", "100M"))
+	multipartMemoryMB := common.GetEnvInt("MULTIPART_MEMORY_MB", 32)
 
 	// Service configuration
 	serviceURL := common.GetEnv("GRAPHDB_SERVICE_URL", "")
 	registryURL := common.GetEnv("GRAPHDB_REGISTRY_URL", "http://localhost:8096")
 	apiKey := common.GetEnv("GRAPHDB_API_KEY", "")
+	identityFile = common.GetEnv("GRAPHDB_IDENTITY_FILE", "")
+	skipStartupCheck := common.GetEnvBool("GRAPHDB_SKIP_STARTUP_CHECK", false)
+	migrationLogDir := common.GetEnv("MIGRATION_LOG_DIR", "migration-logs")
+	migrationTempDir = configuredTempDir()
+	migrationSessions = configuredSessionLimiter()
 
 	// Override from flags if provided
 	if flagPort, _ := cmd.Flags().GetInt("port"); flagPort != 0 {
@@ -84,9 +139,16 @@ func runSemanticService(cmd *cobra.Command, args []string) {
 	if flagDebug, _ := cmd.Flags().GetBool("debug"); flagDebug {
 		serverConfig.Debug = true
 	}
+	if flagIdentity, _ := cmd.Flags().GetString("identity"); flagIdentity != "" {
+		identityFile = flagIdentity
+	}
+	if flagSkip, _ := cmd.Flags().GetBool("skip-startup-check"); flagSkip {
+		skipStartupCheck = true
+	}
 
 	// Set debug mode globally
 	debugMode = serverConfig.Debug
+	initLogging(debugMode)
 
 	// Determine service URL if not provided
 	if serviceURL == "" {
@@ -106,14 +168,95 @@ func runSemanticService(cmd *cobra.Command, args []string) {
 	logger.Info("=====================================")
 	logger.Info("GraphDB Semantic Service Starting")
 	logger.Info("=====================================")
+
+	apiKeys, keyErr := loadAPIKeys(apiKey, common.GetEnv("GRAPHDB_API_KEYS", ""))
+	if keyErr != nil {
+		logger.WithError(keyErr).Fatal("Invalid API key configuration")
+	}
+
 	logger.WithFields(map[string]interface{}{
 		"service_url":  serviceURL,
 		"registry_url": registryURL,
 		"port":         serverConfig.Port,
 		"debug":        serverConfig.Debug,
-		"api_key_set":  apiKey != "",
+		"api_keys":     len(apiKeys),
+		"ziti_enabled": identityFile != "",
 	}).Info("Configuration loaded")
 
+	// Validate and apply request size limits
+	if !bodyLimitPattern.MatchString(serverConfig.BodyLimit) {
+		logger.Fatal(fmt.Sprintf("Invalid BODY_LIMIT '%s': expected a size like 100M or 2G", serverConfig.BodyLimit))
+	}
+	if multipartMemoryMB <= 0 {
+		logger.Fatal(fmt.Sprintf("Invalid MULTIPART_MEMORY_MB %d: must be greater than 0", multipartMemoryMB))
+	}
+	multipartMemoryBytes = int64(multipartMemoryMB) << 20
+	logger.WithFields(map[string]interface{}{
+		"body_limit":          serverConfig.BodyLimit,
+		"multipart_memory_mb": multipartMemoryMB,
+	}).Info("Request size limits configured")
+
+	// Validate configuration before accepting any traffic
+	if skipStartupCheck {
+		logger.Warn("Startup self-check skipped")
+	} else {
+		check := runStartupSelfCheck(startupCheckConfig{
+			Port:         serverConfig.Port,
+			ServiceURL:   serviceURL,
+			RegistryURL:  registryURL,
+			APIKeys:      len(apiKeys),
+			IdentityFile: identityFile,
+			TempDir:      migrationTempDir,
+		})
+		for _, warning := range check.Warnings {
+			logger.Warn(warning)
+		}
+		if err := check.Err(); err != nil {
+			logger.WithError(err).Fatal("Invalid configuration")
+		}
+		logger.Info("Startup self-check passed")
+	}
+
+	// Record migration sessions submitted via the task endpoint
+	if ml, err := NewMigrationLogger(migrationLogDir); err != nil {
+		logger.WithError(err).Warn("Migration session logging disabled")
+	} else {
+		migrationLogger = ml
+		logger.WithFields(map[string]interface{}{
+			"dir": migrationLogDir,
+		}).Info("Migration session logging enabled")
+
+		// Purge sessions beyond the retention period daily
+		if retentionDays := common.GetEnvInt("MIGRATION_LOG_RETENTION_DAYS", 0); retentionDays > 0 {
+			ml.startRetention(retentionDays)
+			logger.WithFields(map[string]interface{}{
+				"retention_days": retentionDays,
+			}).Info("Migration session retention enabled")
+		}
+
+		// Report finished sessions to the configured notifiers (email, Slack)
+		if notifiers, err := configuredNotifiers(serviceURL); err != nil {
+			logger.WithError(err).Warn("Session notifications disabled")
+		} else {
+			for _, notifier := range notifiers {
+				ml.AddNotifier(notifier)
+				logger.WithFields(map[string]interface{}{
+					"notifier":  notifier.Name(),
+					"notify_on": common.GetEnv("NOTIFY_ON", notifyOnFailure),
+				}).Info("Session notifications enabled")
+			}
+		}
+	}
+	// Async callbacks are signed with the API key of their request
+	setCallbackSecrets(apiKeys)
+
+	// Resume the callback sessions that were queued when the service stopped
+	if ids := recoverQueuedSessions(); len(ids) > 0 {
+		logger.WithFields(map[string]interface{}{
+			"sessions": ids,
+		}).Info("Resumed queued migration sessions")
+	}
+
 	// Register action handlers with the semantic action registry
 	// This allows the service to handle semantic actions without modifying switch statements
 	semantic.MustRegister("TransferAction", executeSemanticTransferAction)
@@ -136,6 +279,11 @@ func runSemanticService(cmd *cobra.Command, args []string) {
 	// Add security headers middleware
 	e.Use(evehttp.SecurityHeadersMiddleware())
 
+	// Compress responses of clients accepting gzip (GRAPHDB_GZIP)
+	if gzipMiddleware := responseGzipMiddleware(); gzipMiddleware != nil {
+		e.Use(gzipMiddleware)
+	}
+
 	// Initialize tracing (gracefully disabled if unavailable)
 	if tracer := tracing.Init(tracing.InitConfig{
 		ServiceID:        "graphdbservice",
@@ -150,24 +298,50 @@ func runSemanticService(cmd *cobra.Command, args []string) {
 
 	// API key middleware
 	var apiKeyMiddleware echo.MiddlewareFunc
-	if apiKey != "" {
-		apiKeyMiddleware = evehttp.APIKeyMiddleware(apiKey)
+	if len(apiKeys) > 0 {
+		apiKeyMiddleware = apiKeysMiddleware(apiKeys)
 		// Semantic action endpoint with API key protection (primary interface)
 		apiGroup.POST("/semantic/action", handleSemanticAction, apiKeyMiddleware)
+		// Task based endpoint (MigrationRequest with version and tasks)
+		apiGroup.POST("/action", migrationHandler, apiKeyMiddleware)
+		// Validation of task requests without executing them
+		apiGroup.POST("/validate", validateRequestHandler, apiKeyMiddleware)
 	} else {
 		// Semantic action endpoint without protection (primary interface)
 		apiGroup.POST("/semantic/action", handleSemanticAction)
+		// Task based endpoint (MigrationRequest with version and tasks)
+		apiGroup.POST("/action", migrationHandler)
+		// Validation of task requests without executing them
+		apiGroup.POST("/validate", validateRequestHandler)
 	}
 
 	// REST endpoints (convenience adapters that convert to semantic actions)
-	if apiKey != "" {
+	if apiKeyMiddleware != nil {
 		registerRESTEndpoints(apiGroup, apiKeyMiddleware)
 	} else {
 		registerRESTEndpoints(apiGroup, nil)
 	}
 
-	// Health check endpoint using EVE utilities (always public)
-	e.GET("/health", evehttp.HealthCheckHandler("graphdb-semantic", "v1"))
+	// Read-only discovery endpoints for repositories and graphs
+	registerRepositoryEndpoints(apiGroup, apiKeyMiddleware)
+
+	// Migration session status endpoints
+	registerSessionEndpoints(apiGroup, apiKeyMiddleware)
+	registerAdminMigrationEndpoints(e.Group("/admin/migrations"), apiKeyMiddleware)
+
+	// Download of server-backup archives
+	registerServerBackupEndpoints(apiGroup, apiKeyMiddleware)
+
+	// Description of the supported task actions (public, like the docs)
+	apiGroup.GET("/actions", listActionsREST)
+	// JSON schema of task requests (public, like the docs)
+	apiGroup.GET("/schema", requestSchemaREST)
+
+	// Health check endpoints (always public): liveness via EVE utilities, and
+	// readiness checking the GraphDB servers of GRAPHDB_HEALTH_TARGETS
+	readiness := newReadinessChecker()
+	e.GET("/health", healthHandler(evehttp.HealthCheckHandler("graphdb-semantic", "v1"), readiness))
+	e.GET("/health/ready", readiness.handler)
 
 	// Documentation endpoint
 	e.GET("/v1/api/docs", evehttp.DocumentationHandler(evehttp.ServiceDocConfig{
@@ -188,6 +362,26 @@ func runSemanticService(cmd *cobra.Command, args []string) {
 				Path:        "/v1/api/semantic/action",
 				Description: "Execute semantic actions for GraphDB operations (primary interface)",
 			},
+			{
+				Method:      "POST",
+				Path:        "/v1/api/action",
+				Description: "Execute a list of GraphDB tasks (version + tasks, optionally parallel); Accept: application/ld+json returns a Schema.org ItemList of actions",
+			},
+			{
+				Method:      "POST",
+				Path:        "/v1/api/validate",
+				Description: "Validate a task request (JSON or multipart) without executing it; returns per-task errors with field names",
+			},
+			{
+				Method:      "GET",
+				Path:        "/v1/api/actions",
+				Description: "Describe the supported task actions, their src/tgt fields, file uploads and options",
+			},
+			{
+				Method:      "GET",
+				Path:        "/v1/api/schema",
+				Description: "JSON schema of the task request body; POST /v1/api/action and /v1/api/validate report all schema violations at once",
+			},
 			{
 				Method:      "POST",
 				Path:        "/v1/api/queries",
@@ -213,10 +407,90 @@ func runSemanticService(cmd *cobra.Command, args []string) {
 				Path:        "/v1/api/relationships",
 				Description: "Create relationship (REST convenience - converts to CreateAction)",
 			},
+			{
+				Method:      "GET",
+				Path:        "/v1/api/server-info",
+				Description: "GraphDB version and edition of a server (query: url, username, password)",
+			},
+			{
+				Method:      "GET",
+				Path:        "/v1/api/repositories",
+				Description: "List repositories on a GraphDB server (query: url, username, password)",
+			},
+			{
+				Method:      "GET",
+				Path:        "/v1/api/repositories/:repo/graphs",
+				Description: "List named graphs in a repository with triple counts (query: url, username, password, prefix)",
+			},
+			{
+				Method:      "GET",
+				Path:        "/v1/api/repositories/:repo/graphs/export",
+				Description: "Download a named graph in a chosen RDF format (query: url, username, password, graph, format)",
+			},
+			{
+				Method:      "GET",
+				Path:        "/v1/api/repositories/:repo/export",
+				Description: "Download a repository as BRF backup or its TTL config (query: url, username, password, format=brf|ttl)",
+			},
+			{
+				Method:      "POST",
+				Path:        "/v1/api/repositories/diff",
+				Description: "Compare the graphs of two repositories (body: source, target, triple_counts, summary)",
+			},
+			{
+				Method:      "GET",
+				Path:        "/admin/migrations",
+				Description: "List migration session summaries, newest first (query: from, to, offset, limit; admin API key)",
+			},
+			{
+				Method:      "GET",
+				Path:        "/admin/migrations/stats",
+				Description: "Aggregated migration statistics (query: from, to, action, username, format=json|csv; admin API key)",
+			},
+			{
+				Method:      "GET",
+				Path:        "/admin/migrations/metrics",
+				Description: "Migration statistics in Prometheus text format (query: from, to; admin API key)",
+			},
+			{
+				Method:      "POST",
+				Path:        "/admin/migrations/purge",
+				Description: "Delete the migration sessions older than days days (query: days, default MIGRATION_LOG_RETENTION_DAYS; admin API key); running sessions are kept",
+			},
+			{
+				Method:      "GET",
+				Path:        "/v1/api/sessions/:id",
+				Description: "Get the status of a migration session started with the same API key (or any with an admin key) including per-task progress",
+			},
+			{
+				Method:      "GET",
+				Path:        "/admin/migrations/session/:id/request",
+				Description: "Download the original request of a migration session with passwords, tokens, header values and URL credentials masked (admin API key)",
+			},
+			{
+				Method:      "GET",
+				Path:        "/admin/migrations/session/:id/itemlist",
+				Description: "Migration session as JSON-LD Schema.org ItemList with each task as action and its actionStatus (admin API key)",
+			},
+			{
+				Method:      "POST",
+				Path:        "/v1/api/sessions/:id/cancel",
+				Description: "Cancel a running migration session started with the same API key (or any with an admin key); tasks not started are reported cancelled, abort=true also aborts the running tasks",
+			},
+			{
+				Method:      "GET",
+				Path:        "/v1/api/server-backups/:id",
+				Description: "Download the tar.gz archive of a server-backup task",
+			},
 			{
 				Method:      "GET",
 				Path:        "/health",
-				Description: "Health check endpoint",
+				Description: "Health check endpoint (liveness); deep=true checks the GraphDB servers like /health/ready",
+			},
+			{
+				Method:      "GET",
+				Path:        "/health/ready",
+				Description: "Readiness check: 503 with the status of each server if a GraphDB server of GRAPHDB_HEALTH_TARGETS is unreachable",
 			},
 		},
 	}))
@@ -299,8 +573,9 @@ func runSemanticService(cmd *cobra.Command, args []string) {
 		logger.Info("Successfully deregistered from registry")
 	}
 
-	// Shutdown server using EVE http utilities
-	if err := evehttp.GracefulShutdown(e, serverConfig.ShutdownTimeout); err != nil {
+	// Stop accepting requests and let the active migration sessions finish;
+	// sessions still running after SHUTDOWN_TIMEOUT_SECONDS are marked interrupted
+	if err := shutdownService(e, shutdownTimeout()); err != nil {
 		logger.WithError(err).Error("Error during graceful shutdown")
 	}
 
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+)
+
+// errTaskCancelled is wrapped by the error of a task stopped by a cancelled session
+var errTaskCancelled = errors.New("task cancelled")
+
+// sessionControl lets another request cancel a running session. Once stopped
+// no further task of the session is started; cancel additionally aborts the
+// GraphDB requests of the tasks that are running. queued is set while the
+// session waits for a free slot (see sessionLimiter), slot is its admission.
+type sessionControl struct {
+	stopped atomic.Bool
+	queued  atomic.Bool
+	slot    atomic.Pointer[sessionSlot]
+	cancel  context.CancelFunc
+}
+
+var (
+	runningSessions      = make(map[string]*sessionControl)
+	runningSessionsMutex sync.Mutex
+)
+
+// startSessionControl registers a running session and returns the context its
+// tasks run in. done must be called when all tasks of the session finished.
+func startSessionControl(sessionID string) (context.Context, *sessionControl, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	control := &sessionControl{cancel: cancel}
+	if sessionID == "" {
+		return ctx, control, cancel
+	}
+
+	runningSessionsMutex.Lock()
+	runningSessions[sessionID] = control
+	runningSessionsMutex.Unlock()
+
+	return ctx, control, func() {
+		runningSessionsMutex.Lock()
+		delete(runningSessions, sessionID)
+		runningSessionsMutex.Unlock()
+		cancel()
+	}
+}
+
+// cancelRunningSession stops a running session after its current tasks, or
+// aborts them as well with abort. A queued session is removed from the queue.
+// It reports whether the session was running or queued.
+func cancelRunningSession(sessionID string, abort bool) bool {
+	runningSessionsMutex.Lock()
+	control, running := runningSessions[sessionID]
+	runningSessionsMutex.Unlock()
+	if !running {
+		return false
+	}
+
+	control.stopped.Store(true)
+	if abort || control.queued.Load() {
+		control.cancel()
+	}
+	return true
+}
+
+// sessionQueuePosition returns the place of a queued session in the queue of
+// migrationSessions, or 0 if it is not queued
+func sessionQueuePosition(sessionID string) int {
+	runningSessionsMutex.Lock()
+	control, running := runningSessions[sessionID]
+	runningSessionsMutex.Unlock()
+	if !running || !control.queued.Load() {
+		return 0
+	}
+	return control.slot.Load().position()
+}
+
+// runningSessionIDs returns the IDs of the running and queued sessions
+func runningSessionIDs() []string {
+	runningSessionsMutex.Lock()
+	defer runningSessionsMutex.Unlock()
+
+	ids := make([]string, 0, len(runningSessions))
+	for id := range runningSessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// maySessionBeReadBy reports whether a request may see a session: an admin API
+// key sees any session, other keys only the sessions started with them.
+// Sessions are attributed to the key label (see startMigrationSession).
+func maySessionBeReadBy(c echo.Context, session *MigrationSession) bool {
+	return isAdminRequest(c) || session.Username == apiKeyLabel(c)
+}
+
+// maySessionBeCancelledBy reports whether a request may cancel a session, the
+// same sessions it may read. Without session logging only admins can cancel.
+func maySessionBeCancelledBy(c echo.Context, sessionID string) bool {
+	if isAdminRequest(c) {
+		return true
+	}
+	if migrationLogger == nil {
+		return false
+	}
+	session, err := migrationLogger.GetSession(sessionID)
+	return err == nil && maySessionBeReadBy(c, session)
+}
+
+// cancelSessionREST handles REST POST /v1/api/sessions/:id/cancel
+//
+// Tasks of the session that have not started yet are not run and reported as
+// cancelled. With abort=true the running tasks are cancelled as well by
+// aborting their GraphDB requests; otherwise they run to completion. Only the
+// API key that started the session or an admin key may cancel it.
+func cancelSessionREST(c echo.Context) error {
+	sessionID := c.Param("id")
+	abort := c.QueryParam("abort") == "true"
+
+	if !maySessionBeCancelledBy(c, sessionID) {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "API key may only cancel its own sessions"})
+	}
+	if !cancelRunningSession(sessionID, abort) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "session not found or not running"})
+	}
+
+	serviceLog.Info("Migration session cancelled", "session_id", sessionID, "abort", abort, "api_key", apiKeyLabel(c))
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"session_id": sessionID,
+		"status":     "cancelling",
+		"abort":      abort,
+	})
+}
@@ -0,0 +1,346 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// registerSessionEndpoints adds the endpoints to poll migration sessions
+func registerSessionEndpoints(apiGroup *echo.Group, apiKeyMiddleware echo.MiddlewareFunc) {
+	var middleware []echo.MiddlewareFunc
+	if apiKeyMiddleware != nil {
+		middleware = append(middleware, apiKeyMiddleware)
+	}
+
+	// GET /v1/api/sessions/:id - Status of a migration session
+	apiGroup.GET("/sessions/:id", getSessionREST, middleware...)
+
+	// POST /v1/api/sessions/:id/cancel - Stop a running migration session
+	apiGroup.POST("/sessions/:id/cancel", cancelSessionREST, middleware...)
+}
+
+// registerAdminMigrationEndpoints adds the admin endpoints of the migration
+// sessions of all clients to the /admin/migrations group. With API keys they
+// require a key listed in GRAPHDB_ADMIN_KEYS.
+func registerAdminMigrationEndpoints(adminGroup *echo.Group, apiKeyMiddleware echo.MiddlewareFunc) {
+	if apiKeyMiddleware != nil {
+		adminGroup.Use(apiKeyMiddleware, adminOnlyMiddleware)
+	}
+
+	// GET /admin/migrations - Paginated listing of migration sessions
+	adminGroup.GET("", listSessionsREST)
+
+	// GET /admin/migrations/stats - Aggregated statistics, optionally filtered by action and user
+	adminGroup.GET("/stats", getSessionStatsREST)
+
+	// GET /admin/migrations/metrics - Statistics in Prometheus text exposition format
+	adminGroup.GET("/metrics", getSessionMetricsREST)
+
+	// POST /admin/migrations/purge - Delete sessions older than a number of days
+	adminGroup.POST("/purge", purgeSessionsREST)
+
+	// GET /admin/migrations/session/:id/request - Original request of a migration session
+	adminGroup.GET("/session/:id/request", getSessionRequestREST)
+
+	// GET /admin/migrations/session/:id/itemlist - Migration session as JSON-LD ItemList of actions
+	adminGroup.GET("/session/:id/itemlist", getSessionItemListREST)
+}
+
+const (
+	// defaultSessionListDays is the date range used when no from date is given
+	defaultSessionListDays = 30
+	// maxSessionRangeDays bounds the from/to range, every day of it is read from disk
+	maxSessionRangeDays = 366
+	// defaultSessionPageSize is the page size used when no limit is given
+	defaultSessionPageSize = 50
+	// maxSessionPageSize bounds the limit query parameter
+	maxSessionPageSize = 500
+)
+
+// listSessionsREST handles REST GET /admin/migrations
+//
+// Query parameters: from and to (YYYY-MM-DD, default the last 30 days), offset
+// and limit (default 50, max 500). Returns session summaries newest first with
+// the total number of sessions in the range.
+func listSessionsREST(c echo.Context) error {
+	if migrationLogger == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "migration session logging is disabled"})
+	}
+
+	start, end, err := sessionDateRange(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil || offset < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "offset must be a non-negative integer"})
+	}
+	limit, err := queryInt(c, "limit", defaultSessionPageSize)
+	if err != nil || limit < 1 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "limit must be a positive integer"})
+	}
+	if limit > maxSessionPageSize {
+		limit = maxSessionPageSize
+	}
+
+	sessions, total, err := migrationLogger.GetSessionsPaged(start, end, offset, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"total":    total,
+		"offset":   offset,
+		"limit":    limit,
+		"sessions": sessions,
+	})
+}
+
+// getSessionStatsREST handles REST GET /admin/migrations/stats
+//
+// Query parameters: from and to (YYYY-MM-DD, default the last 30 days), action
+// (only tasks of this action) and username (only sessions of this user).
+// With format=csv one row per matching session is returned instead.
+func getSessionStatsREST(c echo.Context) error {
+	if migrationLogger == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "migration session logging is disabled"})
+	}
+
+	start, end, err := sessionDateRange(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	filter := StatisticsFilter{
+		Action:   c.QueryParam("action"),
+		Username: c.QueryParam("username"),
+	}
+
+	switch format := c.QueryParam("format"); format {
+	case "", "json":
+	case "csv":
+		return sessionStatsCSV(c, start, end, filter)
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("unsupported format '%s' (supported: json, csv)", format)})
+	}
+
+	stats, err := migrationLogger.GetFilteredStatistics(start, end, filter)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
+
+// sessionStatsCSV writes one CSV row per session matching filter
+func sessionStatsCSV(c echo.Context, start, end time.Time, filter StatisticsFilter) error {
+	sessions, err := migrationLogger.GetFilteredSessions(start, end, filter)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="migration-sessions.csv"`)
+	c.Response().WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(c.Response())
+	_ = w.Write([]string{"session_id", "username", "status", "start_time", "end_time", "duration_ms", "total_tasks", "completed_tasks", "failed_tasks", "data_size_bytes"})
+	for _, session := range sessions {
+		endTime := ""
+		if session.EndTime != nil {
+			endTime = session.EndTime.Format(time.RFC3339)
+		}
+		_ = w.Write([]string{
+			session.ID,
+			session.Username,
+			session.Status,
+			session.StartTime.Format(time.RFC3339),
+			endTime,
+			strconv.FormatInt(session.DurationMs, 10),
+			strconv.Itoa(session.TotalTasks),
+			strconv.Itoa(session.CompletedTasks),
+			strconv.Itoa(session.FailedTasks),
+			strconv.FormatInt(session.TotalDataSize, 10),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// getSessionMetricsREST handles REST GET /admin/migrations/metrics
+//
+// Emits the statistics of the last 30 days (or the from/to range) in Prometheus
+// text exposition format. The values are gauges over that window.
+func getSessionMetricsREST(c echo.Context) error {
+	if migrationLogger == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "migration session logging is disabled"})
+	}
+
+	start, end, err := sessionDateRange(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	stats, err := migrationLogger.GetStatistics(start, end)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	var b strings.Builder
+	gauge := func(name, help string, value float64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", name, help, name, name, strconv.FormatFloat(value, 'f', -1, 64))
+	}
+	gauge("graphdbservice_migration_sessions", "Migration sessions started in the window", float64(stats.TotalSessions))
+	gauge("graphdbservice_migration_tasks", "Migration tasks started in the window", float64(stats.TotalTasks))
+	gauge("graphdbservice_migration_tasks_completed", "Migration tasks completed successfully", float64(stats.CompletedTasks))
+	gauge("graphdbservice_migration_tasks_failed", "Migration tasks that failed, including timeouts", float64(stats.FailedTasks))
+	gauge("graphdbservice_migration_tasks_timeout", "Migration tasks cancelled by their timeout", float64(stats.TimeoutTasks))
+	gauge("graphdbservice_migration_tasks_cancelled", "Migration tasks cancelled with their session", float64(stats.CancelledTasks))
+	gauge("graphdbservice_migration_tasks_skipped", "Migration tasks completed without changes, e.g. unchanged graph imports", float64(stats.SkippedTasks))
+	gauge("graphdbservice_migration_data_size_bytes", "Data transferred by migration tasks", float64(stats.TotalDataSize))
+	gauge("graphdbservice_migration_success_rate", "Percentage of finished tasks that completed successfully", stats.SuccessRate)
+
+	b.WriteString("# HELP graphdbservice_migration_action_tasks Migration tasks per action\n# TYPE graphdbservice_migration_action_tasks gauge\n")
+	actions := make([]string, 0, len(stats.ActionCounts))
+	for action := range stats.ActionCounts {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for _, action := range actions {
+		fmt.Fprintf(&b, "graphdbservice_migration_action_tasks{action=%q} %d\n", action, stats.ActionCounts[action])
+	}
+
+	return c.Blob(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+// sessionDateRange parses the from and to query parameters (YYYY-MM-DD).
+// The range defaults to the last defaultSessionListDays days.
+func sessionDateRange(c echo.Context) (time.Time, time.Time, error) {
+	end := time.Now().UTC()
+	start := end.AddDate(0, 0, -defaultSessionListDays)
+	if to := c.QueryParam("to"); to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return start, end, fmt.Errorf("invalid to date, expected YYYY-MM-DD")
+		}
+		end = t
+	}
+	if from := c.QueryParam("from"); from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return start, end, fmt.Errorf("invalid from date, expected YYYY-MM-DD")
+		}
+		start = t
+	}
+	if end.Before(start) {
+		return start, end, fmt.Errorf("to date is before from date")
+	}
+	if end.Sub(start) > maxSessionRangeDays*24*time.Hour {
+		return start, end, fmt.Errorf("date range is longer than %d days", maxSessionRangeDays)
+	}
+	return start, end, nil
+}
+
+// queryInt parses an integer query parameter, returning def when it is absent
+func queryInt(c echo.Context, name string, def int) (int, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// getSessionREST handles REST GET /v1/api/sessions/:id
+//
+// Returns the MigrationSession including per-task status and progress. Running
+// sessions are served from memory, finished sessions from disk. Queued sessions
+// report their queue_position. A session started with another API key is
+// reported as not found unless the request uses an admin key.
+func getSessionREST(c echo.Context) error {
+	if migrationLogger == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "migration session logging is disabled"})
+	}
+
+	session, err := migrationLogger.GetSession(c.Param("id"))
+	if errors.Is(err, errSessionNotFound) || (err == nil && !maySessionBeReadBy(c, session)) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "session not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if session.Status == sessionStatusQueued {
+		session.QueuePosition = sessionQueuePosition(session.ID)
+	}
+
+	return c.JSON(http.StatusOK, session)
+}
+
+// getSessionRequestREST handles REST GET /admin/migrations/session/:id/request
+//
+// Returns the request a session was started with as a JSON download, with all
+// passwords, tokens and URL credentials masked, so a migration can be
+// reproduced. Returns 404 if the session or its stored request does not exist.
+func getSessionRequestREST(c echo.Context) error {
+	if migrationLogger == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "migration session logging is disabled"})
+	}
+
+	session, err := migrationLogger.GetSession(c.Param("id"))
+	if errors.Is(err, errSessionNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "session not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	requestJSON, ok := session.Metadata[sessionRequestKey]
+	if !ok || requestJSON == "" {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "session has no stored request"})
+	}
+
+	// Masked again, so the download never depends on how the session was stored
+	masked, err := redactRequestJSON(requestJSON)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("stored request is not valid JSON: %v", err)})
+	}
+	var body bytes.Buffer
+	if err := json.Indent(&body, []byte(masked), "", "  "); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="session-%s-request.json"`, session.ID))
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, body.Bytes())
+}
+
+// purgeSessionsREST handles REST POST /admin/migrations/purge
+//
+// Query parameter: days, the age in days beyond which sessions are deleted,
+// defaulting to MIGRATION_LOG_RETENTION_DAYS. Running sessions are kept.
+// Returns the number of purged sessions and files and the reclaimed bytes.
+func purgeSessionsREST(c echo.Context) error {
+	if migrationLogger == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "migration session logging is disabled"})
+	}
+
+	days, err := queryInt(c, "days", migrationLogger.retentionDays)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if days < 1 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "days must be at least 1"})
+	}
+
+	result, err := migrationLogger.PurgeOlderThan(days)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": err.Error(), "result": result})
+	}
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// sessionActionStatus maps the status of a session or task to a Schema.org
+// ActionStatusType. Schema.org has no cancelled status, so cancelled and
+// interrupted tasks count as failed.
+func sessionActionStatus(status string) string {
+	switch status {
+	case sessionStatusCompleted, sessionStatusSkipped:
+		return "CompletedActionStatus"
+	case sessionStatusFailed, sessionStatusCancelled, sessionStatusInterrupted:
+		return "FailedActionStatus"
+	case sessionStatusRunning:
+		return "ActiveActionStatus"
+	}
+	return "PotentialActionStatus"
+}
+
+// sessionTaskAction describes a task of a session as Schema.org action of the
+// type of its actionSpec, like taskActionJSONLD does for a live response
+func sessionTaskAction(task MigrationTask) map[string]interface{} {
+	actionType := "Action"
+	if spec, ok := actionHandlers[task.Action].(*actionSpec); ok && spec.SchemaType != "" {
+		actionType = spec.SchemaType
+	}
+
+	action := map[string]interface{}{
+		"@type":        actionType,
+		"identifier":   fmt.Sprintf("task-%d", task.Index),
+		"name":         task.Action,
+		"actionStatus": sessionActionStatus(task.Status),
+		"startTime":    task.StartTime.Format(time.RFC3339),
+	}
+	if task.EndTime != nil {
+		action["endTime"] = task.EndTime.Format(time.RFC3339)
+	}
+	// Repositories are DataCatalogs and graphs Datasets, as in semantic actions
+	if task.SrcURL != "" {
+		action["object"] = map[string]interface{}{"@type": "DataCatalog", "url": task.SrcURL}
+	}
+	if task.TgtURL != "" || task.RepoID != "" {
+		target := map[string]interface{}{"@type": "DataCatalog", "url": task.TgtURL, "identifier": task.RepoID}
+		if task.GraphID != "" {
+			target = map[string]interface{}{"@type": "Dataset", "identifier": task.GraphID, "includedInDataCatalog": target}
+		}
+		action["target"] = target
+	}
+	if task.Status == sessionStatusCompleted || task.Status == sessionStatusSkipped {
+		result := map[string]interface{}{"status": task.Status, "duration_ms": task.DurationMs}
+		if task.DataSize > 0 {
+			result["data_size_bytes"] = task.DataSize
+		}
+		if task.TripleCount > 0 {
+			result["triple_count"] = task.TripleCount
+		}
+		action["result"] = result
+	}
+	if task.ErrorMessage != "" {
+		action["error"] = map[string]interface{}{
+			"@type":       "Thing",
+			"name":        task.ErrorType,
+			"description": task.ErrorMessage,
+		}
+	}
+	return action
+}
+
+// sessionItemList renders a stored session as the Schema.org ItemList a
+// semantic workflow is submitted as, with every task as completed or failed
+// action. Parallel and concurrency are taken from the stored request.
+func sessionItemList(session *MigrationSession) ItemListWorkflow {
+	workflow := ItemListWorkflow{
+		Context:         "https://schema.org",
+		Type:            "ItemList",
+		Identifier:      session.ID,
+		Name:            fmt.Sprintf("Migration session %s", session.ID),
+		Description:     fmt.Sprintf("%s: %d of %d tasks completed, %d failed", session.Status, session.CompletedTasks, session.TotalTasks, session.FailedTasks),
+		Concurrency:     1,
+		ItemListElement: make([]ListItemNode, len(session.Tasks)),
+	}
+	var req MigrationRequest
+	if requestJSON := session.Metadata[sessionRequestKey]; requestJSON != "" && json.Unmarshal([]byte(requestJSON), &req) == nil {
+		workflow.Parallel = req.Parallel
+		if req.Concurrency > 0 {
+			workflow.Concurrency = req.Concurrency
+		}
+	}
+	for i, task := range session.Tasks {
+		workflow.ItemListElement[i] = ListItemNode{Type: "ListItem", Position: i + 1, Item: sessionTaskAction(task)}
+	}
+	return workflow
+}
+
+// getSessionItemListREST handles REST GET /admin/migrations/session/:id/itemlist
+//
+// Returns a stored migration session as JSON-LD Schema.org ItemList of its
+// tasks, so semantic web tooling can consume the migration history. Returns
+// 404 if the session does not exist.
+func getSessionItemListREST(c echo.Context) error {
+	if migrationLogger == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "migration session logging is disabled"})
+	}
+
+	session, err := migrationLogger.GetSession(c.Param("id"))
+	if errors.Is(err, errSessionNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "session not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return writeJSONLD(c, http.StatusOK, sessionItemList(session))
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"eve.evalgo.org/common"
+)
+
+// Defaults of MAX_CONCURRENT_SESSIONS and MAX_QUEUED_SESSIONS
+const (
+	defaultMaxConcurrentSessions = 4
+	defaultMaxQueuedSessions     = 16
+)
+
+// errTooManySessions rejects a submission while the running sessions and the
+// queue are full
+var errTooManySessions = errors.New("too many migration sessions, try again later")
+
+// sessionLimiter is the job queue of migration sessions. At most maxRunning
+// sessions run at once, like a fixed pool of workers; sessions beyond it wait
+// in a first come, first served queue of limited length, and submissions
+// beyond the queue are rejected. A limit of 0 disables it.
+type sessionLimiter struct {
+	mu         sync.Mutex
+	maxRunning int
+	maxQueued  int
+	running    int
+	queue      []*sessionSlot // queued slots in the order they are served
+}
+
+// sessionSlot is the admission of one session. A queued slot must be waited
+// for before the session runs; release must be called when it finished.
+type sessionSlot struct {
+	limiter *sessionLimiter
+	queued  bool
+	done    bool
+	ready   chan struct{} // closed when a queued slot is given a running slot
+}
+
+// migrationSessions limits the sessions of POST /v1/api/action
+var migrationSessions = newSessionLimiter(defaultMaxConcurrentSessions, defaultMaxQueuedSessions)
+
+func newSessionLimiter(maxRunning, maxQueued int) *sessionLimiter {
+	return &sessionLimiter{maxRunning: maxRunning, maxQueued: maxQueued}
+}
+
+// configuredSessionLimiter returns a limiter configured by MAX_CONCURRENT_SESSIONS
+// and MAX_QUEUED_SESSIONS
+func configuredSessionLimiter() *sessionLimiter {
+	return newSessionLimiter(
+		common.GetEnvInt("MAX_CONCURRENT_SESSIONS", defaultMaxConcurrentSessions),
+		common.GetEnvInt("MAX_QUEUED_SESSIONS", defaultMaxQueuedSessions),
+	)
+}
+
+// admit reserves a slot for a new session: a running slot if one is free and
+// no session waits, otherwise a place at the end of the queue. It fails with
+// errTooManySessions when the queue is full as well.
+func (l *sessionLimiter) admit() (*sessionSlot, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxRunning <= 0 || (l.running < l.maxRunning && len(l.queue) == 0) {
+		l.running++
+		return &sessionSlot{limiter: l}, nil
+	}
+	if len(l.queue) >= l.maxQueued {
+		return nil, errTooManySessions
+	}
+	return l.enqueue(), nil
+}
+
+// requeue puts a session recovered at startup at the end of the queue. It is
+// admitted beyond maxQueued, as it was accepted before the restart.
+func (l *sessionLimiter) requeue() *sessionSlot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	slot := l.enqueue()
+	l.dispatch()
+	return slot
+}
+
+// enqueue appends a queued slot. The caller must hold l.mu.
+func (l *sessionLimiter) enqueue() *sessionSlot {
+	slot := &sessionSlot{limiter: l, queued: true, ready: make(chan struct{})}
+	l.queue = append(l.queue, slot)
+	return slot
+}
+
+// dispatch hands the free running slots to the queued sessions in queue
+// order. The caller must hold l.mu.
+func (l *sessionLimiter) dispatch() {
+	for len(l.queue) > 0 && (l.maxRunning <= 0 || l.running < l.maxRunning) {
+		slot := l.queue[0]
+		l.queue = l.queue[1:]
+		slot.queued = false
+		l.running++
+		close(slot.ready)
+	}
+}
+
+// remove takes a queued slot out of the queue. The caller must hold l.mu.
+func (l *sessionLimiter) remove(slot *sessionSlot) {
+	for i, queued := range l.queue {
+		if queued == slot {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			break
+		}
+	}
+	slot.queued = false
+}
+
+// counts returns the number of running and queued sessions
+func (l *sessionLimiter) counts() (running, queued int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.running, len(l.queue)
+}
+
+// isQueued reports whether the session still waits for a running slot
+func (s *sessionSlot) isQueued() bool {
+	if s == nil {
+		return false
+	}
+	s.limiter.mu.Lock()
+	defer s.limiter.mu.Unlock()
+	return s.queued
+}
+
+// waits reports whether the session was put in the queue, even if it has been
+// given a running slot since; wait then returns at once
+func (s *sessionSlot) waits() bool {
+	return s != nil && s.ready != nil
+}
+
+// position returns the 1-based place of a queued session in the queue, 1 for
+// the session started next, or 0 if it does not wait
+func (s *sessionSlot) position() int {
+	if s == nil {
+		return 0
+	}
+	s.limiter.mu.Lock()
+	defer s.limiter.mu.Unlock()
+	for i, queued := range s.limiter.queue {
+		if queued == s {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// wait blocks a queued session until it is given a running slot. It returns
+// the error of ctx if the session was cancelled while waiting; the slot is
+// then given up and release is a no-op.
+func (s *sessionSlot) wait(ctx context.Context) error {
+	if !s.waits() {
+		return nil
+	}
+	select {
+	case <-s.ready:
+		return nil
+	case <-ctx.Done():
+		l := s.limiter
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if !s.queued {
+			// Given a running slot at the same time: the session runs and
+			// its tasks see the cancellation
+			return nil
+		}
+		l.remove(s)
+		s.done = true
+		return ctx.Err()
+	}
+}
+
+// release frees the slot of a finished session and starts the next queued one
+func (s *sessionSlot) release() {
+	if s == nil {
+		return
+	}
+	l := s.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s.done {
+		return
+	}
+	s.done = true
+	if s.queued {
+		l.remove(s)
+		return
+	}
+	l.running--
+	l.dispatch()
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// queuedJobsDir is the directory below MIGRATION_LOG_DIR holding the requests
+// of queued callback sessions
+const queuedJobsDir = "queue"
+
+// errSessionSuspended ends a queued session that is resumed at the next start
+// of the service instead of being cancelled by the shutdown
+var errSessionSuspended = errors.New("session suspended until the service restarts")
+
+// suspendingQueue is set at shutdown. Queued callback sessions then leave the
+// queue and are kept for the next start.
+var suspendingQueue atomic.Bool
+
+// queuedJob is the persisted request of a queued callback session
+type queuedJob struct {
+	SessionID string           `json:"session_id"`
+	KeyLabel  string           `json:"key_label"` // Label of the API key the callback is signed with
+	QueuedAt  time.Time        `json:"queued_at"`
+	Request   MigrationRequest `json:"request"`
+}
+
+// queuedJobPath returns the file holding the request of a queued session
+func (l *MigrationLogger) queuedJobPath(sessionID string) string {
+	return filepath.Join(l.dir, queuedJobsDir, sessionID+".json")
+}
+
+// persistQueuedJob stores the request of a queued callback session, so the
+// session survives a restart. The request holds the GraphDB credentials of its
+// tasks, so the file is only readable by the service user and is removed as
+// soon as the session leaves the queue.
+func (l *MigrationLogger) persistQueuedJob(sessionID, keyLabel string, req MigrationRequest) error {
+	data, err := json.Marshal(queuedJob{SessionID: sessionID, KeyLabel: keyLabel, QueuedAt: time.Now().UTC(), Request: req})
+	if err != nil {
+		return fmt.Errorf("failed to encode queued session %s: %w", sessionID, err)
+	}
+	path := l.queuedJobPath(sessionID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create queue directory: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write queued session %s: %w", sessionID, err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// hasQueuedJob reports whether the request of a session is persisted
+func (l *MigrationLogger) hasQueuedJob(sessionID string) bool {
+	_, err := os.Stat(l.queuedJobPath(sessionID))
+	return err == nil
+}
+
+// removeQueuedJob deletes the persisted request of a session, if any
+func (l *MigrationLogger) removeQueuedJob(sessionID string) error {
+	if err := os.Remove(l.queuedJobPath(sessionID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// queuedJobs returns the persisted requests in the order they were queued.
+// Unreadable files are skipped and returned as error.
+func (l *MigrationLogger) queuedJobs() ([]queuedJob, error) {
+	entries, err := os.ReadDir(filepath.Join(l.dir, queuedJobsDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []queuedJob
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(l.dir, queuedJobsDir, entry.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var job queuedJob
+		if err := json.Unmarshal(data, &job); err != nil {
+			errs = append(errs, fmt.Errorf("failed to decode queued session %s: %w", entry.Name(), err))
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].QueuedAt.Before(jobs[j].QueuedAt)
+	})
+	return jobs, errors.Join(errs...)
+}
+
+// SuspendSession persists an active session as queued and removes it from
+// memory without finishing it, so ResumeSession can continue it after a
+// restart. Notifiers are not told.
+func (l *MigrationLogger) SuspendSession(sessionID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	session, exists := l.active[sessionID]
+	if !exists {
+		return errSessionNotFound
+	}
+	session.Status = sessionStatusQueued
+	delete(l.active, sessionID)
+	if err := l.save(session); err != nil {
+		return err
+	}
+	return l.saveSummary(session)
+}
+
+// ResumeSession makes a persisted queued session active again. It fails for
+// sessions that already started or finished.
+func (l *MigrationLogger) ResumeSession(sessionID string) (*MigrationSession, error) {
+	session, err := l.load(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != sessionStatusQueued {
+		return nil, fmt.Errorf("session %s is %s, not queued", sessionID, session.Status)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, exists := l.active[sessionID]; exists {
+		return nil, fmt.Errorf("session %s is already active", sessionID)
+	}
+	l.active[sessionID] = session
+	return session.clone(), nil
+}
+
+// persistQueuedSession keeps the request of a queued callback session until it
+// leaves the queue. Without session logging there is nothing to resume it from.
+func persistQueuedSession(sessionID, keyLabel string, req MigrationRequest) {
+	if migrationLogger == nil {
+		return
+	}
+	if err := migrationLogger.persistQueuedJob(sessionID, keyLabel, req); err != nil {
+		serviceLog.Warn("Failed to persist queued migration session", "session_id", sessionID, "error", err)
+	}
+}
+
+// dequeueSession removes the persisted request of a session that left the queue
+func dequeueSession(sessionID string) {
+	if migrationLogger == nil || sessionID == "" {
+		return
+	}
+	if err := migrationLogger.removeQueuedJob(sessionID); err != nil {
+		serviceLog.Warn("Failed to remove queued migration session", "session_id", sessionID, "error", err)
+	}
+}
+
+// suspendSession keeps a queued session for the next start when its wait was
+// ended by the shutdown. It reports false for sessions that cannot be resumed,
+// which are cancelled instead.
+func suspendSession(sessionID string) bool {
+	if !suspendingQueue.Load() || migrationLogger == nil || !migrationLogger.hasQueuedJob(sessionID) {
+		return false
+	}
+	if err := migrationLogger.SuspendSession(sessionID); err != nil {
+		serviceLog.Warn("Failed to suspend queued migration session", "session_id", sessionID, "error", err)
+		return false
+	}
+	serviceLog.Info("Queued migration session suspended until the next start", "session_id", sessionID)
+	return true
+}
+
+// suspendQueuedSessions is called at shutdown: the queued sessions with a
+// persisted request leave the queue, so they neither start nor delay the
+// shutdown, and are resumed by recoverQueuedSessions at the next start.
+func suspendQueuedSessions() {
+	suspendingQueue.Store(true)
+	if migrationLogger == nil {
+		return
+	}
+	for _, id := range runningSessionIDs() {
+		runningSessionsMutex.Lock()
+		control, running := runningSessions[id]
+		runningSessionsMutex.Unlock()
+		if running && control.queued.Load() && migrationLogger.hasQueuedJob(id) {
+			control.cancel()
+		}
+	}
+}
+
+// recoverQueuedSessions resumes the callback sessions that were queued when
+// the service stopped, in their original order, and returns their IDs.
+// Requests of sessions that cannot be resumed are dropped.
+func recoverQueuedSessions() []string {
+	if migrationLogger == nil {
+		return nil
+	}
+	jobs, err := migrationLogger.queuedJobs()
+	if err != nil {
+		serviceLog.Warn("Failed to read queued migration sessions", "error", err)
+	}
+
+	var ids []string
+	for _, job := range jobs {
+		if _, err := migrationLogger.ResumeSession(job.SessionID); err != nil {
+			serviceLog.Warn("Dropping queued migration session", "session_id", job.SessionID, "error", err)
+			dequeueSession(job.SessionID)
+			continue
+		}
+		go runAsyncMigration(job.SessionID, job.KeyLabel, job.Request, migrationSessions.requeue())
+		ids = append(ids, job.SessionID)
+	}
+	return ids
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"eve.evalgo.org/common"
+	"github.com/labstack/echo/v4"
+)
+
+// defaultShutdownTimeoutSeconds is the default of SHUTDOWN_TIMEOUT_SECONDS
+const defaultShutdownTimeoutSeconds = 60
+
+// shutdownPollInterval is how often shutdown checks for finished sessions
+const shutdownPollInterval = 100 * time.Millisecond
+
+// interruptedSessionMessage is the error message of sessions interrupted by a shutdown
+const interruptedSessionMessage = "service shut down before the session finished"
+
+// shutdownTimeout returns how long a shutdown waits for active sessions, set
+// by SHUTDOWN_TIMEOUT_SECONDS
+func shutdownTimeout() time.Duration {
+	seconds := common.GetEnvInt("SHUTDOWN_TIMEOUT_SECONDS", defaultShutdownTimeoutSeconds)
+	if seconds < 0 {
+		seconds = 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// shutdownService stops the server gracefully. It suspends the queued callback
+// sessions for the next start, stops accepting requests, waits up to timeout
+// for the running requests and migration sessions, and then marks the
+// sessions still active as interrupted in the MigrationLogger and aborts
+// their tasks.
+func shutdownService(e *echo.Echo, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	suspendQueuedSessions()
+
+	// Synchronous sessions run within their request and are waited for here
+	err := e.Shutdown(ctx)
+	if err != nil {
+		serviceLog.Warn("Requests still running at shutdown", "error", err)
+	}
+	if waitForSessions(ctx) {
+		return err
+	}
+
+	if migrationLogger != nil {
+		ids, logErr := migrationLogger.InterruptSessions(interruptedSessionMessage)
+		if len(ids) > 0 {
+			serviceLog.Warn("Interrupted migration sessions at shutdown", "sessions", ids, "timeout", timeout)
+		}
+		err = errors.Join(err, logErr)
+	}
+	for _, id := range runningSessionIDs() {
+		cancelRunningSession(id, true)
+	}
+	return err
+}
+
+// waitForSessions waits until no migration session is active. It reports
+// false if ctx ended first.
+func waitForSessions(ctx context.Context) bool {
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if activeSessions() == 0 {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// activeSessions returns the number of sessions that have not finished, those
+// recorded by the MigrationLogger as well as running ones without a record
+func activeSessions() int {
+	active := len(runningSessionIDs())
+	if migrationLogger != nil {
+		active = max(active, migrationLogger.ActiveSessionCount())
+	}
+	return active
+}
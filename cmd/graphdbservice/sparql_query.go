@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"eve.evalgo.org/common"
+)
+
+// defaultSparqlQueryMaxRows is the default of SPARQL_QUERY_MAX_ROWS
+const defaultSparqlQueryMaxRows = 1000
+
+// sparqlQueryMaxRows returns the most result rows sparql-query returns. A task
+// limit above it is lowered to it.
+func sparqlQueryMaxRows() int {
+	if rows := common.GetEnvInt("SPARQL_QUERY_MAX_ROWS", defaultSparqlQueryMaxRows); rows > 0 {
+		return rows
+	}
+	return defaultSparqlQueryMaxRows
+}
+
+// validateSparqlQueryTask checks that the query of a sparql-query task is a
+// SELECT or ASK query; updates and graph queries are rejected
+func validateSparqlQueryTask(task Task) error {
+	switch form := sparqlQueryForm(task.Tgt.Query); form {
+	case "SELECT", "ASK":
+	case "":
+		return &taskFieldError{Field: "tgt.query", Message: "sparql-query requires a SELECT or ASK query in tgt.query, updates are not allowed"}
+	default:
+		return &taskFieldError{Field: "tgt.query", Message: fmt.Sprintf("sparql-query only supports SELECT or ASK queries, got %s (use graph-query-import)", form)}
+	}
+	if task.Limit < 0 {
+		return &taskFieldError{Field: "limit", Message: "limit must not be negative"}
+	}
+	return nil
+}
+
+// sparqlQueryResult is the decoded result of a SELECT or ASK query. Boolean is
+// only set for ASK queries.
+type sparqlQueryResult struct {
+	sparqlResults
+	Boolean   *bool
+	Truncated bool
+}
+
+// sparqlQueryLimited runs a SELECT or ASK query against a repository and decodes
+// at most limit bindings; the rest of the response is not read. A positive
+// timeout is passed to GraphDB so it stops evaluating the query as well.
+func sparqlQueryLimited(client *http.Client, serverURL, username, password, repo, query string, limit int, timeout time.Duration) (*sparqlQueryResult, error) {
+	endpoint := fmt.Sprintf("%s/repositories/%s", normalizeURL(serverURL), url.PathEscape(repo))
+	form := url.Values{"query": {query}}
+	if seconds := int(timeout / time.Second); seconds > 0 {
+		form.Set("timeout", strconv.Itoa(seconds))
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/sparql-results+json")
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, graphDBStatusError(resp.StatusCode, "SPARQL query on repository '%s' failed with status %d: %s", repo, resp.StatusCode, readErrorBody(resp))
+	}
+
+	result, err := decodeSparqlResults(json.NewDecoder(resp.Body), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode SPARQL results: %w", err)
+	}
+	return result, nil
+}
+
+// decodeSparqlResults reads a SPARQL JSON result token by token, so a large
+// result is not held in memory beyond the first limit bindings
+func decodeSparqlResults(dec *json.Decoder, limit int) (*sparqlQueryResult, error) {
+	result := &sparqlQueryResult{}
+	result.Results.Bindings = []map[string]sparqlValue{}
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "head":
+			err = dec.Decode(&result.Head)
+		case "boolean":
+			err = dec.Decode(&result.Boolean)
+		case "results":
+			err = decodeSparqlBindings(dec, result, limit)
+			if err == nil && result.Truncated {
+				return result, nil
+			}
+		default:
+			var skipped json.RawMessage
+			err = dec.Decode(&skipped)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// decodeSparqlBindings reads the results object and stops after limit bindings
+func decodeSparqlBindings(dec *json.Decoder, result *sparqlQueryResult, limit int) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if key != "bindings" {
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := expectDelim(dec, '['); err != nil {
+			return err
+		}
+		for dec.More() {
+			if len(result.Results.Bindings) >= limit {
+				result.Truncated = true
+				return nil
+			}
+			var binding map[string]sparqlValue
+			if err := dec.Decode(&binding); err != nil {
+				return err
+			}
+			result.Results.Bindings = append(result.Results.Bindings, binding)
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// expectDelim reads the next token and checks that it is the delimiter want
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("unexpected token %v, expected %v", token, want)
+	}
+	return nil
+}
+
+// executeSparqlQueryTask executes the sparql-query action.
+//
+// The result holds the variables and bindings in the SPARQL JSON layout, or
+// the boolean of an ASK query. At most limit rows are returned (default and
+// upper bound SPARQL_QUERY_MAX_ROWS); truncated results carry a warning.
+func executeSparqlQueryTask(run *taskRun) error {
+	task, result, tgtClient := run.task, run.result, run.tgtClient
+
+	if identityFile != "" {
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
+		if err != nil {
+			return err
+		}
+		tgtClient, err = run.zitiClient(tgtURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := requireTaskRepository(run.graphDB(tgtClient), task.Tgt, "tgt"); err != nil {
+		return err
+	}
+
+	limit := sparqlQueryMaxRows()
+	if task.Limit > 0 && task.Limit < limit {
+		limit = task.Limit
+	} else if task.Limit > limit {
+		addResultWarning(result, fmt.Sprintf("limit %d lowered to SPARQL_QUERY_MAX_ROWS (%d)", task.Limit, limit))
+	}
+
+	form := sparqlQueryForm(task.Tgt.Query)
+	answer, err := sparqlQueryLimited(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Query, limit, taskTimeout(task))
+	if err != nil {
+		return err
+	}
+
+	result["repository"] = task.Tgt.Repo
+	result["query_form"] = form
+	if form == "ASK" {
+		if answer.Boolean == nil {
+			return fmt.Errorf("ASK query on repository '%s' returned no boolean result", task.Tgt.Repo)
+		}
+		result["boolean"] = *answer.Boolean
+		result["message"] = fmt.Sprintf("ASK query on repository '%s' returned %t", task.Tgt.Repo, *answer.Boolean)
+		return nil
+	}
+
+	result["vars"] = answer.Head.Vars
+	result["bindings"] = answer.Results.Bindings
+	result["row_count"] = len(answer.Results.Bindings)
+	result["limit"] = limit
+	result["truncated"] = answer.Truncated
+	if answer.Truncated {
+		addResultWarning(result, fmt.Sprintf("result truncated to %d rows", limit))
+	}
+	result["message"] = fmt.Sprintf("SELECT query on repository '%s' returned %d rows", task.Tgt.Repo, len(answer.Results.Bindings))
+	return nil
+}
@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"eve.evalgo.org/common"
+)
+
+// sparqlUpdateEnabled reports whether the sparql-update action may run. It is
+// disabled unless SPARQL_UPDATE_ENABLED is set, since it can change any data.
+func sparqlUpdateEnabled() bool {
+	return common.GetEnvBool("SPARQL_UPDATE_ENABLED", false)
+}
+
+// sparqlUpdateSafeMode reports whether obviously destructive updates are
+// rejected. SPARQL_UPDATE_SAFE_MODE defaults to true.
+func sparqlUpdateSafeMode() bool {
+	return common.GetEnvBool("SPARQL_UPDATE_SAFE_MODE", true)
+}
+
+// destructiveUpdatePatterns match updates that replace or remove whole graphs
+// or load documents from other servers, applied to the update without IRIs,
+// literals and comments. Variables and prefixed names such as ?copy or ex:drop
+// do not match.
+var destructiveUpdatePatterns = []struct {
+	pattern *regexp.Regexp
+	reason  string
+}{
+	{regexp.MustCompile(`(?i)(?:^|[^\w?$:])(DROP|CLEAR)\b`), "DROP and CLEAR remove whole graphs"},
+	{regexp.MustCompile(`(?i)(?:^|[^\w?$:])(MOVE|COPY|ADD)\b`), "MOVE, COPY and ADD write whole graphs into another graph"},
+	{regexp.MustCompile(`(?i)(?:^|[^\w?$:])LOAD\b`), "LOAD imports documents from any URL"},
+}
+
+// deletePattern matches DELETE WHERE and DELETE { }, but not DELETE DATA
+var deletePattern = regexp.MustCompile(`(?i)(?:^|[^\w?$:])DELETE\s*(?:WHERE\b|\{)`)
+
+// fixedGraphPattern matches WITH <iri> and GRAPH <iri> in an update returned by
+// sparqlUpdateKeywords, where IRIs are <> and prefixed names are kept
+var fixedGraphPattern = regexp.MustCompile(`(?i)\b(?:WITH|GRAPH)\s*(?:<>|[\w-]*:)`)
+
+// updateGraphPattern matches the graph IRIs an update names explicitly
+var updateGraphPattern = regexp.MustCompile(`(?i)\b(?:GRAPH|WITH|INTO|USING(?:\s+NAMED)?)\s*<([^>]*)>`)
+
+// sparqlUpdateKeywords returns the update without IRIs, string literals and
+// comments, so keywords inside them are not mistaken for operations
+func sparqlUpdateKeywords(update string) string {
+	var out strings.Builder
+	for i := 0; i < len(update); i++ {
+		switch c := update[i]; c {
+		case '<':
+			if end := strings.IndexAny(update[i:], "> \t\n"); end > 0 && update[i+end] == '>' {
+				out.WriteString("<>")
+				i += end
+				continue
+			}
+			out.WriteByte(c)
+		case '"', '\'':
+			quote := string(c)
+			if strings.HasPrefix(update[i:], strings.Repeat(quote, 3)) {
+				quote = strings.Repeat(quote, 3)
+			}
+			end := strings.Index(update[i+len(quote):], quote)
+			for end > 0 && update[i+len(quote)+end-1] == '\\' {
+				next := strings.Index(update[i+len(quote)+end+1:], quote)
+				if next < 0 {
+					end = -1
+					break
+				}
+				end += next + 1
+			}
+			if end < 0 {
+				return out.String()
+			}
+			out.WriteString(`""`)
+			i += len(quote) + end + len(quote) - 1
+		case '#':
+			end := strings.IndexByte(update[i:], '\n')
+			if end < 0 {
+				return out.String()
+			}
+			i += end
+			out.WriteByte('\n')
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
+
+// destructiveSPARQLUpdate returns why an update is obviously destructive, or "".
+//
+// Safe mode guards against mistakes such as a forgotten graph: it rejects the
+// keywords of destructiveUpdatePatterns and every DELETE or DELETE WHERE whose
+// operation names no graph by WITH <iri> or, in its template, GRAPH <iri>. It
+// is a keyword check, not a SPARQL parser, and not a security boundary; a
+// client allowed to run sparql-update can still delete any statement.
+func destructiveSPARQLUpdate(update string) string {
+	keywords := sparqlUpdateKeywords(update)
+	for _, p := range destructiveUpdatePatterns {
+		if p.pattern.MatchString(keywords) {
+			return p.reason
+		}
+	}
+	for _, operation := range sparqlOperations(keywords) {
+		if deleteWithoutGraph(operation) {
+			return "DELETE without WITH <iri> or GRAPH <iri> removes statements from every graph"
+		}
+	}
+	return ""
+}
+
+// sparqlOperations splits an update returned by sparqlUpdateKeywords into its
+// operations, which are separated by a ; outside of braces
+func sparqlOperations(keywords string) []string {
+	var operations []string
+	depth, start := 0, 0
+	for i, c := range keywords {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ';':
+			if depth == 0 {
+				operations = append(operations, keywords[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(operations, keywords[start:])
+}
+
+// deleteWithoutGraph reports whether an operation deletes without naming the
+// graph by WITH <iri> before DELETE or by GRAPH <iri> in the template of
+// DELETE { } or the pattern of DELETE WHERE { }
+func deleteWithoutGraph(operation string) bool {
+	match := deletePattern.FindStringIndex(operation)
+	if match == nil || fixedGraphPattern.MatchString(operation[:match[0]]) {
+		return false
+	}
+	template := operation[match[0]:]
+	open := strings.IndexByte(template, '{')
+	if open < 0 {
+		return false // not a valid update, GraphDB rejects it
+	}
+	template = template[open:]
+	depth := 0
+	for i, c := range template {
+		if c == '{' {
+			depth++
+		} else if c == '}' {
+			if depth--; depth == 0 {
+				template = template[:i+1]
+				break
+			}
+		}
+	}
+	return !fixedGraphPattern.MatchString(template)
+}
+
+// sparqlUpdateGraphs lists the graph IRIs named in an update, without duplicates
+func sparqlUpdateGraphs(update string) []string {
+	graphs := []string{}
+	seen := make(map[string]bool)
+	for _, match := range updateGraphPattern.FindAllStringSubmatch(update, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			graphs = append(graphs, match[1])
+		}
+	}
+	return graphs
+}
+
+// validateSparqlUpdateTask checks that sparql-update is enabled and, in safe
+// mode, that the update is not obviously destructive
+func validateSparqlUpdateTask(task Task) error {
+	if !sparqlUpdateEnabled() {
+		return &taskFieldError{Field: "action", Message: "sparql-update is disabled, set SPARQL_UPDATE_ENABLED=true to allow it"}
+	}
+	if sparqlQueryForm(task.Tgt.Update) != "" {
+		return &taskFieldError{Field: "tgt.update", Message: "tgt.update must be a SPARQL UPDATE, not a query"}
+	}
+	if sparqlUpdateSafeMode() {
+		if reason := destructiveSPARQLUpdate(task.Tgt.Update); reason != "" {
+			return &taskFieldError{Field: "tgt.update", Message: fmt.Sprintf("update rejected in safe mode: %s (SPARQL_UPDATE_SAFE_MODE=false allows it)", reason)}
+		}
+	}
+	return nil
+}
+
+// executeSparqlUpdateTask executes the sparql-update action.
+//
+// GraphDB answers updates without details, so the result lists the graphs the
+// update names and the explicit statement counts of the repository before and
+// after the update.
+func executeSparqlUpdateTask(run *taskRun) error {
+	task, result, tgtClient := run.task, run.result, run.tgtClient
+
+	// The flags are checked again in case the configuration changed since validation
+	if err := validateSparqlUpdateTask(task); err != nil {
+		return err
+	}
+
+	if identityFile != "" {
+		tgtURL, err := URL2ServiceRobust(task.Tgt.URL)
+		if err != nil {
+			return err
+		}
+		tgtClient, err = run.zitiClient(tgtURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := requireTaskRepository(run.graphDB(tgtClient), task.Tgt, "tgt"); err != nil {
+		return err
+	}
+
+	graphs := sparqlUpdateGraphs(task.Tgt.Update)
+	result["repository"] = task.Tgt.Repo
+	result["graphs"] = graphs
+
+	if task.DryRun {
+		operations := []map[string]interface{}{}
+		for _, graph := range graphs {
+			operations = append(operations, plannedOperation("update-graph", task.Tgt.Repo, graph, -1))
+		}
+		if len(graphs) == 0 {
+			operations = append(operations, plannedOperation("update-repository", task.Tgt.Repo, "", -1))
+		}
+		setDryRunResult(result, "Dry run: update would be run", operations)
+		return nil
+	}
+
+	before, sizeErr := graphDBRepositorySize(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo)
+	if err := sparqlUpdate(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo, task.Tgt.Update); err != nil {
+		return err
+	}
+	if sizeErr == nil {
+		if after, err := graphDBRepositorySize(tgtClient, task.Tgt.URL, task.Tgt.Username, task.Tgt.Password, task.Tgt.Repo); err == nil {
+			result["statements_before"] = before.Explicit
+			result["statements_after"] = after.Explicit
+		}
+	}
+
+	result["message"] = fmt.Sprintf("SPARQL update on repository '%s' completed", task.Tgt.Repo)
+	return nil
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Error types recorded for failed tasks
+const (
+	taskErrorExecution     = "execution_error"
+	taskErrorTimeout       = "timeout"
+	taskErrorCancelled     = "cancelled"
+	taskErrorValidation    = "validation_error"
+	taskErrorRepoNotFound  = "repository_not_found"
+	taskErrorGraphNotFound = "graph_not_found"
+	taskErrorAuth          = "auth_error"
+	taskErrorUpstream      = "upstream_error"
+)
+
+// TaskErrorKind is a category of task errors. A classified error matches its
+// category with errors.Is, e.g. errors.Is(err, ErrRepoNotFound).
+type TaskErrorKind struct {
+	errorType   string
+	description string
+}
+
+func (k *TaskErrorKind) Error() string { return k.description }
+
+// ErrorType returns the error type recorded in the session log
+func (k *TaskErrorKind) ErrorType() string { return k.errorType }
+
+// Categories of task errors
+var (
+	ErrRepoNotFound  = &TaskErrorKind{taskErrorRepoNotFound, "repository not found"}
+	ErrGraphNotFound = &TaskErrorKind{taskErrorGraphNotFound, "graph not found"}
+	ErrAuth          = &TaskErrorKind{taskErrorAuth, "GraphDB rejected the credentials"}
+	ErrUpstream      = &TaskErrorKind{taskErrorUpstream, "GraphDB request failed"}
+)
+
+// TaskError is a task error of a known category. Its message is shown as is;
+// the category is only used for classification. StatusCode is the HTTP status
+// of the GraphDB response that caused the error, 0 if there was none.
+type TaskError struct {
+	Kind       *TaskErrorKind
+	StatusCode int
+	message    string
+	cause      error
+}
+
+func (e *TaskError) Error() string { return e.message }
+
+// ErrorType returns the error type of the category
+func (e *TaskError) ErrorType() string { return e.Kind.errorType }
+
+// Is matches the category of the error
+func (e *TaskError) Is(target error) bool { return target == e.Kind }
+
+func (e *TaskError) Unwrap() error { return e.cause }
+
+// newTaskError returns an error of category kind with a formatted message
+func newTaskError(kind *TaskErrorKind, format string, args ...interface{}) error {
+	return &TaskError{Kind: kind, message: fmt.Sprintf(format, args...)}
+}
+
+// graphDBStatusError returns the error for an unsuccessful GraphDB response:
+// ErrAuth for 401 and 403, ErrUpstream otherwise
+func graphDBStatusError(statusCode int, format string, args ...interface{}) error {
+	kind := ErrUpstream
+	if statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden {
+		kind = ErrAuth
+	}
+	return &TaskError{Kind: kind, StatusCode: statusCode, message: fmt.Sprintf(format, args...)}
+}
+
+// taskErrorType classifies a task error for the session log and task results.
+// Errors without category are reported as upstream errors when a GraphDB
+// request could not be sent, and as execution errors otherwise.
+func taskErrorType(err error) string {
+	switch {
+	case errors.Is(err, errTaskTimeout):
+		return taskErrorTimeout
+	case errors.Is(err, errTaskCancelled):
+		return taskErrorCancelled
+	}
+
+	var classified interface{ ErrorType() string }
+	if errors.As(err, &classified) {
+		return classified.ErrorType()
+	}
+	var fieldErr *taskFieldError
+	if errors.As(err, &fieldErr) {
+		return taskErrorValidation
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return taskErrorUpstream
+	}
+	return taskErrorExecution
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"sync"
+	"time"
+)
+
+// Steps timed by repo-migration, graph-migration and repo-rename and reported
+// in the "timings" map of their result
+const (
+	stepListRepositories = "list_repositories"
+	stepListGraphs       = "list_graphs"
+	stepDownloadConfig   = "download_config"
+	stepDeleteTarget     = "delete_target"
+	stepRestoreConfig    = "restore_config"
+	stepTransferData     = "transfer_data" // BRF download and restore, streamed at once
+	stepExportGraphs     = "export_graphs"
+	stepImportGraphs     = "import_graphs"
+	stepDeleteSource     = "delete_source"
+	stepVerify           = "verify"
+	stepReportGraphs     = "report_graphs"
+)
+
+// taskTimings adds up how long the steps of a task took
+type taskTimings struct {
+	mu    sync.Mutex
+	steps map[string]time.Duration
+}
+
+// add records that a step took d. Steps run several times, such as one export
+// per graph, add up.
+func (t *taskTimings) add(step string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.steps == nil {
+		t.steps = make(map[string]time.Duration)
+	}
+	t.steps[step] += d
+}
+
+// milliseconds returns the recorded steps in milliseconds, or nil if no step
+// was timed
+func (t *taskTimings) milliseconds() map[string]int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.steps) == 0 {
+		return nil
+	}
+	timings := make(map[string]int64, len(t.steps))
+	for step, d := range t.steps {
+		timings[step] = d.Milliseconds()
+	}
+	return timings
+}
+
+// timeStep starts timing a step of the task; the returned function ends it.
+//
+//	done := run.timeStep(stepDownloadConfig)
+//	err := graphDBDownloadRepositoryConfig(...)
+//	done()
+func (r *taskRun) timeStep(step string) func() {
+	start := time.Now()
+	return func() { r.timings.add(step, time.Since(start)) }
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"eve.evalgo.org/common"
+)
+
+// migrationTempDir holds the temp files of all tasks: uploads, exports and
+// repository downloads. It is set from MIGRATION_TEMP_DIR or TEMP_DIR at
+// startup and defaults to os.TempDir(), which may be a small tmpfs.
+var migrationTempDir = os.TempDir()
+
+// configuredTempDir returns the temp directory configured by the environment
+func configuredTempDir() string {
+	return common.GetEnv("MIGRATION_TEMP_DIR", common.GetEnv("TEMP_DIR", os.TempDir()))
+}
+
+// validateTempDir checks that dir is an existing, writable directory
+func validateTempDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("temp directory '%s' is not accessible: %v", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("temp directory '%s' is not a directory", dir)
+	}
+
+	probe, err := os.CreateTemp(dir, "graphdb_selfcheck_*")
+	if err != nil {
+		return fmt.Errorf("temp directory '%s' is not writable: %v", dir, err)
+	}
+	_ = probe.Close()
+	_ = os.Remove(probe.Name())
+	return nil
+}
+
+// allowedTempDirs returns the directories whose subdirectories a request may
+// name in temp_dir: MIGRATION_TEMP_DIR and the comma separated ALLOWED_TEMP_DIRS
+func allowedTempDirs() []string {
+	dirs := []string{migrationTempDir}
+	for _, dir := range strings.Split(common.GetEnv("ALLOWED_TEMP_DIRS", ""), ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// resolveRequestTempDir checks the temp_dir of a request and returns it with
+// symlinks resolved. A relative path is taken relative to MIGRATION_TEMP_DIR.
+// The directory must be one of allowedTempDirs or below one of them after
+// cleaning the path and resolving symlinks, so a request cannot make the
+// service write into arbitrary directories of the host.
+func resolveRequestTempDir(dir string) (string, error) {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(migrationTempDir, dir)
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Clean(dir))
+	if err != nil {
+		return "", fmt.Errorf("temp directory '%s' is not accessible: %v", dir, err)
+	}
+	for _, allowed := range allowedTempDirs() {
+		root, err := filepath.EvalSymlinks(filepath.Clean(allowed))
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(root, resolved); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, validateTempDir(resolved)
+		}
+	}
+	return "", fmt.Errorf("temp directory '%s' is not within MIGRATION_TEMP_DIR or ALLOWED_TEMP_DIRS", dir)
+}
+
+// tempDirContextKey stores the temp directory of a request in a task context
+type tempDirContextKey struct{}
+
+// withTempDir returns a copy of ctx whose tasks write their temp files to dir
+func withTempDir(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, tempDirContextKey{}, dir)
+}
+
+// tempDirFromContext returns the temp directory of a task context, falling
+// back to migrationTempDir when the request did not set temp_dir
+func tempDirFromContext(ctx context.Context) string {
+	if dir, _ := ctx.Value(tempDirContextKey{}).(string); dir != "" {
+		return dir
+	}
+	return migrationTempDir
+}
+
+// tempFile returns the path of a temp file named name in the task's temp directory
+func (run *taskRun) tempFile(name string) string {
+	return filepath.Join(run.tempDir, name)
+}